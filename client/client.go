@@ -0,0 +1,281 @@
+// Package client is a typed Go client for the users API. Every router serves
+// the same API, so a Client works against any of them.
+package client
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Framework is one of the routers serving the API, each on its own port.
+type Framework int
+
+const (
+	Standard Framework = iota
+	HttpRouter
+	Mux
+	Chi
+	Echo
+	Gin
+)
+
+var frameworkNames = []string{"standard", "httprouter", "mux", "chi", "echo", "gin"}
+
+func (f Framework) String() string {
+	if f < 0 || int(f) >= len(frameworkNames) {
+		return "Framework(" + strconv.Itoa(int(f)) + ")"
+	}
+	return frameworkNames[f]
+}
+
+// Port returns the port the framework listens on.
+func (f Framework) Port() int {
+	return 8000 + int(f)
+}
+
+// URL returns the base URL of the framework on host, such as
+// http://localhost:8003 for Chi on localhost.
+func (f Framework) URL(host string) string {
+	return "http://" + host + ":" + strconv.Itoa(f.Port())
+}
+
+// RetryPolicy controls how failed requests are retried. A request is retried
+// when it fails to reach the server or gets a 429, 502, 503 or 504, waiting
+// an exponentially growing, jittered delay between attempts, or the
+// Retry-After the server asks for. POST requests are only retried when they
+// carry an idempotency key.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts, including the first. 1 disables
+	// retries.
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultRetryPolicy is used unless WithRetryPolicy says otherwise.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  100 * time.Millisecond,
+	MaxBackoff:  2 * time.Second,
+}
+
+// Client calls the users API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	adminToken string
+	retry      RetryPolicy
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client requests are sent with.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAdminToken sends token as a bearer token, for the admin operations.
+func WithAdminToken(token string) Option {
+	return func(c *Client) {
+		c.adminToken = token
+	}
+}
+
+// WithRetryPolicy sets how failed requests are retried.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// New returns a Client for the API at baseURL, such as Chi.URL("localhost").
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("client: base URL %q must be an absolute http or https URL", baseURL)
+	}
+
+	c := &Client{baseURL: u, httpClient: http.DefaultClient, retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+	return c, nil
+}
+
+// CallOption configures a single call.
+type CallOption func(*call)
+
+type call struct {
+	idempotencyKey string
+}
+
+// WithIdempotencyKey sends key as the Idempotency-Key of the request, so a
+// retried request is applied once. CreateUser generates a key when none is
+// given.
+func WithIdempotencyKey(key string) CallOption {
+	return func(c *call) {
+		c.idempotencyKey = key
+	}
+}
+
+func newCall(opts []CallOption) call {
+	var c call
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// NewIdempotencyKey returns a random key for WithIdempotencyKey.
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// do sends a request, retrying it as the retry policy allows, and decodes a
+// successful JSON response into out unless out is nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body any, opts call, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("client: encoding request: %w", err)
+		}
+	}
+
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	retryable := method != http.MethodPost || opts.idempotencyKey != ""
+	for attempt := 1; ; attempt++ {
+		res, err := c.send(ctx, method, u.String(), payload, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !retryable || attempt >= c.retry.MaxAttempts {
+				return err
+			}
+			if err := sleep(ctx, c.backoff(attempt, nil)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if retryable && attempt < c.retry.MaxAttempts && retryStatus(res.StatusCode) {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+			if err := sleep(ctx, c.backoff(attempt, res)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		return decodeResponse(res, out)
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, u string, payload []byte, opts call) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	}
+	if opts.idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", opts.idempotencyKey)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+func retryStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns how long to wait before the attempt after attempt: the
+// Retry-After of res if it asks for longer, otherwise a random delay between
+// half and all of an exponentially growing backoff.
+func (c *Client) backoff(attempt int, res *http.Response) time.Duration {
+	d := c.retry.MinBackoff << (attempt - 1)
+	if d > c.retry.MaxBackoff || d <= 0 {
+		d = c.retry.MaxBackoff
+	}
+	if d > 0 {
+		d = d/2 + rand.N(d/2+1)
+	}
+
+	if res != nil {
+		if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			if after := time.Duration(seconds) * time.Second; after > d {
+				d = after
+			}
+		}
+	}
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func decodeResponse(res *http.Response, out any) error {
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return newError(res)
+	}
+
+	if out == nil || res.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, res.Body)
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("client: decoding %d response: %w", res.StatusCode, err)
+	}
+	return nil
+}
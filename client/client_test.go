@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database/dbtest"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/openapi"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/routers"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var created = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// store holds the users the routers serve, and the pages they were asked for.
+type store struct {
+	mu    sync.Mutex
+	users []database.User
+	pages [][2]int32
+}
+
+func newStore(n int) *store {
+	s := &store{}
+	for i := 1; i <= n; i++ {
+		s.users = append(s.users, database.User{
+			ID:        int32(i),
+			Name:      "User " + string(rune('A'+i-1)),
+			Email:     string(rune('a'+i-1)) + "@example.com",
+			Age:       int32(20 + i),
+			CreatedAt: pgtype.Timestamptz{Time: created, Valid: true},
+		})
+	}
+	return s
+}
+
+func (s *store) db() *dbtest.DB {
+	db := dbtest.New()
+	db.Handle("GetUser", func(args ...any) ([][]any, error) {
+		id := args[0].(int32)
+		for _, user := range s.users {
+			if user.ID == id {
+				return dbtest.Rows(user), nil
+			}
+		}
+		// No rows, which GetUser reports as pgx.ErrNoRows
+		return nil, nil
+	})
+	db.Handle("ListUsers", func(args ...any) ([][]any, error) {
+		limit, offset := args[1].(int32), args[2].(int32)
+		s.mu.Lock()
+		s.pages = append(s.pages, [2]int32{limit, offset})
+		s.mu.Unlock()
+		users := s.users[min(int(offset), len(s.users)):]
+		return dbtest.Rows(users[:min(int(limit), len(users))]...), nil
+	})
+	return db
+}
+
+// servers starts every router on s, with handlers wrapping each of them, and
+// returns a client for each.
+func servers(t *testing.T, s *store, wrap func(http.Handler) http.Handler, opts ...Option) map[string]*Client {
+	t.Helper()
+	cfg := &config.APIConfig{
+		DB:         database.New(s.db()),
+		AdminToken: "secret",
+	}
+	broker := events.NewBroker(cfg)
+
+	handlers := map[string]http.Handler{
+		"standard":   routers.StandardRouter(cfg, broker),
+		"httprouter": routers.HttpRouter(cfg, broker),
+		"mux":        routers.MuxRouter(cfg, broker),
+		"chi":        routers.ChiRouter(cfg, broker),
+		"echo":       routers.EchoRouter(cfg, broker),
+		"gin":        routers.GinRouter(cfg, broker),
+	}
+	clients := map[string]*Client{}
+	for framework, h := range handlers {
+		if wrap != nil {
+			h = wrap(h)
+		}
+		srv := httptest.NewServer(h)
+		t.Cleanup(srv.Close)
+
+		c, err := New(srv.URL, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clients[framework] = c
+	}
+	return clients
+}
+
+func TestGetUser(t *testing.T) {
+	for framework, c := range servers(t, newStore(3), nil) {
+		user, err := c.GetUser(context.Background(), 2)
+		if err != nil {
+			t.Errorf("%s: %v", framework, err)
+			continue
+		}
+		want := User{ID: 2, Name: "User B", Email: "b@example.com", Age: 22, CreatedAt: created}
+		if user != want {
+			t.Errorf("%s: got %+v, want %+v", framework, user, want)
+		}
+	}
+}
+
+func TestErrorsAreDecoded(t *testing.T) {
+	ctx := context.Background()
+	validate := openapi.NewValidator(config.ValidationConfig{Requests: true, MaxBodyBytes: 1 << 20}).Middleware
+
+	for framework, c := range servers(t, newStore(3), validate) {
+		_, err := c.GetUser(ctx, 99)
+		var apiErr *Error
+		if !errors.As(err, &apiErr) || !errors.Is(err, ErrNotFound) || apiErr.Message != "User not found" {
+			t.Errorf("%s: missing user: got %v, want a 404 with the error of the body", framework, err)
+		}
+
+		_, err = c.ListUsersPage(ctx, ListUsersOptions{IncludeDeleted: true})
+		if !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s: include_deleted without the token: got %v, want a 403", framework, err)
+		}
+
+		// The validator answers a limit over the maximum with a problem.
+		_, err = c.ListUsersPage(ctx, ListUsersOptions{Limit: 500})
+		if !errors.As(err, &apiErr) || !errors.Is(err, ErrBadRequest) || apiErr.Problem == nil {
+			t.Errorf("%s: invalid limit: got %v, want a 400 problem", framework, err)
+			continue
+		}
+		if len(apiErr.Problem.Errors) == 0 || apiErr.Problem.Errors[0].Parameter != "limit" {
+			t.Errorf("%s: invalid limit: problem errors %+v, want one for the limit parameter", framework, apiErr.Problem.Errors)
+		}
+	}
+}
+
+func TestListUsersPaginates(t *testing.T) {
+	s := newStore(5)
+	for framework, c := range servers(t, s, nil) {
+		s.mu.Lock()
+		s.pages = nil
+		s.mu.Unlock()
+
+		var ids []int32
+		for user, err := range c.ListUsers(context.Background(), ListUsersOptions{Limit: 2}) {
+			if err != nil {
+				t.Fatalf("%s: %v", framework, err)
+			}
+			ids = append(ids, user.ID)
+		}
+
+		if len(ids) != 5 || ids[0] != 1 || ids[4] != 5 {
+			t.Errorf("%s: listed %v, want users 1 to 5", framework, ids)
+		}
+		// The last page is short, so no empty page is fetched after it.
+		want := [][2]int32{{2, 0}, {2, 2}, {2, 4}}
+		if len(s.pages) != len(want) {
+			t.Errorf("%s: fetched pages %v, want %v", framework, s.pages, want)
+			continue
+		}
+		for i := range want {
+			if s.pages[i] != want[i] {
+				t.Errorf("%s: fetched pages %v, want %v", framework, s.pages, want)
+				break
+			}
+		}
+	}
+}
+
+func TestUnavailableRequestsAreRetried(t *testing.T) {
+	var mu sync.Mutex
+	failures := map[string]int{}
+	unavailableOnce := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			failures[r.Host]++
+			first := failures[r.Host] == 1
+			mu.Unlock()
+			if first {
+				http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	policy := RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	for framework, c := range servers(t, newStore(1), unavailableOnce, WithRetryPolicy(policy)) {
+		if _, err := c.GetUser(context.Background(), 1); err != nil {
+			t.Errorf("%s: %v", framework, err)
+		}
+	}
+}
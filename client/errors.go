@@ -0,0 +1,121 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Errors an *Error matches with errors.Is, by status code.
+var (
+	ErrBadRequest          = errors.New("bad request")
+	ErrForbidden           = errors.New("forbidden")
+	ErrNotFound            = errors.New("not found")
+	ErrConflict            = errors.New("conflict")
+	ErrUnprocessableEntity = errors.New("unprocessable entity")
+	ErrTooManyRequests     = errors.New("too many requests")
+	ErrServer              = errors.New("server error")
+)
+
+// Problem is a problem details body, which the API answers requests that do
+// not match its OpenAPI document with.
+type Problem struct {
+	Type   string         `json:"type"`
+	Title  string         `json:"title"`
+	Status int            `json:"status"`
+	Detail string         `json:"detail,omitempty"`
+	Errors []ProblemError `json:"errors,omitempty"`
+}
+
+// ProblemError is one thing wrong with a request. Pointer is a JSON pointer
+// into the body and Parameter names a parameter.
+type ProblemError struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	Detail    string `json:"detail"`
+}
+
+// Error is returned for a response with a 4xx or 5xx status.
+type Error struct {
+	StatusCode int
+	// Message is the error of the body, or the title of a problem.
+	Message string
+	// Problem is set when the body is a problem details body.
+	Problem *Problem
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(e.StatusCode))
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	}
+	if e.Problem != nil {
+		if e.Problem.Detail != "" {
+			b.WriteString(": " + e.Problem.Detail)
+		}
+		for _, pe := range e.Problem.Errors {
+			b.WriteString("; ")
+			if where := pe.Pointer + pe.Parameter; where != "" {
+				b.WriteString(where + " ")
+			}
+			b.WriteString(pe.Detail)
+		}
+	}
+	return b.String()
+}
+
+// Is reports whether target is the sentinel error of the status code.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrUnprocessableEntity:
+		return e.StatusCode == http.StatusUnprocessableEntity
+	case ErrTooManyRequests:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+func newError(res *http.Response) *Error {
+	e := &Error{StatusCode: res.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/problem+json":
+		var problem Problem
+		if json.Unmarshal(data, &problem) == nil {
+			e.Problem = &problem
+			e.Message = problem.Title
+		}
+	case "application/json":
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &body) == nil {
+			e.Message = body.Error
+		}
+	}
+
+	if e.Message == "" {
+		e.Message = strings.TrimSpace(string(data))
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(res.StatusCode)
+	}
+	return e
+}
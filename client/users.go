@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// User is a user of the API.
+type User struct {
+	ID        int32      `json:"id"`
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	Age       int32      `json:"age"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+// CreateUserRequest holds the fields of a new user.
+type CreateUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Age   int32  `json:"age"`
+}
+
+// UpdateUserRequest holds the fields of a user to change. Fields left nil
+// keep their current value.
+type UpdateUserRequest struct {
+	Name  *string `json:"name,omitempty"`
+	Email *string `json:"email,omitempty"`
+	Age   *int32  `json:"age,omitempty"`
+}
+
+// ListUsersOptions selects the users to list.
+type ListUsersOptions struct {
+	// IncludeDeleted lists soft-deleted users too, which needs the admin
+	// token.
+	IncludeDeleted bool
+	// Limit is the page size, 50 by default and at most 200.
+	Limit int
+	// Offset is the number of users to skip.
+	Offset int
+}
+
+const defaultPageSize = 50
+
+func (opts ListUsersOptions) query() url.Values {
+	query := url.Values{}
+	if opts.IncludeDeleted {
+		query.Set("include_deleted", "true")
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(opts.Offset))
+	return query
+}
+
+// ListUsersPage returns one page of users ordered by ID.
+func (c *Client) ListUsersPage(ctx context.Context, opts ListUsersOptions) ([]User, error) {
+	var users []User
+	if err := c.do(ctx, http.MethodGet, "/users", opts.query(), nil, call{}, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ListUsers iterates over the users ordered by ID, fetching a page of
+// opts.Limit users at a time starting at opts.Offset. Iteration stops after
+// the first error, which is yielded with a zero User.
+func (c *Client) ListUsers(ctx context.Context, opts ListUsersOptions) iter.Seq2[User, error] {
+	return func(yield func(User, error) bool) {
+		if opts.Limit <= 0 {
+			opts.Limit = defaultPageSize
+		}
+		for {
+			users, err := c.ListUsersPage(ctx, opts)
+			if err != nil {
+				yield(User{}, err)
+				return
+			}
+			for _, user := range users {
+				if !yield(user, nil) {
+					return
+				}
+			}
+			if len(users) < opts.Limit {
+				return
+			}
+			opts.Offset += len(users)
+		}
+	}
+}
+
+func userPath(id int32) string {
+	return "/users/" + strconv.FormatInt(int64(id), 10)
+}
+
+// GetUser returns the user with id. A user that does not exist or was
+// deleted is an error matching ErrNotFound.
+func (c *Client) GetUser(ctx context.Context, id int32) (User, error) {
+	var user User
+	err := c.do(ctx, http.MethodGet, userPath(id), nil, nil, call{}, &user)
+	return user, err
+}
+
+// CreateUser creates a user. The request carries an idempotency key, a
+// random one unless WithIdempotencyKey gives it, so it is safe to retry.
+func (c *Client) CreateUser(ctx context.Context, req CreateUserRequest, opts ...CallOption) (User, error) {
+	co := newCall(opts)
+	if co.idempotencyKey == "" {
+		co.idempotencyKey = NewIdempotencyKey()
+	}
+
+	var user User
+	err := c.do(ctx, http.MethodPost, "/users", nil, req, co, &user)
+	return user, err
+}
+
+// UpdateUser changes the fields of the user with id that req sets.
+func (c *Client) UpdateUser(ctx context.Context, id int32, req UpdateUserRequest, opts ...CallOption) (User, error) {
+	var user User
+	err := c.do(ctx, http.MethodPut, userPath(id), nil, req, newCall(opts), &user)
+	return user, err
+}
+
+// DeleteUser soft-deletes the user with id.
+func (c *Client) DeleteUser(ctx context.Context, id int32, opts ...CallOption) error {
+	return c.do(ctx, http.MethodDelete, userPath(id), nil, nil, newCall(opts), nil)
+}
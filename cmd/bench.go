@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// bench runs the bench subcommand, which sends the same request to each
+// server over one protocol and reports the throughput and latency, failing
+// if any response came back over another protocol:
+//
+//	go run ./cmd bench [-proto h1|h2|h2c] [-path /openapi.json] [-n 1000] [-c 10] [-targets url,...]
+//
+// h1 is HTTP/1.1 over plain HTTP or TLS, h2 is HTTP/2 over TLS and h2c is
+// HTTP/2 over plain HTTP with prior knowledge. With h2c the servers are also
+// checked to upgrade an HTTP/1.1 request to h2c.
+func bench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	proto := fs.String("proto", "h1", "protocol to send requests with: h1, h2 or h2c")
+	path := fs.String("path", "/openapi.json", "path to request on every server")
+	n := fs.Int("n", 1000, "number of requests per server")
+	c := fs.Int("c", 10, "number of requests in flight at once")
+	targets := fs.String("targets", "", "comma separated server URLs, by default the six local servers over http, or https for h2")
+	caFile := fs.String("cacert", "", "CA certificate to verify HTTPS servers with")
+	fs.Parse(args)
+
+	if *n <= 0 || *c <= 0 {
+		log.Fatal("-n and -c must be positive")
+	}
+
+	tlsConfig := &tls.Config{}
+	if *caFile != "" {
+		pem, err := os.ReadFile(*caFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			log.Fatalf("No certificates found in %s", *caFile)
+		}
+	}
+
+	var transport http.RoundTripper
+	var wantProto string
+	switch *proto {
+	case "h1":
+		// An empty TLSNextProto keeps HTTP/2 from being negotiated over TLS
+		transport = &http.Transport{
+			TLSClientConfig:     tlsConfig,
+			TLSNextProto:        map[string]func(string, *tls.Conn) http.RoundTripper{},
+			MaxIdleConnsPerHost: *c,
+		}
+		wantProto = "HTTP/1.1"
+	case "h2":
+		transport = &http2.Transport{TLSClientConfig: tlsConfig}
+		wantProto = "HTTP/2.0"
+	case "h2c":
+		transport = &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		}
+		wantProto = "HTTP/2.0"
+	default:
+		log.Fatalf("Unknown protocol %q: must be h1, h2 or h2c", *proto)
+	}
+	client := &http.Client{Transport: transport}
+
+	urls := strings.Split(*targets, ",")
+	if *targets == "" {
+		scheme := "http"
+		if *proto == "h2" {
+			scheme = "https"
+		}
+		urls = nil
+		for port := 8000; port <= 8005; port++ {
+			urls = append(urls, fmt.Sprintf("%s://localhost:%d", scheme, port))
+		}
+	}
+
+	failed := false
+	for _, target := range urls {
+		target = strings.TrimRight(strings.TrimSpace(target), "/")
+		result := run(client, target+*path, *n, *c)
+		fmt.Println(result.report(target))
+
+		if result.errors > 0 || len(result.protos) != 1 || result.protos[wantProto] == 0 {
+			fmt.Printf("  FAIL: expected every response over %s\n", wantProto)
+			failed = true
+		}
+		if *proto == "h2c" {
+			if err := checkUpgrade(target + *path); err != nil {
+				fmt.Printf("  FAIL: h2c upgrade: %v\n", err)
+				failed = true
+			} else {
+				fmt.Println("  h2c upgrade: 101 Switching Protocols")
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+type benchResult struct {
+	elapsed   time.Duration
+	latencies []time.Duration
+	statuses  map[int]int
+	protos    map[string]int
+	errors    int
+	lastError error
+}
+
+func run(client *http.Client, target string, n, c int) *benchResult {
+	result := &benchResult{statuses: map[int]int{}, protos: map[string]int{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	requests := make(chan struct{})
+
+	start := time.Now()
+	for range c {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range requests {
+				sent := time.Now()
+				res, err := client.Get(target)
+				if err == nil {
+					// Read the whole body so the connection is reused
+					_, err = io.Copy(io.Discard, res.Body)
+					res.Body.Close()
+				}
+				latency := time.Since(sent)
+
+				mu.Lock()
+				if err != nil {
+					result.errors++
+					result.lastError = err
+				} else {
+					result.latencies = append(result.latencies, latency)
+					result.statuses[res.StatusCode]++
+					result.protos[res.Proto]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for range n {
+		requests <- struct{}{}
+	}
+	close(requests)
+	wg.Wait()
+	result.elapsed = time.Since(start)
+	return result
+}
+
+func (r *benchResult) report(target string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d requests in %v, %.0f requests/s\n", target,
+		len(r.latencies)+r.errors, r.elapsed.Round(time.Millisecond),
+		float64(len(r.latencies)+r.errors)/r.elapsed.Seconds())
+
+	if len(r.latencies) > 0 {
+		slices.Sort(r.latencies)
+		percentile := func(p int) time.Duration {
+			return r.latencies[(len(r.latencies)-1)*p/100].Round(time.Microsecond)
+		}
+		fmt.Fprintf(&b, "  latency p50 %v, p90 %v, p99 %v, max %v\n",
+			percentile(50), percentile(90), percentile(99), percentile(100))
+	}
+
+	fmt.Fprintf(&b, "  protocols %v, statuses %v", r.protos, r.statuses)
+	if r.errors > 0 {
+		fmt.Fprintf(&b, "\n  %d errors, the last: %v", r.errors, r.lastError)
+	}
+	return b.String()
+}
+
+// checkUpgrade sends an HTTP/1.1 request asking to upgrade to h2c and
+// expects the server to agree.
+func checkUpgrade(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", u.Host, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// HTTP2-Settings carries an empty SETTINGS payload, base64url encoded
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\nConnection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: \r\n\r\n",
+		u.RequestURI(), u.Host)
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("got %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/imports"
+)
+
+// importUsers runs the import subcommand, which imports a file the same way
+// POST /users/import does but waits for it to finish:
+//
+//	go run ./cmd import [-format csv] [-on-duplicate fail] [-dry-run] [-mapping name:Full Name] [-errors report.csv] users.csv
+func importUsers(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", "", "csv or ndjson, guessed from the file extension by default")
+	onDuplicate := fs.String("on-duplicate", "fail", "what to do with rows whose email is in use: skip, fail or upsert")
+	dryRun := fs.Bool("dry-run", false, "validate and check every row without saving anything")
+	mapping := fs.String("mapping", "", "source columns of the user fields, such as name:Full Name,email:Mail")
+	errorsPath := fs.String("errors", "", "write the rows that failed to this CSV file")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		log.Fatal("Usage: import [flags] FILE")
+	}
+	path := fs.Arg(0)
+
+	columnMap, err := imports.ParseColumnMap(*mapping)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := imports.Options{
+		Format:      *format,
+		OnDuplicate: *onDuplicate,
+		DryRun:      *dryRun,
+		ColumnMap:   columnMap,
+	}
+	if opts.Format == "" {
+		opts.Format = imports.FormatFromContentType("", path)
+	}
+	if err := opts.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", path, err)
+	}
+
+	cfg := config.ApiCfg()
+	defer cfg.Close()
+
+	// The import is started here rather than left for a server to claim
+	meta := audit.Meta{Actor: "cli", RequestID: "cli", Framework: "cli"}
+	imp, err := imports.Create(ctx, cfg, meta, opts, data, true)
+	if err != nil {
+		log.Fatalf("Failed to create import: %v", err)
+	}
+
+	if err := imports.Process(ctx, cfg, imp); err != nil {
+		log.Fatalf("Failed to process import %d: %v", imp.ID, err)
+	}
+
+	imp, err = cfg.DB.GetUserImport(context.WithoutCancel(ctx), imp.ID)
+	if err != nil {
+		log.Fatalf("Failed to read import %d: %v", imp.ID, err)
+	}
+
+	fmt.Printf("Import %d %s: %d rows, %d created, %d updated, %d skipped, %d failed\n",
+		imp.ID, imp.Status, imp.TotalRows, imp.CreatedRows, imp.UpdatedRows, imp.SkippedRows, imp.FailedRows)
+	if imp.DryRun {
+		fmt.Println("Dry run, nothing was saved")
+	}
+	if imp.Error.Valid {
+		log.Fatal(imp.Error.String)
+	}
+
+	if *errorsPath != "" {
+		if err := writeImportErrors(ctx, cfg, imp.ID, *errorsPath); err != nil {
+			log.Fatalf("Failed to write %s: %v", *errorsPath, err)
+		}
+	}
+}
+
+func writeImportErrors(ctx context.Context, cfg *config.APIConfig, importID int32, path string) error {
+	rowErrors, err := cfg.DB.ListUserImportErrors(ctx, importID)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"row", "email", "error"})
+	for _, rowError := range rowErrors {
+		cw.Write([]string{strconv.Itoa(int(rowError.RowNumber)), rowError.Email, rowError.Error})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -1,64 +1,313 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/certs"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/cors"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/failfast"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/idempotency"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/imports"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/jobs"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/loadshed"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/metrics"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/middleware"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/negotiate"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/openapi"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/purge"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/route"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/routers"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/security"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/sticky"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/timeout"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/webhooks"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 func main() {
 	godotenv.Load()
 
-	standardRouter := routers.StandardRouter()
-	httpRouter := routers.HttpRouter()
-	muxRouter := routers.MuxRouter()
-	chiRouter := routers.ChiRouter()
-	e := routers.EchoRouter()
-	r := routers.GinRouter()
+	// Cancelled on SIGINT or SIGTERM to shut everything down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		importUsers(ctx, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "openapi" {
+		openAPI(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		bench(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "tls-cert" {
+		tlsCert(os.Args[2:])
+		return
+	}
+
+	// Background work shares one config and connection pool
+	cfg := config.ApiCfg()
+	defer cfg.Close()
+
+	// User events broker, shared by the event streams of every router
+	broker := events.NewBroker(cfg)
+	go broker.Run(ctx)
 
 	gin.SetMode(gin.ReleaseMode)
 
+	// Every response carries the security headers, and browser apps on the
+	// allowed origins may call the API
+	secure := security.New(cfg.Security).Middleware
+	crossOrigin := cors.New(cfg.CORS).Middleware
+
+	// Responses are compressed, and sent as JSON, XML or MessagePack, as the
+	// Accept-Encoding and Accept headers of the request prefer
+	compress := negotiate.NewCompressor(cfg.Compression).Middleware
+
+	// Requests, and in debug mode responses, are optionally checked against
+	// the OpenAPI document before they reach the routers
+	validate := openapi.NewValidator(cfg.Validation).Middleware
+
+	// The server chain wraps each router and sees every request, matched or
+	// not, in this order, along with the route it matched once it has been
+	// answered
+	server := func(framework string) middleware.Chain {
+		chain := middleware.Chain{route.Capture}
+		if cfg.Logging.Requests {
+			chain = append(chain, middleware.Logger(framework))
+		}
+		return append(chain, secure, crossOrigin, compress, negotiate.Middleware, validate)
+	}
+
+	// The route chain runs inside each router once a route has matched, with
+	// its path parameters. Requests over the concurrency limits of the
+	// server and route are shed first, by priority. Requests the database
+	// could not serve, the Idempotency-Key check included, get a 503 rather
+	// than a 500. Writes, and the reads of a client that just wrote, read
+	// from the primary rather than the replicas. POST and PATCH requests
+	// with an Idempotency-Key run once per key, and within the timeout of
+	// their route, so the key is released even if the request times out
+	idempotent := idempotency.New(cfg).Middleware
+	readYourWrites := sticky.New(cfg.Database).Middleware
+	routeChain := func(framework string) middleware.Chain {
+		return middleware.Chain{
+			loadshed.New(cfg.Limits[framework], framework).Middleware,
+			failfast.New(framework).Middleware,
+			readYourWrites,
+			idempotent,
+			timeout.New(cfg.Timeouts, framework).Middleware,
+		}
+	}
+
+	// Each router has a config and connection pool of its own, closed once
+	// its server has shut down
+	routerCfgs := make([]*config.APIConfig, len(config.Frameworks))
+	for i := range routerCfgs {
+		routerCfgs[i] = config.ApiCfg()
+		defer routerCfgs[i].Close()
+	}
+
+	// One server per router, in the order of config.Frameworks
+	routes := []http.Handler{
+		routers.StandardRouter(routerCfgs[0], broker, routeChain("standard")...),
+		routers.HttpRouter(routerCfgs[1], broker, routeChain("httprouter")...),
+		routers.MuxRouter(routerCfgs[2], broker, routeChain("mux")...),
+		routers.ChiRouter(routerCfgs[3], broker, routeChain("chi")...),
+		routers.EchoRouter(routerCfgs[4], broker, routeChain("echo")...),
+		routers.GinRouter(routerCfgs[5], broker, routeChain("gin")...),
+	}
+	servers := make([]*http.Server, len(routes))
+	for i, framework := range config.Frameworks {
+		servers[i] = &http.Server{
+			Addr:    fmt.Sprintf(":%d", 8000+i),
+			Handler: server(framework).Then(routes[i]),
+		}
+	}
+
+	// Servers with a certificate serve HTTPS, and HTTP/2 along with it,
+	// reloading the certificate when its files change
+	var redirects []*http.Server
+	for i, framework := range config.Frameworks {
+		tlsCfg := cfg.TLS.Listeners[framework]
+		if !tlsCfg.Enabled() {
+			continue
+		}
+		listener, err := certs.New(tlsCfg)
+		if err != nil {
+			log.Fatalf("Error loading the %s certificate: %v", framework, err)
+		}
+		servers[i].TLSConfig = listener.TLSConfig()
+		go listener.Watch(ctx, cfg.TLS.ReloadInterval)
+
+		if tlsCfg.RedirectAddr != "" {
+			redirects = append(redirects, &http.Server{Addr: tlsCfg.RedirectAddr, Handler: certs.Redirect(servers[i].Addr)})
+		}
+	}
+
+	// Header limits, keep-alives and HTTP/2, over TLS or as h2c, per server
+	for i, framework := range config.Frameworks {
+		configure(servers[i], cfg.Servers[framework])
+	}
+
 	// Standard lib routine
-	go func() {
-		log.Fatal(http.ListenAndServe(":8000", standardRouter))
-	}()
-	fmt.Println("Standard router running at http://localhost:8000")
+	go serve(servers[0])
+	fmt.Println("Standard router running at", address(servers[0]))
 
 	// httprouter routine
-	go func() {
-		log.Fatal(http.ListenAndServe(":8001", httpRouter))
-	}()
-	fmt.Println("HttpRouter running at http://localhost:8001")
+	go serve(servers[1])
+	fmt.Println("HttpRouter running at", address(servers[1]))
 
 	// Mux router routine
-	go func() {
-		log.Fatal(http.ListenAndServe(":8002", muxRouter))
-	}()
-	fmt.Println("Mux router running at http://localhost:8002")
+	go serve(servers[2])
+	fmt.Println("Mux router running at", address(servers[2]))
 
 	// Chi router routine
-	go func() {
-		log.Fatal(http.ListenAndServe(":8003", chiRouter))
-	}()
-	fmt.Println("Chi router running at http://localhost:8003")
+	go serve(servers[3])
+	fmt.Println("Chi router running at", address(servers[3]))
 
 	// Echo routine
-	go func() {
-		if err := e.Start(":8004"); err != http.ErrServerClosed {
-			log.Fatal(err)
-		}
-	}()
-	fmt.Println("Echo running at http://localhost:8004")
+	go serve(servers[4])
+	fmt.Println("Echo running at", address(servers[4]))
 
 	// Gin routine
+	go serve(servers[5])
+	fmt.Println("Gin running at", address(servers[5]))
+
+	// HTTP to HTTPS redirect routines
+	for _, srv := range redirects {
+		go serve(srv)
+		fmt.Printf("Redirecting http://localhost%s to HTTPS\n", srv.Addr)
+	}
+
+	// Metrics routine, on a port of its own so it is not exposed with the API
+	var monitoring []*http.Server
+	if cfg.Metrics.Addr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", metrics.Handler())
+		srv := &http.Server{Addr: cfg.Metrics.Addr, Handler: metricsMux}
+		monitoring = append(monitoring, srv)
+		go serve(srv)
+		fmt.Printf("Metrics served at http://localhost%s/metrics\n", srv.Addr)
+	}
+
+	// Outbox dispatcher routine
+	sinks, err := outbox.NewSinks(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sinks = append(sinks, webhooks.NewFanoutSink(cfg.DB))
+	go outbox.NewDispatcher(cfg, sinks).Run(ctx)
+
+	// Webhook delivery routine
+	go webhooks.NewDeliverer(cfg, nil).Run(ctx)
+
+	// Background job routine: user imports, the purge of soft-deleted users
+	// and the deletion of expired idempotency keys
+	purgeSchedule, err := jobs.ParseSchedule(cfg.PurgeSchedule)
+	if err != nil {
+		log.Fatalf("Invalid PURGE_SCHEDULE: %v", err)
+	}
+	idempotencySchedule, err := jobs.ParseSchedule(cfg.Idempotency.CleanupSchedule)
+	if err != nil {
+		log.Fatalf("Invalid IDEMPOTENCY_CLEANUP_SCHEDULE: %v", err)
+	}
+	runner := jobs.NewRunner(cfg)
+	imports.Register(runner, cfg)
+	purge.Register(runner, cfg, purgeSchedule)
+	idempotency.Register(runner, cfg, idempotencySchedule)
+	jobsDone := make(chan struct{})
 	go func() {
-		r.Run(":8005")
+		runner.Run(ctx)
+		close(jobsDone)
 	}()
-	fmt.Println("Gin running at http://localhost:8005")
 
-	select {}
+	<-ctx.Done()
+	fmt.Println("Shutting down")
+
+	// Event streams and WebSockets never finish on their own, and hijacked
+	// connections are not tracked by Shutdown, so close them first.
+	broker.Close()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, srv := range slices.Concat(servers, redirects, monitoring) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Error shutting down server on %s: %v", srv.Addr, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Running jobs get the drain timeout to finish before they are requeued
+	<-jobsDone
+}
+
+// How long in-flight requests get to finish once shutdown starts.
+const shutdownTimeout = 15 * time.Second
+
+func serve(srv *http.Server) {
+	var err error
+	if srv.TLSConfig != nil {
+		// The certificate comes from the TLS configuration
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
+
+// configure applies the protocol and connection settings of cfg to srv,
+// whose TLS configuration must be set already.
+func configure(srv *http.Server, cfg config.ServerConfig) {
+	srv.MaxHeaderBytes = cfg.MaxHeaderBytes
+	srv.IdleTimeout = cfg.IdleTimeout
+	srv.SetKeepAlivesEnabled(cfg.KeepAlives)
+
+	h2 := &http2.Server{
+		MaxConcurrentStreams: uint32(cfg.MaxConcurrentStreams),
+		IdleTimeout:          cfg.IdleTimeout,
+	}
+	switch {
+	case srv.TLSConfig != nil:
+		if err := http2.ConfigureServer(srv, h2); err != nil {
+			log.Fatalf("Error configuring HTTP/2 on %s: %v", srv.Addr, err)
+		}
+	case cfg.H2C:
+		// h2c connections are hijacked from the HTTP/1 server, so Shutdown
+		// does not wait for them
+		srv.Handler = h2c.NewHandler(srv.Handler, h2)
+	}
+}
+
+// address is the URL the server can be reached at locally.
+func address(srv *http.Server) string {
+	if srv.TLSConfig != nil {
+		return "https://localhost" + srv.Addr
+	}
+	return "http://localhost" + srv.Addr
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/openapi"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/routers"
+)
+
+// openAPI runs the openapi subcommand, which prints the OpenAPI document or,
+// with -check, fails when the routes of any router differ from it, as
+// TestRoutersMatchOpenAPI does:
+//
+//	go run ./cmd openapi [-check]
+func openAPI(args []string) {
+	fs := flag.NewFlagSet("openapi", flag.ExitOnError)
+	check := fs.Bool("check", false, "compare the routes of every router with the document instead of printing it")
+	fs.Parse(args)
+
+	if !*check {
+		os.Stdout.Write(openapi.JSON())
+		fmt.Println()
+		return
+	}
+
+	// The routers are only built, so they need no database, and the config
+	// none of the environment
+	cfg := &config.APIConfig{}
+	broker := events.NewBroker(cfg)
+
+	chiRoutes, err := routers.ChiRoutes(routers.ChiRouter(cfg, broker))
+	if err != nil {
+		log.Fatal(err)
+	}
+	muxRoutes, err := routers.MuxRoutes(routers.MuxRouter(cfg, broker))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	served := []struct {
+		name      string
+		endpoints []openapi.Endpoint
+	}{
+		{"standard", routers.StandardRoutes(routers.StandardRouter(cfg, broker))},
+		{"httprouter", routers.HttpRoutes(routers.HttpRouter(cfg, broker))},
+		{"mux", muxRoutes},
+		{"chi", chiRoutes},
+		{"echo", routers.EchoRoutes(routers.EchoRouter(cfg, broker))},
+		{"gin", routers.GinRoutes(routers.GinRouter(cfg, broker))},
+	}
+
+	failed := false
+	for _, router := range served {
+		if err := openapi.Check(router.endpoints); err != nil {
+			fmt.Printf("%s: %v\n", router.name, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: %d routes match\n", router.name, len(router.endpoints))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tlsCert runs the tls-cert subcommand, which writes a self-signed CA along
+// with a server and a client certificate signed by it, for trying out TLS
+// and client certificates locally:
+//
+//	go run ./cmd tls-cert [-dir certs] [-hosts localhost,127.0.0.1,::1] [-client admin]
+func tlsCert(args []string) {
+	fs := flag.NewFlagSet("tls-cert", flag.ExitOnError)
+	dir := fs.String("dir", "certs", "directory to write the certificates and keys to")
+	hosts := fs.String("hosts", "localhost,127.0.0.1,::1", "comma separated host names and IP addresses of the server certificate")
+	client := fs.String("client", "admin", "common name, and so identity, of the client certificate")
+	validFor := fs.Duration("valid-for", 365*24*time.Hour, "how long the certificates are valid")
+	fs.Parse(args)
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+
+	notAfter := time.Now().Add(*validFor)
+	ca := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "go-frameworks-crud local CA"},
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caKey := writeCertificate(*dir, "ca", ca, ca, nil)
+
+	server := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range strings.Split(*hosts, ",") {
+		host = strings.TrimSpace(host)
+		if ip := net.ParseIP(host); ip != nil {
+			server.IPAddresses = append(server.IPAddresses, ip)
+		} else if host != "" {
+			server.DNSNames = append(server.DNSNames, host)
+		}
+	}
+	writeCertificate(*dir, "server", server, ca, caKey)
+
+	clientCert := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: *client},
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	writeCertificate(*dir, "client", clientCert, ca, caKey)
+
+	fmt.Printf("Wrote ca, server and client certificates and keys to %s\n", *dir)
+}
+
+// writeCertificate creates a key for template, signs the certificate with
+// parentKey, or with the new key itself when parentKey is nil, and writes
+// both as <name>.pem and <name>-key.pem.
+func writeCertificate(dir, name string, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if parentKey == nil {
+		parentKey = key
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		log.Fatal(err)
+	}
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	writePEM(filepath.Join(dir, name+".pem"), "CERTIFICATE", der, 0o644)
+	writePEM(filepath.Join(dir, name+"-key.pem"), "PRIVATE KEY", keyDER, 0o600)
+	return key
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, perm); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/certs"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+)
+
+// The certificates written by tls-cert serve TLS with client certificates:
+// the server is trusted through the CA, and the client is identified by the
+// name it was given.
+func TestTLSCertServesClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	tlsCert([]string{"-dir", dir, "-hosts", "localhost, 127.0.0.1", "-client", "ops", "-valid-for", "48h"})
+
+	for name, perm := range map[string]os.FileMode{"ca-key.pem": 0o600, "server-key.pem": 0o600, "client-key.pem": 0o600, "server.pem": 0o644} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != perm {
+			t.Errorf("%s: mode %v, want %v", name, info.Mode().Perm(), perm)
+		}
+	}
+
+	l, err := certs.New(config.TLSListenerConfig{
+		CertFile:     filepath.Join(dir, "server.pem"),
+		KeyFile:      filepath.Join(dir, "server-key.pem"),
+		MinVersion:   tls.VersionTLS12,
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, config.ClientIdentity(r))
+		}),
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+	go srv.Serve(tls.NewListener(ln, l.TLSConfig()))
+	defer srv.Close()
+
+	caPEM, err := os.ReadFile(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		t.Fatal("ca.pem holds no certificate")
+	}
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both host names of the server certificate are trusted
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	for _, host := range []string{"localhost", "127.0.0.1"} {
+		c := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}, ServerName: host},
+		}}
+		res, err := c.Get("https://" + net.JoinHostPort("127.0.0.1", port))
+		if err != nil {
+			t.Fatalf("%s: %v", host, err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != "ops" {
+			t.Errorf("%s: client identity %q, want ops", host, body)
+		}
+		if notAfter := res.TLS.PeerCertificates[0].NotAfter; notAfter.After(time.Now().Add(48*time.Hour)) || notAfter.Before(time.Now().Add(47*time.Hour)) {
+			t.Errorf("%s: server certificate valid until %v, want in 48h", host, notAfter)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+)
+
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+)
+
+// Meta describes who made a change and through which server.
+type Meta struct {
+	Actor     string
+	RequestID string
+	Framework string
+}
+
+// System is used for changes made by background jobs rather than requests.
+var System = Meta{Actor: "system", Framework: "system"}
+
+// FromRequest builds the audit metadata for a request. Admins are recorded as
+// "admin"; clients with a certificate as its identity; everyone else as the
+// self-reported X-Actor header, if any.
+func FromRequest(cfg *config.APIConfig, r *http.Request, framework string) Meta {
+	actor := r.Header.Get("X-Actor")
+	if identity := config.ClientIdentity(r); identity != "" {
+		actor = identity
+	}
+	if cfg.IsAdmin(r) {
+		actor = "admin"
+	}
+	if actor == "" {
+		actor = "anonymous"
+	}
+
+	requestID := r.Header.Get("X-Request-ID")
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+
+	return Meta{Actor: actor, RequestID: requestID, Framework: framework}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Change is the before and after value of a single field.
+type Change struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// Diff returns the fields that differ between two versions of a user. A nil
+// before means the user was created; a nil after means it was purged.
+func Diff(before, after *database.User) map[string]Change {
+	from, to := fields(before), fields(after)
+
+	changes := map[string]Change{}
+	for name := range to {
+		if from[name] != to[name] {
+			changes[name] = Change{From: from[name], To: to[name]}
+		}
+	}
+
+	return changes
+}
+
+func fields(user *database.User) map[string]any {
+	values := map[string]any{"name": nil, "email": nil, "age": nil, "deleted_at": nil}
+	if user == nil {
+		return values
+	}
+
+	values["name"] = user.Name
+	values["email"] = user.Email
+	values["age"] = user.Age
+	if user.DeletedAt.Valid {
+		values["deleted_at"] = user.DeletedAt.Time.UTC()
+	}
+
+	return values
+}
+
+// Record writes an audit event using q, which should be bound to the same
+// transaction as the change itself.
+func Record(ctx context.Context, q *database.Queries, meta Meta, action string, before, after *database.User) error {
+	changes, err := json.Marshal(Diff(before, after))
+	if err != nil {
+		return err
+	}
+
+	userID := int32(0)
+	if after != nil {
+		userID = after.ID
+	} else if before != nil {
+		userID = before.ID
+	}
+
+	_, err = q.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+		UserID:    userID,
+		Action:    action,
+		Actor:     meta.Actor,
+		RequestID: meta.RequestID,
+		Framework: meta.Framework,
+		Changes:   changes,
+	})
+	return err
+}
@@ -0,0 +1,186 @@
+// Package certs serves HTTPS from certificate files, picking up renewed
+// certificates without a restart, and redirects plain HTTP to it.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+)
+
+// The TLS 1.2 cipher suites of the strict policy: ECDHE key exchange for
+// forward secrecy and AEAD ciphers only. TLS 1.3 suites are not
+// configurable and all meet this already.
+var strictCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// Listener holds the TLS configuration of one server, rebuilt whenever its
+// certificate, key or client CA files change.
+type Listener struct {
+	cfg     config.TLSListenerConfig
+	current atomic.Pointer[tls.Config]
+	// When the files were last read, to tell whether they changed since.
+	modTimes []time.Time
+}
+
+// New reads the files of cfg, failing if they do not make a usable
+// configuration.
+func New(cfg config.TLSListenerConfig) (*Listener, error) {
+	l := &Listener{cfg: cfg}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Listener) files() []string {
+	files := []string{l.cfg.CertFile, l.cfg.KeyFile}
+	if l.cfg.ClientCAFile != "" {
+		files = append(files, l.cfg.ClientCAFile)
+	}
+	return files
+}
+
+func (l *Listener) load() error {
+	var modTimes []time.Time
+	for _, file := range l.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.cfg.CertFile, l.cfg.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   l.cfg.MinVersion,
+		ClientAuth:   l.cfg.ClientAuth,
+		// Offering h2 is what turns HTTP/2 on for the server
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	if l.cfg.CipherPolicy == "strict" {
+		tlsConfig.CipherSuites = strictCipherSuites
+	}
+
+	if l.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(l.cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", l.cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+	}
+
+	l.current.Store(tlsConfig)
+	l.modTimes = modTimes
+	return nil
+}
+
+// changed reports whether any of the files was modified since it was read.
+func (l *Listener) changed() bool {
+	for i, file := range l.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			// Likely in the middle of being replaced, so check again later
+			return false
+		}
+		if !info.ModTime().Equal(l.modTimes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// TLSConfig returns the configuration for the server. Every handshake uses
+// the files as last read, so a reload applies to new connections only.
+func (l *Listener) TLSConfig() *tls.Config {
+	current := l.current.Load()
+	return &tls.Config{
+		// The server reads these to set up HTTP/2; the handshake itself uses
+		// the configuration GetConfigForClient returns
+		MinVersion:   current.MinVersion,
+		CipherSuites: current.CipherSuites,
+		NextProtos:   current.NextProtos,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &l.current.Load().Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return l.current.Load(), nil
+		},
+	}
+}
+
+// Watch reloads the files every interval if they changed, until ctx is
+// cancelled. A failed reload keeps the configuration that was working.
+func (l *Listener) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !l.changed() {
+				continue
+			}
+			if err := l.load(); err != nil {
+				log.Printf("Error reloading certificate %s: %v", l.cfg.CertFile, err)
+				continue
+			}
+			log.Printf("Reloaded certificate %s", l.cfg.CertFile)
+		}
+	}
+}
+
+// Redirect answers every request with a permanent redirect to the same URL
+// on the HTTPS server listening on addr.
+func Redirect(addr string) http.Handler {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		port = ""
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			http.Error(w, "Bad Request: Missing Host header", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case port != "" && port != "443":
+			host = net.JoinHostPort(host, port)
+		case strings.Contains(host, ":"):
+			host = "[" + host + "]"
+		}
+		// 308 keeps the method and body of the request, unlike 301
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,289 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+)
+
+// authority is a CA created for a test, signing the certificates it needs.
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+	dir  string
+}
+
+func newAuthority(t *testing.T) *authority {
+	t.Helper()
+	a := &authority{dir: t.TempDir()}
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test CA"},
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, key := a.sign(t, template)
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.cert, a.key = cert, key
+	a.pool = x509.NewCertPool()
+	a.pool.AddCert(cert)
+	writePEM(t, filepath.Join(a.dir, "ca.pem"), "CERTIFICATE", der)
+	return a
+}
+
+// sign creates a key for template and a certificate signed by the CA, or by
+// the key itself for the CA.
+func (a *authority) sign(t *testing.T, template *x509.Certificate) ([]byte, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parent, parentKey := template, key
+	if a.cert != nil {
+		parent, parentKey = a.cert, a.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der, key
+}
+
+// server writes a server certificate for 127.0.0.1 with common name cn and
+// returns the paths of it and its key.
+func (a *authority) server(t *testing.T, cn string) (string, string) {
+	t.Helper()
+	der, key := a.sign(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: cn},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	certFile, keyFile := filepath.Join(a.dir, "server.pem"), filepath.Join(a.dir, "server-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writeKey(t, keyFile, key)
+	return certFile, keyFile
+}
+
+// client returns a client certificate with common name cn.
+func (a *authority) client(t *testing.T, cn string) tls.Certificate {
+	t.Helper()
+	der, key := a.sign(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: cn},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeKey(t *testing.T, path string, key *ecdsa.PrivateKey) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, path, "PRIVATE KEY", der)
+}
+
+// serve serves h over TLS with the configuration of l, returning its URL.
+func serve(t *testing.T, l *Listener, h http.Handler) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: h, ErrorLog: log.New(io.Discard, "", 0)}
+	go srv.Serve(tls.NewListener(ln, l.TLSConfig()))
+	t.Cleanup(func() { srv.Close() })
+	return "https://" + ln.Addr().String()
+}
+
+// httpClient trusts the CA and presents the given client certificates.
+func (a *authority) httpClient(certs ...tls.Certificate) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: a.pool, Certificates: certs},
+		// A new connection per request, so each sees the current files
+		DisableKeepAlives: true,
+	}}
+}
+
+func get(c *http.Client, url string) (string, error) {
+	res, err := c.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return string(body), err
+}
+
+func TestHandshake(t *testing.T) {
+	a := newAuthority(t)
+	certFile, keyFile := a.server(t, "server")
+	l, err := New(config.TLSListenerConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: tls.VersionTLS12, CipherPolicy: "strict"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.TLS.ServerName == "" && r.TLS.HandshakeComplete)
+	}))
+
+	body, err := get(a.httpClient(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "true" {
+		t.Fatalf("handshake not complete: %s", body)
+	}
+
+	// A client that does not trust the CA refuses the server
+	if _, err := get(http.DefaultClient, url); err == nil {
+		t.Fatal("a client without the CA connected")
+	}
+}
+
+func TestClientCertificateRequired(t *testing.T) {
+	a := newAuthority(t)
+	certFile, keyFile := a.server(t, "server")
+	l, err := New(config.TLSListenerConfig{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		MinVersion:   tls.VersionTLS12,
+		ClientCAFile: filepath.Join(a.dir, "ca.pem"),
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+
+	if _, err := get(a.httpClient(), url); err == nil {
+		t.Fatal("a client without a certificate connected")
+	}
+
+	other := newAuthority(t)
+	if _, err := get(a.httpClient(other.client(t, "admin")), url); err == nil {
+		t.Fatal("a client with a certificate of another CA connected")
+	}
+
+	if _, err := get(a.httpClient(a.client(t, "admin")), url); err != nil {
+		t.Fatalf("a client with a certificate of the CA: %v", err)
+	}
+}
+
+func TestClientIdentityGrantsAdmin(t *testing.T) {
+	a := newAuthority(t)
+	certFile, keyFile := a.server(t, "server")
+	l, err := New(config.TLSListenerConfig{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		MinVersion:   tls.VersionTLS12,
+		ClientCAFile: filepath.Join(a.dir, "ca.pem"),
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.APIConfig{TLS: config.TLSConfig{AdminIdentities: []string{"admin"}}}
+	url := serve(t, l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %t", config.ClientIdentity(r), cfg.IsAdmin(r))
+	}))
+
+	tests := []struct {
+		name  string
+		certs []tls.Certificate
+		want  string
+	}{
+		{"admin", []tls.Certificate{a.client(t, "admin")}, "admin true"},
+		{"other", []tls.Certificate{a.client(t, "reporting")}, "reporting false"},
+		{"none", nil, " false"},
+	}
+	for _, tt := range tests {
+		body, err := get(a.httpClient(tt.certs...), url)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if body != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, body, tt.want)
+		}
+	}
+}
+
+func TestWatchReloadsRewrittenFiles(t *testing.T) {
+	a := newAuthority(t)
+	certFile, keyFile := a.server(t, "first")
+	l, err := New(config.TLSListenerConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: tls.VersionTLS12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.Watch(ctx, 10*time.Millisecond)
+
+	served := func() string {
+		res, err := a.httpClient().Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	if cn := served(); cn != "first" {
+		t.Fatalf("serving %q, want first", cn)
+	}
+
+	// Rewrite the files, with a modification time the watcher cannot miss
+	a.server(t, "second")
+	later := time.Now().Add(time.Minute)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for served() != "second" {
+		if time.Now().After(deadline) {
+			t.Fatal("the rewritten certificate was not picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
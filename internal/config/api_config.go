@@ -1,15 +1,54 @@
 package config
 
 import (
+	"context"
+	"crypto/subtle"
 	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type APIConfig struct {
-	DB   *database.Queries
-	pool *pgxpool.Pool
+	DB         *database.Queries
+	AdminToken string
+	// How long soft-deleted users are kept before being purged for good,
+	// and when the purge job looks for them, as a cron expression or
+	// "@every <duration>".
+	DeletedUserRetention time.Duration
+	PurgeSchedule        string
+	Outbox               OutboxConfig
+	Webhooks             WebhookConfig
+	Events               EventsConfig
+	Bulk                 BulkConfig
+	Imports              ImportConfig
+	Jobs                 JobsConfig
+	Validation           ValidationConfig
+	Idempotency          IdempotencyConfig
+	Compression          CompressionConfig
+	CORS                 CORSConfig
+	Security             SecurityConfig
+	TLS                  TLSConfig
+	Servers              map[string]ServerConfig
+	Logging              LoggingConfig
+	Timeouts             TimeoutConfig
+	Metrics              MetricsConfig
+	Limits               map[string]LimitConfig
+	Database             DatabaseConfig
+	pool                 *pgxpool.Pool
+	// db is the pool as cfg.DB runs statements on it, and breaker the
+	// circuit breaker they and transactions go through.
+	db      database.DBTX
+	breaker *database.Breaker
+	// replicas are the read replicas some reads of cfg.DB go to, shared
+	// with every other APIConfig.
+	replicas []*replicaConn
 }
 
 func ApiCfg() *APIConfig {
@@ -18,14 +57,223 @@ func ApiCfg() *APIConfig {
 		log.Fatal(err)
 	}
 
+	retention, err := durationEnv("DELETED_USER_RETENTION", 30*24*time.Hour)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if retention <= 0 {
+		log.Fatal("invalid DELETED_USER_RETENTION: must be positive")
+	}
+
+	purgeInterval, err := durationEnv("PURGE_INTERVAL", time.Hour)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if purgeInterval <= 0 {
+		log.Fatal("invalid PURGE_INTERVAL: must be positive")
+	}
+
+	purgeSchedule := os.Getenv("PURGE_SCHEDULE")
+	if purgeSchedule == "" {
+		purgeSchedule = "@every " + purgeInterval.String()
+	}
+
+	outbox, err := loadOutboxConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	webhooks, err := loadWebhookConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	events, err := loadEventsConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bulk, err := loadBulkConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	imports, err := loadImportConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	jobs, err := loadJobsConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	validation, err := loadValidationConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	idempotency, err := loadIdempotencyConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	compression, err := loadCompressionConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cors, err := loadCORSConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	security, err := loadSecurityConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tlsConfig, err := loadTLSConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	servers, err := loadServerConfigs()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logging, err := loadLoggingConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	timeouts, err := loadTimeoutConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	limits, err := loadLimitConfigs()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	databaseConfig, err := loadDatabaseConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	breaker := sharedBreaker("primary", databaseConfig)
+	db := database.WithResilience(database.WithStatementTimeout(pool, timeouts.Statement), databaseConfig.Retry, breaker)
+	database.ObservePool("primary", pool)
+
+	replicas, err := acquireReplicas(databaseConfig, timeouts.Statement)
+	if err != nil {
+		log.Fatal(err)
+	}
+	replicaDBs := make([]database.ReplicaDB, len(replicas))
+	for i, replica := range replicas {
+		replicaDBs[i] = replica.ReplicaDB
+	}
+
 	return &APIConfig{
-		DB:   database.New(pool),
-		pool: pool,
+		DB:                   database.New(database.WithReplicas(db, replicaDBs, replicaReads...)),
+		AdminToken:           os.Getenv("ADMIN_TOKEN"),
+		DeletedUserRetention: retention,
+		PurgeSchedule:        purgeSchedule,
+		Outbox:               outbox,
+		Webhooks:             webhooks,
+		Events:               events,
+		Bulk:                 bulk,
+		Imports:              imports,
+		Jobs:                 jobs,
+		Validation:           validation,
+		Idempotency:          idempotency,
+		Compression:          compression,
+		CORS:                 cors,
+		Security:             security,
+		TLS:                  tlsConfig,
+		Servers:              servers,
+		Logging:              logging,
+		Timeouts:             timeouts,
+		Metrics:              loadMetricsConfig(),
+		Limits:               limits,
+		Database:             databaseConfig,
+		pool:                 pool,
+		db:                   db,
+		breaker:              breaker,
+		replicas:             replicas,
 	}
 }
 
 func (cfg *APIConfig) Close() {
+	releaseReplicas(cfg.replicas)
+	cfg.replicas = nil
 	if cfg.pool != nil {
 		cfg.pool.Close()
+		database.UnobservePool("primary", cfg.pool)
+	}
+}
+
+// WithTx runs fn with cfg.DB bound to a single transaction, committing only
+// if fn returns nil. Each statement has the statement timeout, and goes
+// through the circuit breaker, as outside a transaction.
+func (cfg *APIConfig) WithTx(ctx context.Context, fn func(*database.Queries) error) error {
+	return database.RunTx(ctx, cfg.pool, database.RetryPolicy{Attempts: 1}, cfg.breaker, cfg.Timeouts.Statement, func(tx pgx.Tx) error {
+		return fn(cfg.DB.WithTx(tx))
+	})
+}
+
+// WithRetryableTx is WithTx for fn that are safe to run more than once. The
+// transaction is run again, by the retry policy, when it fails for the
+// database being briefly unavailable or loses a serialization race or a
+// deadlock, so fn must have no effects outside the transaction, and must set
+// anything it returns results in afresh on every run.
+func (cfg *APIConfig) WithRetryableTx(ctx context.Context, fn func(*database.Queries) error) error {
+	return database.RunTx(ctx, cfg.pool, cfg.Database.Retry, cfg.breaker, cfg.Timeouts.Statement, func(tx pgx.Tx) error {
+		return fn(cfg.DB.WithTx(tx))
+	})
+}
+
+// CheckDatabase returns the state of the circuit breaker of the database,
+// and whether a statement can be run on it within ctx, which it cannot while
+// the breaker is open.
+func (cfg *APIConfig) CheckDatabase(ctx context.Context) (database.BreakerState, error) {
+	_, err := cfg.db.Exec(ctx, "SELECT 1")
+	return cfg.breaker.State(), err
+}
+
+// Replicas returns the health of the read replicas.
+func (cfg *APIConfig) Replicas() []*database.Replica {
+	replicas := make([]*database.Replica, len(cfg.replicas))
+	for i, replica := range cfg.replicas {
+		replicas[i] = replica.Replica
 	}
+	return replicas
+}
+
+// Acquire takes a connection out of the pool for work that needs a session of
+// its own, such as LISTEN. The caller must release it.
+func (cfg *APIConfig) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	return cfg.pool.Acquire(ctx)
+}
+
+// IsAdmin reports whether the request carries the admin bearer token, or a
+// client certificate whose identity is in TLS_ADMIN_IDENTITIES. Token access
+// is disabled when ADMIN_TOKEN is not set.
+func (cfg *APIConfig) IsAdmin(r *http.Request) bool {
+	if identity := ClientIdentity(r); identity != "" && slices.Contains(cfg.TLS.AdminIdentities, identity) {
+		return true
+	}
+
+	if cfg.AdminToken == "" {
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AdminToken)) == 1
 }
@@ -0,0 +1,25 @@
+package config
+
+type BulkConfig struct {
+	// Requests with more items than this are rejected outright.
+	MaxItems int
+	// Request bodies larger than this are rejected while being read.
+	MaxBodyBytes int
+}
+
+func loadBulkConfig() (BulkConfig, error) {
+	maxItems, err := intEnv("BULK_MAX_ITEMS", 1000)
+	if err != nil {
+		return BulkConfig{}, err
+	}
+
+	maxBodyBytes, err := intEnv("BULK_MAX_BODY_BYTES", 10<<20)
+	if err != nil {
+		return BulkConfig{}, err
+	}
+
+	return BulkConfig{
+		MaxItems:     maxItems,
+		MaxBodyBytes: maxBodyBytes,
+	}, nil
+}
@@ -0,0 +1,18 @@
+package config
+
+type CompressionConfig struct {
+	// Responses smaller than this are sent uncompressed, since compressing
+	// them saves little and costs a round of CPU.
+	MinBytes int
+}
+
+func loadCompressionConfig() (CompressionConfig, error) {
+	minBytes, err := intEnv("COMPRESSION_MIN_BYTES", 1024)
+	if err != nil {
+		return CompressionConfig{}, err
+	}
+
+	return CompressionConfig{
+		MinBytes: minBytes,
+	}, nil
+}
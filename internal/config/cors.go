@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type CORSConfig struct {
+	// Origins browsers may call the API from, such as
+	// https://app.example.com. A * stands for any subdomain, as in
+	// https://*.example.com, or any port, as in http://localhost:*, and a
+	// lone * allows every origin.
+	AllowedOrigins []string
+	AllowedMethods []string
+	// Request headers a cross-origin request may carry, or * for any.
+	AllowedHeaders []string
+	// Response headers scripts on the calling page may read.
+	ExposedHeaders []string
+	// Cookies and Authorization headers are sent along and the response is
+	// readable by the calling page. Cannot be combined with a lone * origin.
+	AllowCredentials bool
+	// How long browsers may cache the answer to a preflight request.
+	MaxAge time.Duration
+}
+
+func loadCORSConfig() (CORSConfig, error) {
+	// The docs page served on one port calls the others, so local origins
+	// are allowed out of the box
+	origins := listEnv("CORS_ALLOWED_ORIGINS", []string{
+		"http://localhost:*", "http://127.0.0.1:*", "https://localhost:*", "https://127.0.0.1:*",
+	})
+	for _, origin := range origins {
+		if origin == "*" {
+			continue
+		}
+		scheme, host, ok := strings.Cut(origin, "://")
+		if !ok || scheme == "" || host == "" || strings.ContainsAny(host, "/?#") {
+			return CORSConfig{}, fmt.Errorf("invalid CORS_ALLOWED_ORIGINS: %q is not a scheme://host[:port] origin", origin)
+		}
+	}
+
+	allowCredentials, err := boolEnv("CORS_ALLOW_CREDENTIALS", false)
+	if err != nil {
+		return CORSConfig{}, err
+	}
+	if allowCredentials && slices.Contains(origins, "*") {
+		return CORSConfig{}, errors.New("invalid CORS_ALLOW_CREDENTIALS: cannot be true when CORS_ALLOWED_ORIGINS is *")
+	}
+
+	maxAge, err := durationEnv("CORS_MAX_AGE", 10*time.Minute)
+	if err != nil {
+		return CORSConfig{}, err
+	}
+	if maxAge < 0 {
+		return CORSConfig{}, fmt.Errorf("invalid CORS_MAX_AGE: must not be negative")
+	}
+
+	return CORSConfig{
+		AllowedOrigins: origins,
+		AllowedMethods: listEnv("CORS_ALLOWED_METHODS", []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}),
+		AllowedHeaders: listEnv("CORS_ALLOWED_HEADERS", []string{
+			"Authorization", "Content-Type", "Idempotency-Key", "Last-Event-ID", "X-Request-ID", "X-Actor", "Read-Primary-Until",
+		}),
+		ExposedHeaders: listEnv("CORS_EXPOSED_HEADERS", []string{
+			"Location", "Retry-After", "Content-Disposition", "Idempotent-Replayed", "Read-Primary-Until",
+		}),
+		AllowCredentials: allowCredentials,
+		MaxAge:           maxAge,
+	}, nil
+}
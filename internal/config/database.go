@@ -0,0 +1,214 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DatabaseConfig struct {
+	// How reads, and transactions that are safe to run again, are retried
+	// when the database is briefly unavailable or they lose a serialization
+	// race or a deadlock.
+	Retry database.RetryPolicy
+	// How many consecutive failures to reach the database open its circuit
+	// breaker, and how long it then fails statements fast before letting
+	// one through to find out whether the database is back.
+	BreakerFailures int
+	BreakerCooldown time.Duration
+	// Read replicas, by URL, that the reads of GET /users and
+	// GET /users/{id} go to while they are no more than MaxReplicaLag
+	// behind the primary, as checked every ReplicaCheckInterval. After each
+	// of its writes, a client's reads go to the primary for StickyWindow, so
+	// that it sees them.
+	ReplicaURLs          []string
+	MaxReplicaLag        time.Duration
+	ReplicaCheckInterval time.Duration
+	StickyWindow         time.Duration
+}
+
+// replicaReads are the queries that may go to a replica, those of
+// GET /users and GET /users/{id}, whose results may be a moment old.
+var replicaReads = []string{"GetUser", "GetUserIncludingDeleted", "GetUsers", "GetUsersIncludingDeleted", "ListUsers"}
+
+func loadDatabaseConfig() (DatabaseConfig, error) {
+	attempts, err := intEnv("DB_RETRY_ATTEMPTS", 3)
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+
+	minBackoff, err := durationEnv("DB_RETRY_MIN_BACKOFF", 50*time.Millisecond)
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+
+	maxBackoff, err := durationEnv("DB_RETRY_MAX_BACKOFF", time.Second)
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+	if minBackoff <= 0 || maxBackoff < minBackoff {
+		return DatabaseConfig{}, fmt.Errorf("invalid DB_RETRY_MIN_BACKOFF or DB_RETRY_MAX_BACKOFF: must be positive, the maximum no less than the minimum")
+	}
+
+	failures, err := intEnv("DB_BREAKER_FAILURES", 5)
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+
+	cooldown, err := durationEnv("DB_BREAKER_COOLDOWN", 10*time.Second)
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+	if cooldown <= 0 {
+		return DatabaseConfig{}, fmt.Errorf("invalid DB_BREAKER_COOLDOWN: must be positive")
+	}
+
+	maxLag, err := durationEnv("DB_REPLICA_MAX_LAG", time.Second)
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+
+	checkInterval, err := durationEnv("DB_REPLICA_CHECK_INTERVAL", time.Second)
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+	if maxLag <= 0 || checkInterval <= 0 {
+		return DatabaseConfig{}, fmt.Errorf("invalid DB_REPLICA_MAX_LAG or DB_REPLICA_CHECK_INTERVAL: must be positive")
+	}
+
+	stickyWindow, err := durationEnv("DB_REPLICA_STICKY_WINDOW", 5*time.Second)
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+	if stickyWindow < maxLag {
+		return DatabaseConfig{}, fmt.Errorf("invalid DB_REPLICA_STICKY_WINDOW: must not be shorter than DB_REPLICA_MAX_LAG, or clients may not see their writes")
+	}
+
+	return DatabaseConfig{
+		Retry: database.RetryPolicy{
+			Attempts:   attempts,
+			MinBackoff: minBackoff,
+			MaxBackoff: maxBackoff,
+		},
+		BreakerFailures:      failures,
+		BreakerCooldown:      cooldown,
+		ReplicaURLs:          listEnv("DATABASE_REPLICA_URLS", nil),
+		MaxReplicaLag:        maxLag,
+		ReplicaCheckInterval: checkInterval,
+		StickyWindow:         stickyWindow,
+	}, nil
+}
+
+// breakers are the circuit breakers of the databases by name, shared by
+// every APIConfig, as their pools connect to the same databases and so fail
+// together.
+var breakers struct {
+	sync.Mutex
+	byName map[string]*database.Breaker
+}
+
+func sharedBreaker(name string, cfg DatabaseConfig) *database.Breaker {
+	breakers.Lock()
+	defer breakers.Unlock()
+	if b, ok := breakers.byName[name]; ok {
+		return b
+	}
+	if breakers.byName == nil {
+		breakers.byName = map[string]*database.Breaker{}
+	}
+	b := database.NewBreaker(name, cfg.BreakerFailures, cfg.BreakerCooldown)
+	breakers.byName[name] = b
+	return b
+}
+
+// replicaConns are the connections to the replicas by URL, shared by every
+// APIConfig, like the breakers, so that each replica has one pool and one
+// monitor of its lag. They are counted, and closed when the last APIConfig
+// using them closes.
+var replicaConns struct {
+	sync.Mutex
+	byURL map[string]*replicaConn
+}
+
+type replicaConn struct {
+	database.ReplicaDB
+	url  string
+	pool *pgxpool.Pool
+	refs int
+	// ctx is the monitor's, done once the last APIConfig releases it.
+	ctx  context.Context
+	stop context.CancelFunc
+}
+
+// acquireReplicas returns the connections to the replicas, connecting to
+// and monitoring those no other APIConfig uses. Statements on a replica are
+// not retried, as a read it cannot serve goes to the primary instead. The
+// caller must release them.
+func acquireReplicas(cfg DatabaseConfig, statementTimeout time.Duration) ([]*replicaConn, error) {
+	replicaConns.Lock()
+	defer replicaConns.Unlock()
+	if replicaConns.byURL == nil {
+		replicaConns.byURL = map[string]*replicaConn{}
+	}
+
+	var conns []*replicaConn
+	for i, url := range cfg.ReplicaURLs {
+		if conn, ok := replicaConns.byURL[url]; ok {
+			conn.refs++
+			conns = append(conns, conn)
+			continue
+		}
+
+		name := fmt.Sprintf("replica-%d", i+1)
+		pool, err := database.Connect(url, "DATABASE_REPLICA_URLS")
+		if err != nil {
+			releaseLocked(conns)
+			return nil, err
+		}
+		database.ObservePool(name, pool)
+
+		replica := database.NewReplica(name, cfg.MaxReplicaLag, sharedBreaker(name, cfg))
+		db := database.WithResilience(database.WithStatementTimeout(pool, statementTimeout), database.RetryPolicy{Attempts: 1}, replica.Breaker())
+		ctx, stop := context.WithCancel(context.Background())
+		go replica.Monitor(ctx, db, cfg.ReplicaCheckInterval)
+
+		conn := &replicaConn{
+			ReplicaDB: database.ReplicaDB{Replica: replica, DB: db},
+			url:       url,
+			pool:      pool,
+			refs:      1,
+			ctx:       ctx,
+			stop:      stop,
+		}
+		replicaConns.byURL[url] = conn
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+// releaseReplicas releases connections acquired with acquireReplicas,
+// stopping the monitor of each, closing its pool and no longer reporting it
+// once no APIConfig uses it.
+func releaseReplicas(conns []*replicaConn) {
+	replicaConns.Lock()
+	defer replicaConns.Unlock()
+	releaseLocked(conns)
+}
+
+func releaseLocked(conns []*replicaConn) {
+	for _, conn := range conns {
+		conn.refs--
+		if conn.refs > 0 {
+			continue
+		}
+		conn.stop()
+		conn.pool.Close()
+		database.UnobservePool(conn.Replica.Name(), conn.pool)
+		conn.Replica.Close()
+		delete(replicaConns.byURL, conn.url)
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/metrics"
+)
+
+// The replicas are never reached: pools connect lazily, and their monitors
+// check once an hour.
+var replicaConfig = DatabaseConfig{
+	ReplicaURLs:          []string{"postgres://app@127.0.0.1:1/one", "postgres://app@127.0.0.1:1/two"},
+	MaxReplicaLag:        time.Second,
+	ReplicaCheckInterval: time.Hour,
+	BreakerFailures:      5,
+	BreakerCooldown:      time.Second,
+}
+
+// reported returns the metrics of the pool and the lag of replica that are
+// reported.
+func reported(replica string) []string {
+	var out strings.Builder
+	metrics.Write(&out)
+	var lines []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "db_pool_max_connections{") || strings.HasPrefix(line, "db_replica_lag_seconds{") {
+			if strings.Contains(line, `pool="`+replica+`"`) {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
+func TestReplicasAreSharedUntilTheLastRelease(t *testing.T) {
+	first, err := acquireReplicas(replicaConfig, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := acquireReplicas(replicaConfig, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("replica %d: connected to twice, want one shared pool", i+1)
+		}
+	}
+	if lines := reported("replica-1"); len(lines) != 2 {
+		t.Fatalf("reported %q for the shared replica, want its pool and lag once", lines)
+	}
+
+	// The first to close leaves the replicas monitored for the other
+	releaseReplicas(first)
+	for i, conn := range second {
+		if conn.ctx.Err() != nil {
+			t.Fatalf("replica %d: monitor stopped while still in use", i+1)
+		}
+	}
+
+	releaseReplicas(second)
+	for i, conn := range second {
+		if conn.ctx.Err() == nil {
+			t.Errorf("replica %d: monitor running after the last release", i+1)
+		}
+	}
+	if n := len(replicaConns.byURL); n != 0 {
+		t.Fatalf("%d replicas kept after the last release, want none", n)
+	}
+	if lines := reported("replica-1"); len(lines) != 0 {
+		t.Fatalf("still reporting %q after the last release", lines)
+	}
+
+	// Acquiring them again connects afresh
+	third, err := acquireReplicas(replicaConfig, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseReplicas(third)
+	if third[0] == second[0] || third[0].ctx.Err() != nil {
+		t.Fatal("reacquired a closed replica")
+	}
+}
+
+func TestFailedAcquireReleasesReplicas(t *testing.T) {
+	cfg := replicaConfig
+	cfg.ReplicaURLs = []string{replicaConfig.ReplicaURLs[0], "postgres://app@127.0.0.1:1/%zz"}
+	if _, err := acquireReplicas(cfg, time.Second); err == nil {
+		t.Fatal("connected to an invalid URL")
+	}
+	if n := len(replicaConns.byURL); n != 0 {
+		t.Fatalf("%d replicas kept after failing to connect, want none", n)
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func durationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return d, nil
+}
+
+func intEnv(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s: must be a positive integer", key)
+	}
+
+	return n, nil
+}
+
+func listEnv(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func boolEnv(key string, fallback bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: must be true or false", key)
+	}
+
+	return b, nil
+}
+
+// frameworkEnv looks up a setting that can be made for every server as
+// <PREFIX>_<NAME> or for a single one as <PREFIX>_<FRAMEWORK>_<NAME>, which
+// takes precedence. It returns the variable that was used, or the shared
+// one if neither is set, along with its value.
+func frameworkEnv(prefix, framework, name string) (string, string) {
+	key := prefix + "_" + strings.ToUpper(framework) + "_" + name
+	if value := os.Getenv(key); value != "" {
+		return key, value
+	}
+	key = prefix + "_" + name
+	return key, os.Getenv(key)
+}
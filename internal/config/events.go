@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+type EventsConfig struct {
+	// How many recent events are kept for clients resuming with Last-Event-ID.
+	ReplayBuffer int
+	// How many events may queue up for one client before it is disconnected.
+	ClientBuffer int
+	Heartbeat    time.Duration
+}
+
+func loadEventsConfig() (EventsConfig, error) {
+	replayBuffer, err := intEnv("EVENTS_REPLAY_BUFFER", 1000)
+	if err != nil {
+		return EventsConfig{}, err
+	}
+
+	clientBuffer, err := intEnv("EVENTS_CLIENT_BUFFER", 64)
+	if err != nil {
+		return EventsConfig{}, err
+	}
+
+	heartbeat, err := durationEnv("EVENTS_HEARTBEAT", 15*time.Second)
+	if err != nil {
+		return EventsConfig{}, err
+	}
+	if heartbeat <= 0 {
+		return EventsConfig{}, fmt.Errorf("invalid EVENTS_HEARTBEAT: must be positive")
+	}
+
+	return EventsConfig{
+		ReplayBuffer: replayBuffer,
+		ClientBuffer: clientBuffer,
+		Heartbeat:    heartbeat,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+type IdempotencyConfig struct {
+	// How long a key and its stored response are kept, and so how long a
+	// request can be safely retried with it.
+	TTL time.Duration
+	// A request still in progress after this long is assumed to have died,
+	// letting a retry with the same key run it again.
+	LockTimeout time.Duration
+	// Requests with a key and a body larger than this are rejected, since
+	// the body is read into memory to fingerprint it.
+	MaxBodyBytes int
+	// When expired keys are deleted, as a cron expression or
+	// "@every <duration>".
+	CleanupSchedule string
+}
+
+func loadIdempotencyConfig() (IdempotencyConfig, error) {
+	ttl, err := durationEnv("IDEMPOTENCY_TTL", 24*time.Hour)
+	if err != nil {
+		return IdempotencyConfig{}, err
+	}
+	if ttl <= 0 {
+		return IdempotencyConfig{}, fmt.Errorf("invalid IDEMPOTENCY_TTL: must be positive")
+	}
+
+	lockTimeout, err := durationEnv("IDEMPOTENCY_LOCK_TIMEOUT", time.Minute)
+	if err != nil {
+		return IdempotencyConfig{}, err
+	}
+	if lockTimeout <= 0 {
+		return IdempotencyConfig{}, fmt.Errorf("invalid IDEMPOTENCY_LOCK_TIMEOUT: must be positive")
+	}
+
+	maxBodyBytes, err := intEnv("IDEMPOTENCY_MAX_BODY_BYTES", 10<<20)
+	if err != nil {
+		return IdempotencyConfig{}, err
+	}
+
+	cleanupSchedule := os.Getenv("IDEMPOTENCY_CLEANUP_SCHEDULE")
+	if cleanupSchedule == "" {
+		cleanupSchedule = "@every 1h"
+	}
+
+	return IdempotencyConfig{
+		TTL:             ttl,
+		LockTimeout:     lockTimeout,
+		MaxBodyBytes:    maxBodyBytes,
+		CleanupSchedule: cleanupSchedule,
+	}, nil
+}
@@ -0,0 +1,25 @@
+package config
+
+type ImportConfig struct {
+	// Uploads larger than this are rejected while being read.
+	MaxBytes int
+	// Rows written per transaction, and so how often progress is saved.
+	ChunkSize int
+}
+
+func loadImportConfig() (ImportConfig, error) {
+	maxBytes, err := intEnv("IMPORT_MAX_BYTES", 50<<20)
+	if err != nil {
+		return ImportConfig{}, err
+	}
+
+	chunkSize, err := intEnv("IMPORT_CHUNK_SIZE", 500)
+	if err != nil {
+		return ImportConfig{}, err
+	}
+
+	return ImportConfig{
+		MaxBytes:  maxBytes,
+		ChunkSize: chunkSize,
+	}, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+type JobsConfig struct {
+	// Jobs run at the same time by each server, across every kind.
+	Concurrency  int
+	PollInterval time.Duration
+	// Running jobs whose heartbeat is older than this are taken to have lost
+	// their worker and are claimed again.
+	StaleAfter time.Duration
+	// How long running jobs get to finish on shutdown before they are
+	// interrupted and handed back to the queue.
+	DrainTimeout time.Duration
+}
+
+func loadJobsConfig() (JobsConfig, error) {
+	concurrency, err := intEnv("JOBS_CONCURRENCY", 4)
+	if err != nil {
+		return JobsConfig{}, err
+	}
+
+	pollInterval, err := durationEnv("JOBS_POLL_INTERVAL", time.Second)
+	if err != nil {
+		return JobsConfig{}, err
+	}
+	if pollInterval <= 0 {
+		return JobsConfig{}, fmt.Errorf("invalid JOBS_POLL_INTERVAL: must be positive")
+	}
+
+	staleAfter, err := durationEnv("JOBS_STALE_AFTER", 2*time.Minute)
+	if err != nil {
+		return JobsConfig{}, err
+	}
+	if staleAfter < time.Second {
+		return JobsConfig{}, fmt.Errorf("invalid JOBS_STALE_AFTER: must be at least 1s, as running jobs send heartbeats four times as often")
+	}
+
+	drainTimeout, err := durationEnv("JOBS_DRAIN_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return JobsConfig{}, err
+	}
+	if drainTimeout < 0 {
+		return JobsConfig{}, fmt.Errorf("invalid JOBS_DRAIN_TIMEOUT: must not be negative")
+	}
+
+	return JobsConfig{
+		Concurrency:  concurrency,
+		PollInterval: pollInterval,
+		StaleAfter:   staleAfter,
+		DrainTimeout: drainTimeout,
+	}, nil
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Priority is the class of a route's requests when requests are shed.
+type Priority string
+
+const (
+	// Critical requests, such as health checks, are never queued or shed.
+	PriorityCritical Priority = "critical"
+	// Read, write and bulk requests may use all of the concurrency limit,
+	// most of it and half of it, so under load bulk requests are shed
+	// first and reads last.
+	PriorityRead  Priority = "read"
+	PriorityWrite Priority = "write"
+	PriorityBulk  Priority = "bulk"
+	// Unlimited requests, such as event streams, which stay open for as
+	// long as their clients do, are not limited at all.
+	PriorityUnlimited Priority = "unlimited"
+)
+
+var priorities = []Priority{PriorityCritical, PriorityRead, PriorityWrite, PriorityBulk, PriorityUnlimited}
+
+type LimitConfig struct {
+	// static keeps the limits as they are. aimd lowers them when requests
+	// get slower than TargetLatency and raises them back while they are
+	// fast, and gradient follows the ratio of the usual latency to the
+	// latest.
+	Mode string
+	// How many requests the server, and each route, run at once, which is
+	// also the highest an adaptive limit goes.
+	ServerLimit int
+	RouteLimit  int
+	// The lowest an adaptive limit goes.
+	MinLimit      int
+	TargetLatency time.Duration
+	// How many requests may wait for a slot, and for how long, before they
+	// are shed.
+	QueueSize int
+	MaxWait   time.Duration
+	// The priority of routes, keyed by method and route pattern, as in
+	// "POST /users/bulk". Other routes are reads if their method is GET or
+	// HEAD, and writes otherwise.
+	Priorities map[string]Priority
+}
+
+// defaultPriorities keeps health checks up and streams open, and sheds
+// bulk work first.
+var defaultPriorities = map[string]Priority{
+	"GET /healthz":       PriorityCritical,
+	"GET /readyz":        PriorityCritical,
+	"GET /users/events":  PriorityUnlimited,
+	"GET /ws":            PriorityUnlimited,
+	"GET /users/export":  PriorityBulk,
+	"POST /users/import": PriorityBulk,
+	"POST /users/bulk":   PriorityBulk,
+	"PATCH /users/bulk":  PriorityBulk,
+	"DELETE /users/bulk": PriorityBulk,
+}
+
+// Priority returns the priority of requests with method to the route
+// pattern.
+func (c LimitConfig) Priority(method, pattern string) Priority {
+	if priority, ok := c.Priorities[method+" "+pattern]; ok {
+		return priority
+	}
+	if method == http.MethodHead {
+		if priority, ok := c.Priorities["GET "+pattern]; ok {
+			return priority
+		}
+	}
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+		return PriorityRead
+	}
+	return PriorityWrite
+}
+
+func loadLimitConfigs() (map[string]LimitConfig, error) {
+	limits := map[string]LimitConfig{}
+	for _, framework := range Frameworks {
+		limit, err := loadLimitConfig(framework)
+		if err != nil {
+			return nil, err
+		}
+		limits[framework] = limit
+	}
+	return limits, nil
+}
+
+// loadLimitConfig reads the limits of one server, each of which can be set
+// for every server as LIMIT_<NAME> or for one as LIMIT_<FRAMEWORK>_<NAME>.
+func loadLimitConfig(framework string) (LimitConfig, error) {
+	key := func(name string) string {
+		key, _ := frameworkEnv("LIMIT", framework, name)
+		return key
+	}
+
+	modeKey, mode := frameworkEnv("LIMIT", framework, "MODE")
+	if mode == "" {
+		mode = "aimd"
+	}
+	if mode != "static" && mode != "aimd" && mode != "gradient" {
+		return LimitConfig{}, fmt.Errorf("invalid %s: must be static, aimd or gradient", modeKey)
+	}
+
+	serverLimit, err := intEnv(key("SERVER"), 100)
+	if err != nil {
+		return LimitConfig{}, err
+	}
+
+	routeLimit, err := intEnv(key("ROUTE"), 50)
+	if err != nil {
+		return LimitConfig{}, err
+	}
+
+	minLimit, err := intEnv(key("MIN"), 4)
+	if err != nil {
+		return LimitConfig{}, err
+	}
+	if minLimit > min(serverLimit, routeLimit) {
+		return LimitConfig{}, fmt.Errorf("invalid %s: must not be above the server or route limit", key("MIN"))
+	}
+
+	targetLatency, err := durationEnv(key("TARGET_LATENCY"), 250*time.Millisecond)
+	if err != nil {
+		return LimitConfig{}, err
+	}
+	if targetLatency <= 0 {
+		return LimitConfig{}, fmt.Errorf("invalid %s: must be positive", key("TARGET_LATENCY"))
+	}
+
+	queueSize, err := intEnv(key("QUEUE_SIZE"), 50)
+	if err != nil {
+		return LimitConfig{}, err
+	}
+
+	maxWait, err := durationEnv(key("MAX_WAIT"), 500*time.Millisecond)
+	if err != nil {
+		return LimitConfig{}, err
+	}
+	if maxWait < 0 {
+		return LimitConfig{}, fmt.Errorf("invalid %s: must not be negative", key("MAX_WAIT"))
+	}
+
+	priorities, err := routePrioritiesEnv(key("ROUTE_PRIORITIES"))
+	if err != nil {
+		return LimitConfig{}, err
+	}
+
+	return LimitConfig{
+		Mode:          mode,
+		ServerLimit:   serverLimit,
+		RouteLimit:    routeLimit,
+		MinLimit:      minLimit,
+		TargetLatency: targetLatency,
+		QueueSize:     queueSize,
+		MaxWait:       maxWait,
+		Priorities:    priorities,
+	}, nil
+}
+
+// routePrioritiesEnv reads comma separated "METHOD /pattern=priority"
+// pairs, which take the place of the defaults for the same routes.
+func routePrioritiesEnv(key string) (map[string]Priority, error) {
+	routes := maps.Clone(defaultPriorities)
+	for _, item := range listEnv(key, nil) {
+		route, value, ok := strings.Cut(item, "=")
+		method, pattern, hasPattern := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !hasPattern || method != strings.ToUpper(method) || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("invalid %s: %q must be METHOD /pattern=priority", key, item)
+		}
+		priority := Priority(strings.TrimSpace(value))
+		if !slices.Contains(priorities, priority) {
+			return nil, fmt.Errorf("invalid %s: %q must have a priority of critical, read, write, bulk or unlimited", key, item)
+		}
+		routes[method+" "+pattern] = priority
+	}
+	return routes, nil
+}
@@ -0,0 +1,17 @@
+package config
+
+type LoggingConfig struct {
+	// Every request is logged once answered, on every server.
+	Requests bool
+}
+
+func loadLoggingConfig() (LoggingConfig, error) {
+	requests, err := boolEnv("LOG_REQUESTS", true)
+	if err != nil {
+		return LoggingConfig{}, err
+	}
+
+	return LoggingConfig{
+		Requests: requests,
+	}, nil
+}
@@ -0,0 +1,20 @@
+package config
+
+import "os"
+
+type MetricsConfig struct {
+	// The address metrics are served on, in the Prometheus text format, at
+	// /metrics. Empty means they are not served.
+	Addr string
+}
+
+func loadMetricsConfig() MetricsConfig {
+	addr, ok := os.LookupEnv("METRICS_ADDR")
+	if !ok {
+		addr = ":9090"
+	}
+
+	return MetricsConfig{
+		Addr: addr,
+	}
+}
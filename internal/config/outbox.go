@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+type OutboxConfig struct {
+	// Sinks every event is published to: "log", "webhook" and/or "notify".
+	// "notify" is always among them, as the user events stream and the
+	// WebSocket endpoint of every server are fed by it.
+	Sinks []string
+	// File the log sink appends to. Empty means standard output.
+	LogFile       string
+	WebhookURL    string
+	NotifyChannel string
+	PollInterval  time.Duration
+	BatchSize     int
+	// Events that fail this many times are dead-lettered and not retried.
+	MaxAttempts int
+}
+
+func loadOutboxConfig() (OutboxConfig, error) {
+	pollInterval, err := durationEnv("OUTBOX_POLL_INTERVAL", time.Second)
+	if err != nil {
+		return OutboxConfig{}, err
+	}
+	if pollInterval <= 0 {
+		return OutboxConfig{}, fmt.Errorf("invalid OUTBOX_POLL_INTERVAL: must be positive")
+	}
+
+	batchSize, err := intEnv("OUTBOX_BATCH_SIZE", 100)
+	if err != nil {
+		return OutboxConfig{}, err
+	}
+
+	maxAttempts, err := intEnv("OUTBOX_MAX_ATTEMPTS", 10)
+	if err != nil {
+		return OutboxConfig{}, err
+	}
+
+	notifyChannel := os.Getenv("OUTBOX_NOTIFY_CHANNEL")
+	if notifyChannel == "" {
+		notifyChannel = "user_events"
+	}
+
+	sinks := listEnv("OUTBOX_SINKS", nil)
+	if !slices.Contains(sinks, "notify") {
+		sinks = append(sinks, "notify")
+	}
+
+	return OutboxConfig{
+		Sinks:         sinks,
+		LogFile:       os.Getenv("OUTBOX_LOG_FILE"),
+		WebhookURL:    os.Getenv("OUTBOX_WEBHOOK_URL"),
+		NotifyChannel: notifyChannel,
+		PollInterval:  pollInterval,
+		BatchSize:     batchSize,
+		MaxAttempts:   maxAttempts,
+	}, nil
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNotifySinkIsAlwaysRegistered(t *testing.T) {
+	tests := []struct {
+		sinks string
+		want  []string
+	}{
+		{"", []string{"notify"}},
+		{"log", []string{"log", "notify"}},
+		{"log, webhook", []string{"log", "webhook", "notify"}},
+		{"notify,log", []string{"notify", "log"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("OUTBOX_SINKS", tt.sinks)
+		cfg, err := loadOutboxConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(cfg.Sinks, tt.want) {
+			t.Errorf("OUTBOX_SINKS=%q: sinks %v, want %v", tt.sinks, cfg.Sinks, tt.want)
+		}
+	}
+}
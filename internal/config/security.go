@@ -0,0 +1,45 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+type SecurityConfig struct {
+	// How long browsers should only use HTTPS for the API, sent with
+	// responses to requests made over TLS. Zero leaves the header out.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	// DENY keeps pages from being framed at all, SAMEORIGIN allows the API's
+	// own origin to frame them.
+	FrameOptions string
+}
+
+func loadSecurityConfig() (SecurityConfig, error) {
+	hstsMaxAge, err := durationEnv("SECURITY_HSTS_MAX_AGE", 365*24*time.Hour)
+	if err != nil {
+		return SecurityConfig{}, err
+	}
+
+	includeSubdomains, err := boolEnv("SECURITY_HSTS_INCLUDE_SUBDOMAINS", false)
+	if err != nil {
+		return SecurityConfig{}, err
+	}
+
+	frameOptions := strings.ToUpper(os.Getenv("SECURITY_FRAME_OPTIONS"))
+	switch frameOptions {
+	case "":
+		frameOptions = "DENY"
+	case "DENY", "SAMEORIGIN":
+	default:
+		return SecurityConfig{}, errors.New("invalid SECURITY_FRAME_OPTIONS: must be DENY or SAMEORIGIN")
+	}
+
+	return SecurityConfig{
+		HSTSMaxAge:            hstsMaxAge,
+		HSTSIncludeSubdomains: includeSubdomains,
+		FrameOptions:          frameOptions,
+	}, nil
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"net/http"
+	"time"
+)
+
+type ServerConfig struct {
+	// Plain HTTP servers also speak HTTP/2 without TLS (h2c), to clients
+	// that start with it or upgrade to it. HTTPS servers speak HTTP/2
+	// regardless.
+	H2C bool
+	// The largest request header accepted, in bytes.
+	MaxHeaderBytes int
+	// How many requests an HTTP/2 client may have in flight on one
+	// connection.
+	MaxConcurrentStreams int
+	// Connections are kept open between requests, for up to IdleTimeout.
+	KeepAlives  bool
+	IdleTimeout time.Duration
+}
+
+func loadServerConfigs() (map[string]ServerConfig, error) {
+	servers := map[string]ServerConfig{}
+	for _, framework := range Frameworks {
+		server, err := loadServerConfig(framework)
+		if err != nil {
+			return nil, err
+		}
+		servers[framework] = server
+	}
+	return servers, nil
+}
+
+// loadServerConfig reads the settings of one server, each of which can be
+// set for every server as SERVER_<NAME> or for one as
+// SERVER_<FRAMEWORK>_<NAME>.
+func loadServerConfig(framework string) (ServerConfig, error) {
+	key := func(name string) string {
+		key, _ := frameworkEnv("SERVER", framework, name)
+		return key
+	}
+
+	h2c, err := boolEnv(key("H2C"), false)
+	if err != nil {
+		return ServerConfig{}, err
+	}
+
+	maxHeaderBytes, err := intEnv(key("MAX_HEADER_BYTES"), http.DefaultMaxHeaderBytes)
+	if err != nil {
+		return ServerConfig{}, err
+	}
+
+	maxConcurrentStreams, err := intEnv(key("MAX_CONCURRENT_STREAMS"), 250)
+	if err != nil {
+		return ServerConfig{}, err
+	}
+
+	keepAlives, err := boolEnv(key("KEEP_ALIVES"), true)
+	if err != nil {
+		return ServerConfig{}, err
+	}
+
+	idleTimeout, err := durationEnv(key("IDLE_TIMEOUT"), 2*time.Minute)
+	if err != nil {
+		return ServerConfig{}, err
+	}
+
+	return ServerConfig{
+		H2C:                  h2c,
+		MaxHeaderBytes:       maxHeaderBytes,
+		MaxConcurrentStreams: maxConcurrentStreams,
+		KeepAlives:           keepAlives,
+		IdleTimeout:          idleTimeout,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type TimeoutConfig struct {
+	// How long a request may take, unless its route has a timeout of its
+	// own in Routes, keyed by method and route pattern, as in
+	// "GET /users/export". 0 means no limit.
+	Request time.Duration
+	Routes  map[string]time.Duration
+	// How long a single database statement may run, within the request
+	// timeout. 0 means no limit. It is set as statement_timeout in every
+	// transaction, and is a deadline on the client for other statements,
+	// which the server is asked to cancel once it passes.
+	Statement time.Duration
+}
+
+// defaultRouteTimeouts leaves streams open for as long as their clients stay
+// connected, and gives exports, imports and bulk requests longer.
+var defaultRouteTimeouts = map[string]time.Duration{
+	"GET /users/events":  0,
+	"GET /ws":            0,
+	"GET /users/export":  5 * time.Minute,
+	"POST /users/import": 5 * time.Minute,
+	"POST /users/bulk":   2 * time.Minute,
+	"PATCH /users/bulk":  2 * time.Minute,
+	"DELETE /users/bulk": 2 * time.Minute,
+}
+
+// For returns the timeout of requests with method to the route pattern. A
+// HEAD request gets the timeout of the GET route it is served by.
+func (c TimeoutConfig) For(method, pattern string) time.Duration {
+	if timeout, ok := c.Routes[method+" "+pattern]; ok {
+		return timeout
+	}
+	if timeout, ok := c.Routes["GET "+pattern]; ok && method == http.MethodHead {
+		return timeout
+	}
+	return c.Request
+}
+
+func loadTimeoutConfig() (TimeoutConfig, error) {
+	request, err := durationEnv("REQUEST_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return TimeoutConfig{}, err
+	}
+	if request < 0 {
+		return TimeoutConfig{}, fmt.Errorf("invalid REQUEST_TIMEOUT: must not be negative")
+	}
+
+	routes, err := routeTimeoutsEnv("REQUEST_ROUTE_TIMEOUTS")
+	if err != nil {
+		return TimeoutConfig{}, err
+	}
+
+	statement, err := durationEnv("DB_STATEMENT_TIMEOUT", 15*time.Second)
+	if err != nil {
+		return TimeoutConfig{}, err
+	}
+	if statement < 0 {
+		return TimeoutConfig{}, fmt.Errorf("invalid DB_STATEMENT_TIMEOUT: must not be negative")
+	}
+
+	return TimeoutConfig{
+		Request:   request,
+		Routes:    routes,
+		Statement: statement,
+	}, nil
+}
+
+// routeTimeoutsEnv reads comma separated "METHOD /pattern=duration" pairs,
+// which take the place of the defaults for the same routes.
+func routeTimeoutsEnv(key string) (map[string]time.Duration, error) {
+	routes := maps.Clone(defaultRouteTimeouts)
+	for _, item := range listEnv(key, nil) {
+		route, value, ok := strings.Cut(item, "=")
+		method, pattern, hasPattern := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !hasPattern || method != strings.ToUpper(method) || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("invalid %s: %q must be METHOD /pattern=duration", key, item)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid %s: %q must have a duration that is not negative", key, item)
+		}
+		routes[method+" "+pattern] = timeout
+	}
+	return routes, nil
+}
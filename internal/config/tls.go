@@ -0,0 +1,159 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Frameworks names the servers, one per router, as used in audit events and
+// in the per-listener TLS variables such as TLS_ECHO_CERT_FILE.
+var Frameworks = []string{"standard", "httprouter", "mux", "chi", "echo", "gin"}
+
+type TLSConfig struct {
+	// The TLS settings of each server, by framework. Servers without a
+	// certificate serve plain HTTP.
+	Listeners map[string]TLSListenerConfig
+	// How often certificate files are checked for changes.
+	ReloadInterval time.Duration
+	// Client certificate identities granted admin access, as an
+	// alternative to the admin token.
+	AdminIdentities []string
+}
+
+type TLSListenerConfig struct {
+	CertFile string
+	KeyFile  string
+	// tls.VersionTLS12 or tls.VersionTLS13.
+	MinVersion uint16
+	// "default" leaves the TLS 1.2 cipher suites to Go, "strict" allows
+	// only those with forward secrecy and authenticated encryption.
+	CipherPolicy string
+	// Client certificates are verified against the CAs in this file.
+	ClientCAFile string
+	ClientAuth   tls.ClientAuthType
+	// Plain HTTP requests to this address are redirected to the server.
+	RedirectAddr string
+}
+
+// Enabled reports whether the server has a certificate to serve HTTPS with.
+func (c TLSListenerConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+func loadTLSConfig() (TLSConfig, error) {
+	listeners := map[string]TLSListenerConfig{}
+	for _, framework := range Frameworks {
+		listener, err := loadTLSListenerConfig(framework)
+		if err != nil {
+			return TLSConfig{}, err
+		}
+		listeners[framework] = listener
+	}
+
+	reloadInterval, err := durationEnv("TLS_RELOAD_INTERVAL", 10*time.Second)
+	if err != nil {
+		return TLSConfig{}, err
+	}
+	if reloadInterval <= 0 {
+		return TLSConfig{}, fmt.Errorf("invalid TLS_RELOAD_INTERVAL: must be positive")
+	}
+
+	return TLSConfig{
+		Listeners:       listeners,
+		ReloadInterval:  reloadInterval,
+		AdminIdentities: listEnv("TLS_ADMIN_IDENTITIES", nil),
+	}, nil
+}
+
+// loadTLSListenerConfig reads the settings of one server. Each TLS_<NAME>
+// variable can be set for a single server as TLS_<FRAMEWORK>_<NAME>, which
+// takes precedence.
+func loadTLSListenerConfig(framework string) (TLSListenerConfig, error) {
+	env := func(name string) (string, string) {
+		return frameworkEnv("TLS", framework, name)
+	}
+
+	_, certFile := env("CERT_FILE")
+	keyKey, keyFile := env("KEY_FILE")
+	if (certFile == "") != (keyFile == "") {
+		return TLSListenerConfig{}, fmt.Errorf("invalid %s: a certificate and its key must be set together", keyKey)
+	}
+
+	minVersion := uint16(tls.VersionTLS12)
+	switch key, value := env("MIN_VERSION"); value {
+	case "", "1.2":
+	case "1.3":
+		minVersion = tls.VersionTLS13
+	default:
+		return TLSListenerConfig{}, fmt.Errorf("invalid %s: must be 1.2 or 1.3", key)
+	}
+
+	key, cipherPolicy := env("CIPHER_POLICY")
+	switch cipherPolicy {
+	case "":
+		cipherPolicy = "default"
+	case "default", "strict":
+	default:
+		return TLSListenerConfig{}, fmt.Errorf("invalid %s: must be default or strict", key)
+	}
+
+	_, clientCAFile := env("CLIENT_CA_FILE")
+	clientAuthKey, clientAuthValue := env("CLIENT_AUTH")
+	clientAuth := tls.NoClientCert
+	switch clientAuthValue {
+	case "", "none":
+	case "optional":
+		clientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return TLSListenerConfig{}, fmt.Errorf("invalid %s: must be none, optional or require", clientAuthKey)
+	}
+	if clientAuth != tls.NoClientCert && clientCAFile == "" {
+		return TLSListenerConfig{}, fmt.Errorf("invalid %s: a client CA file must be set to verify client certificates", clientAuthKey)
+	}
+
+	// Redirect listeners need an address of their own, so there is no
+	// shared TLS_REDIRECT_ADDR
+	redirectKey := "TLS_" + strings.ToUpper(framework) + "_REDIRECT_ADDR"
+	redirectAddr := os.Getenv(redirectKey)
+	if redirectAddr != "" && certFile == "" {
+		return TLSListenerConfig{}, fmt.Errorf("invalid %s: the server has no certificate to redirect to", redirectKey)
+	}
+
+	return TLSListenerConfig{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		MinVersion:   minVersion,
+		CipherPolicy: cipherPolicy,
+		ClientCAFile: clientCAFile,
+		ClientAuth:   clientAuth,
+		RedirectAddr: redirectAddr,
+	}, nil
+}
+
+// ClientIdentity returns the identity of the verified client certificate of
+// the request: its subject common name or, failing that, its first URI,
+// email or DNS name. It is empty for requests without one.
+func ClientIdentity(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	}
+	return ""
+}
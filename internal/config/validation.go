@@ -0,0 +1,35 @@
+package config
+
+type ValidationConfig struct {
+	// Requests are checked against the OpenAPI document before they reach
+	// the handlers.
+	Requests bool
+	// Responses are checked too. They are buffered to do so, which makes
+	// this a debugging aid rather than something to run in production.
+	Responses bool
+	// Bodies larger than this are passed on without checking their content.
+	MaxBodyBytes int
+}
+
+func loadValidationConfig() (ValidationConfig, error) {
+	requests, err := boolEnv("VALIDATE_REQUESTS", false)
+	if err != nil {
+		return ValidationConfig{}, err
+	}
+
+	responses, err := boolEnv("VALIDATE_RESPONSES", false)
+	if err != nil {
+		return ValidationConfig{}, err
+	}
+
+	maxBodyBytes, err := intEnv("VALIDATE_MAX_BODY_BYTES", 1<<20)
+	if err != nil {
+		return ValidationConfig{}, err
+	}
+
+	return ValidationConfig{
+		Requests:     requests,
+		Responses:    responses,
+		MaxBodyBytes: maxBodyBytes,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+type WebhookConfig struct {
+	// Deliveries that fail this many times are given up on.
+	MaxAttempts int
+	// Webhooks are disabled after this many consecutive failed attempts.
+	MaxFailures  int
+	Timeout      time.Duration
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+func loadWebhookConfig() (WebhookConfig, error) {
+	maxAttempts, err := intEnv("WEBHOOK_MAX_ATTEMPTS", 8)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+
+	maxFailures, err := intEnv("WEBHOOK_MAX_FAILURES", 20)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+
+	timeout, err := durationEnv("WEBHOOK_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+	if timeout <= 0 {
+		return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_TIMEOUT: must be positive")
+	}
+
+	pollInterval, err := durationEnv("WEBHOOK_POLL_INTERVAL", time.Second)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+	if pollInterval <= 0 {
+		return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_POLL_INTERVAL: must be positive")
+	}
+
+	batchSize, err := intEnv("WEBHOOK_BATCH_SIZE", 50)
+	if err != nil {
+		return WebhookConfig{}, err
+	}
+
+	return WebhookConfig{
+		MaxAttempts:  maxAttempts,
+		MaxFailures:  maxFailures,
+		Timeout:      timeout,
+		PollInterval: pollInterval,
+		BatchSize:    batchSize,
+	}, nil
+}
@@ -0,0 +1,190 @@
+// Package cors lets browser apps on other origins call the API, answering
+// preflight requests and marking responses as readable by the allowed
+// origins.
+package cors
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+)
+
+// Policy decides which cross-origin requests are allowed.
+type Policy struct {
+	cfg       config.CORSConfig
+	anyOrigin bool
+	origins   []*regexp.Regexp
+	// Lower case, or nil when any header is allowed.
+	headers []string
+	// The values of the Access-Control-* headers that are the same for
+	// every request.
+	allowMethods  string
+	exposeHeaders string
+	maxAge        string
+}
+
+func New(cfg config.CORSConfig) *Policy {
+	p := &Policy{
+		cfg:           cfg,
+		allowMethods:  strings.Join(cfg.AllowedMethods, ", "),
+		exposeHeaders: strings.Join(cfg.ExposedHeaders, ", "),
+		maxAge:        strconv.Itoa(int(cfg.MaxAge.Seconds())),
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			p.anyOrigin = true
+			continue
+		}
+		p.origins = append(p.origins, originPattern(origin))
+	}
+
+	if !slices.Contains(cfg.AllowedHeaders, "*") {
+		for _, header := range cfg.AllowedHeaders {
+			p.headers = append(p.headers, strings.ToLower(header))
+		}
+	}
+	return p
+}
+
+// originPattern compiles an allowed origin. A * after a colon stands for a
+// port, anywhere else for one or more host name labels.
+func originPattern(origin string) *regexp.Regexp {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for i, part := range strings.Split(strings.ToLower(origin), "*") {
+		if i > 0 {
+			if strings.HasSuffix(pattern.String(), ":") {
+				pattern.WriteString(`[0-9]+`)
+			} else {
+				pattern.WriteString(`[a-z0-9-]+(\.[a-z0-9-]+)*`)
+			}
+		}
+		pattern.WriteString(regexp.QuoteMeta(part))
+	}
+	pattern.WriteString("$")
+	return regexp.MustCompile(pattern.String())
+}
+
+// AllowsOrigin reports whether pages on origin may call the API.
+func (p *Policy) AllowsOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, pattern := range p.origins {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHeaders reports whether every header of an
+// Access-Control-Request-Headers list is allowed.
+func (p *Policy) allowsHeaders(list string) bool {
+	if p.headers == nil {
+		return true
+	}
+	for _, header := range strings.Split(list, ",") {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header != "" && !slices.Contains(p.headers, header) {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware answers preflight requests itself and adds the
+// Access-Control-* headers to the responses to allowed origins. Requests
+// without an Origin header, such as those from other servers, pass through
+// unchanged.
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		if !p.anyOrigin || p.cfg.AllowCredentials {
+			// The response depends on the origin, so caches must not hand
+			// it to another
+			header.Add("Vary", "Origin")
+		}
+
+		requestMethod := r.Header.Get("Access-Control-Request-Method")
+		if r.Method == http.MethodOptions && requestMethod != "" {
+			p.preflight(w, r, origin, requestMethod)
+			return
+		}
+
+		if p.AllowsOrigin(origin) {
+			p.allowOrigin(header, origin)
+			if p.exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", p.exposeHeaders)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (p *Policy) preflight(w http.ResponseWriter, r *http.Request, origin, method string) {
+	header := w.Header()
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+	switch {
+	case !p.AllowsOrigin(origin):
+		writeForbidden(w, "The origin "+origin+" is not allowed to call the API.")
+		return
+	case !slices.Contains(p.cfg.AllowedMethods, method):
+		writeForbidden(w, "The method "+method+" is not allowed for cross-origin requests.")
+		return
+	case !p.allowsHeaders(requestHeaders):
+		writeForbidden(w, "The headers "+requestHeaders+" are not all allowed for cross-origin requests.")
+		return
+	}
+
+	p.allowOrigin(header, origin)
+	header.Set("Access-Control-Allow-Methods", p.allowMethods)
+	if requestHeaders != "" {
+		// Either every requested header is in the list or any is allowed,
+		// so naming the requested ones answers both
+		header.Set("Access-Control-Allow-Headers", requestHeaders)
+	}
+	if p.cfg.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Policy) allowOrigin(header http.Header, origin string) {
+	if p.anyOrigin && !p.cfg.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+	if p.cfg.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func writeForbidden(w http.ResponseWriter, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(models.Problem{
+		Type:   "about:blank",
+		Title:  "Cross-origin request not allowed",
+		Status: http.StatusForbidden,
+		Detail: detail,
+	})
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/metrics"
+)
+
+// ErrCircuitOpen is returned, without running the statement, while the
+// circuit breaker of the database is open.
+var ErrCircuitOpen = errors.New("database unavailable: circuit breaker open")
+
+var breakerRejections = metrics.NewCounter("db_breaker_rejections_total",
+	"Database statements failed without running while the circuit breaker was open.",
+	"pool")
+
+// breakers are every Breaker, for the gauge.
+var breakers struct {
+	sync.Mutex
+	all []*Breaker
+}
+
+func init() {
+	metrics.NewGaugeFunc("db_breaker_state", "Whether the circuit breaker of each pool is in each state, 1 for the one it is in.",
+		[]string{"pool", "state"}, func(report func(float64, ...string)) {
+			breakers.Lock()
+			defer breakers.Unlock()
+			for _, b := range breakers.all {
+				current := b.State()
+				for _, state := range []BreakerState{BreakerClosed, BreakerHalfOpen, BreakerOpen} {
+					value := 0.0
+					if state == current {
+						value = 1
+					}
+					report(value, b.name, state.String())
+				}
+			}
+		})
+}
+
+type BreakerState int
+
+const (
+	// BreakerClosed lets every statement through.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets one statement through, to find out whether the
+	// database is back.
+	BreakerHalfOpen
+	// BreakerOpen fails every statement without running it.
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	}
+	return "closed"
+}
+
+// Breaker fails statements fast while the database is unavailable, rather
+// than having every request wait on a connection that will not come. It
+// opens after a number of consecutive failures, as told by IsUnavailable,
+// and after a cooldown lets a single statement through as a probe, closing
+// again if it succeeds. Errors of the statements themselves, such as a
+// unique violation, count as successes, since the database answered.
+type Breaker struct {
+	name     string
+	failures int
+	cooldown time.Duration
+
+	mu          sync.Mutex
+	state       BreakerState
+	consecutive int
+	openedAt    time.Time
+	// probing is when the probe of a half-open breaker was let through, or
+	// zero if none is in flight.
+	probing time.Time
+}
+
+// NewBreaker returns a closed breaker for the named pool, which opens after
+// failures consecutive failures for cooldown.
+func NewBreaker(name string, failures int, cooldown time.Duration) *Breaker {
+	b := &Breaker{name: name, failures: failures, cooldown: cooldown}
+	breakers.Lock()
+	breakers.all = append(breakers.all, b)
+	breakers.Unlock()
+	return b
+}
+
+// Allow returns ErrCircuitOpen if a statement may not run now. Every nil it
+// returns must be followed by a record of how the statement went.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			break
+		}
+		b.state = BreakerHalfOpen
+		fallthrough
+	case BreakerHalfOpen:
+		// A probe that never reported back is given up on after a cooldown
+		if !b.probing.IsZero() && time.Since(b.probing) < b.cooldown {
+			break
+		}
+		b.probing = time.Now()
+		return nil
+	default:
+		return nil
+	}
+
+	breakerRejections.Inc(b.name)
+	return ErrCircuitOpen
+}
+
+// record notes how a statement run with ctx went. A statement whose context
+// was done before it failed says nothing about the database.
+func (b *Breaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case IsUnavailable(err) && ctx.Err() == nil:
+		b.consecutive++
+		if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.consecutive >= b.failures) {
+			if b.state == BreakerClosed {
+				log.Printf("Opened the %s database circuit breaker after %d consecutive failures: %v", b.name, b.consecutive, err)
+			}
+			b.state, b.openedAt, b.probing = BreakerOpen, time.Now(), time.Time{}
+		}
+	case err != nil && ctx.Err() != nil:
+		if b.state == BreakerHalfOpen {
+			b.probing = time.Time{}
+		}
+	default:
+		b.consecutive = 0
+		// While open, a statement that started before the breaker opened
+		// does not close it; only the probe does
+		if b.state == BreakerHalfOpen {
+			log.Printf("Closed the %s database circuit breaker", b.name)
+			b.state, b.probing = BreakerClosed, time.Time{}
+		}
+	}
+}
+
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// RetryAfter returns how long until the breaker lets a probe through, or 0
+// if it would now.
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != BreakerOpen {
+		return 0
+	}
+	return max(0, b.cooldown-time.Since(b.openedAt))
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -14,7 +17,26 @@ func ConnectDB() (*pgxpool.Pool, error) {
 		return nil, fmt.Errorf("DATABASE_URL environment variable not set")
 	}
 
-	pool, err := pgxpool.New(context.Background(), dbUrl)
+	return Connect(dbUrl, "DATABASE_URL")
+}
+
+// Connect creates a pool of connections to dbUrl, which was read from the
+// variable key, for errors.
+func Connect(dbUrl, key string) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(dbUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+
+	// A statement whose context is done is canceled on the server, which
+	// leaves the connection fit for reuse, rather than having its connection
+	// closed under it. The connection is only closed if the server does not
+	// answer the cancel request in time.
+	config.ConnConfig.BuildContextWatcherHandler = func(conn *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{Conn: conn, DeadlineDelay: 2 * time.Second}
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
@@ -0,0 +1,294 @@
+// Package dbtest stands in for the database in tests. DB answers each sqlc
+// query by its name with a function the test gives, so code built on
+// database.Queries can be tested without PostgreSQL.
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Handler answers a query given its arguments, in the order sqlc passes
+// them, with the values of the columns of each row it returns. Statements
+// run with Exec report the number of rows as the rows they affected.
+type Handler func(args ...any) ([][]any, error)
+
+// DB is a database.DBTX that runs the handlers of the queries it is asked
+// to run. A query without a handler fails.
+type DB struct {
+	mu       sync.Mutex
+	handlers map[string]Handler
+	txs      []*Tx
+}
+
+func New() *DB {
+	return &DB{handlers: map[string]Handler{}}
+}
+
+// Handle answers the sqlc query named name, such as "GetUser", with h.
+func (db *DB) Handle(name string, h Handler) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.handlers[name] = h
+}
+
+func (db *DB) run(sql string, args []any) ([][]any, error) {
+	name := queryName(sql)
+	db.mu.Lock()
+	h, ok := db.handlers[name]
+	db.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("dbtest: no handler for query %q", name)
+	}
+	return h(args...)
+}
+
+func (db *DB) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	rows, err := db.run(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", len(rows))), nil
+}
+
+func (db *DB) Query(_ context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	values, err := db.run(sql, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{values: values}, nil
+}
+
+func (db *DB) QueryRow(_ context.Context, sql string, args ...interface{}) pgx.Row {
+	values, err := db.run(sql, args)
+	return row{values: values, err: err}
+}
+
+func (db *DB) CopyFrom(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error) {
+	return 0, errors.New("dbtest: CopyFrom is not supported")
+}
+
+func (db *DB) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults {
+	panic("dbtest: SendBatch is not supported")
+}
+
+// Begin starts a transaction, whose statements are answered by the handlers
+// of db as outside one.
+func (db *DB) Begin(context.Context) (pgx.Tx, error) {
+	tx := &Tx{db: db}
+	db.mu.Lock()
+	db.txs = append(db.txs, tx)
+	db.mu.Unlock()
+	return tx, nil
+}
+
+// Txs returns the transactions begun on db, in order.
+func (db *DB) Txs() []*Tx {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return slices.Clone(db.txs)
+}
+
+// Tx is a transaction of a DB, which records the statements run in it and
+// how it ended. Statements that are not sqlc queries, such as SET LOCAL, are
+// only recorded. It has no savepoints, large objects or prepared statements.
+type Tx struct {
+	pgx.Tx
+	db *DB
+
+	mu         sync.Mutex
+	statements []string
+	committed  bool
+	rolledBack bool
+}
+
+// Statements returns the SQL of the statements run in tx, in order.
+func (tx *Tx) Statements() []string {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return slices.Clone(tx.statements)
+}
+
+// Committed reports whether tx was committed, and RolledBack whether it was
+// rolled back instead.
+func (tx *Tx) Committed() bool {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return tx.committed
+}
+
+func (tx *Tx) RolledBack() bool {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return tx.rolledBack
+}
+
+// record notes sql as run in tx, failing once tx has ended.
+func (tx *Tx) record(sql string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.committed || tx.rolledBack {
+		return pgx.ErrTxClosed
+	}
+	tx.statements = append(tx.statements, sql)
+	return nil
+}
+
+func (tx *Tx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if err := tx.record(sql); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	if !strings.HasPrefix(strings.TrimLeft(sql, " \t\r\n"), "-- name: ") {
+		keyword, _, _ := strings.Cut(sql, " ")
+		return pgconn.NewCommandTag(keyword), nil
+	}
+	return tx.db.Exec(ctx, sql, args...)
+}
+
+func (tx *Tx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if err := tx.record(sql); err != nil {
+		return nil, err
+	}
+	return tx.db.Query(ctx, sql, args...)
+}
+
+func (tx *Tx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if err := tx.record(sql); err != nil {
+		return row{err: err}
+	}
+	return tx.db.QueryRow(ctx, sql, args...)
+}
+
+func (tx *Tx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return tx.db.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (tx *Tx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return tx.db.SendBatch(ctx, b)
+}
+
+func (tx *Tx) Commit(context.Context) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.committed || tx.rolledBack {
+		return pgx.ErrTxClosed
+	}
+	tx.committed = true
+	return nil
+}
+
+func (tx *Tx) Rollback(context.Context) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.committed || tx.rolledBack {
+		return pgx.ErrTxClosed
+	}
+	tx.rolledBack = true
+	return nil
+}
+
+// Row returns the values of the fields of v, a struct such as a sqlc model
+// or row type, in order, which is the order sqlc scans its columns in.
+func Row(v any) []any {
+	rv := reflect.ValueOf(v)
+	values := make([]any, rv.NumField())
+	for i := range values {
+		values[i] = rv.Field(i).Interface()
+	}
+	return values
+}
+
+// Rows returns the values of each of items, as Row does.
+func Rows[T any](items ...T) [][]any {
+	rows := make([][]any, len(items))
+	for i, item := range items {
+		rows[i] = Row(item)
+	}
+	return rows
+}
+
+// queryName returns the name sqlc gives a query in its first line, as in
+// "-- name: GetUser :one".
+func queryName(sql string) string {
+	line, _, _ := strings.Cut(strings.TrimLeft(sql, " \t\r\n"), "\n")
+	name, _ := strings.CutPrefix(line, "-- name: ")
+	name, _, _ = strings.Cut(name, " ")
+	return name
+}
+
+// scan sets each of dest, which are pointers, to the value of its column.
+// A nil value sets it to its zero value.
+func scan(values []any, dest []any) error {
+	if len(values) != len(dest) {
+		return fmt.Errorf("dbtest: %d columns scanned into %d destinations", len(values), len(dest))
+	}
+	for i, d := range dest {
+		target := reflect.ValueOf(d).Elem()
+		if values[i] == nil {
+			target.SetZero()
+			continue
+		}
+		value := reflect.ValueOf(values[i])
+		if !value.Type().AssignableTo(target.Type()) {
+			return fmt.Errorf("dbtest: column %d is a %s, scanned into a %s", i, value.Type(), target.Type())
+		}
+		target.Set(value)
+	}
+	return nil
+}
+
+type rows struct {
+	values [][]any
+	next   int
+}
+
+func (r *rows) Close()     {}
+func (r *rows) Err() error { return nil }
+
+func (r *rows) CommandTag() pgconn.CommandTag {
+	return pgconn.NewCommandTag(fmt.Sprintf("SELECT %d", len(r.values)))
+}
+
+func (r *rows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+
+func (r *rows) Next() bool {
+	if r.next >= len(r.values) {
+		return false
+	}
+	r.next++
+	return true
+}
+
+func (r *rows) Scan(dest ...any) error {
+	return scan(r.values[r.next-1], dest)
+}
+
+func (r *rows) Values() ([]any, error) {
+	return r.values[r.next-1], nil
+}
+
+func (r *rows) RawValues() [][]byte { return nil }
+func (r *rows) Conn() *pgx.Conn     { return nil }
+
+type row struct {
+	values [][]any
+	err    error
+}
+
+func (r row) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.values) == 0 {
+		return pgx.ErrNoRows
+	}
+	return scan(r.values[0], dest)
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IsUniqueViolation reports whether err was caused by a unique constraint,
+// such as two active users sharing an email address.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// IsUnavailable reports whether err shows the database could not be reached
+// or would not take the statement: a failed connection, a connection lost
+// mid statement, or a server that is shutting down, starting up or out of
+// connections, as during a failover. Timeouts are not counted, as a slow
+// statement says more about itself than about the database.
+func IsUnavailable(err error) bool {
+	if err == nil || IsTimeout(err) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "57P01", "57P02", "57P03", "53300":
+			return true
+		}
+		return strings.HasPrefix(pgErr.Code, "08")
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) || errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed)
+}
+
+// isConflict reports whether err was caused by a statement losing a
+// serialization race or a deadlock to another transaction, after which it
+// can be run again.
+func isConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
+
+// notSent reports whether err happened before the statement reached the
+// server, so that running it again cannot apply it twice.
+func notSent(err error) bool {
+	var connectErr *pgconn.ConnectError
+	return pgconn.SafeToRetry(err) || errors.As(err, &connectErr)
+}
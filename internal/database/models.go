@@ -8,10 +8,133 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AuditEvent struct {
+	ID        int64
+	UserID    int32
+	Action    string
+	Actor     string
+	RequestID string
+	Framework string
+	Changes   []byte
+	CreatedAt pgtype.Timestamptz
+}
+
+type IdempotencyKey struct {
+	Key         string
+	Fingerprint string
+	Status      pgtype.Int4
+	Headers     []byte
+	Body        []byte
+	CreatedAt   pgtype.Timestamptz
+	ExpiresAt   pgtype.Timestamptz
+	Scope       string
+	ClaimToken  string
+}
+
+type Job struct {
+	ID          int64
+	Kind        string
+	Payload     []byte
+	Status      string
+	Attempts    int32
+	MaxAttempts int32
+	RunAt       pgtype.Timestamptz
+	UniqueKey   pgtype.Text
+	LastError   pgtype.Text
+	HeartbeatAt pgtype.Timestamptz
+	CreatedAt   pgtype.Timestamptz
+	StartedAt   pgtype.Timestamptz
+	FinishedAt  pgtype.Timestamptz
+}
+
+type OutboxEvent struct {
+	ID            int64
+	UserID        int32
+	EventType     string
+	Payload       []byte
+	Status        string
+	Attempts      int32
+	LastError     pgtype.Text
+	NextAttemptAt pgtype.Timestamptz
+	CreatedAt     pgtype.Timestamptz
+	DispatchedAt  pgtype.Timestamptz
+	PublishedTo   []string
+}
+
 type User struct {
 	ID        int32
 	Name      string
 	Email     string
 	Age       int32
 	CreatedAt pgtype.Timestamptz
+	DeletedAt pgtype.Timestamptz
+}
+
+type UserImport struct {
+	ID            int32
+	Status        string
+	Format        string
+	OnDuplicate   string
+	DryRun        bool
+	ColumnMap     []byte
+	TotalRows     int32
+	ProcessedRows int32
+	CreatedRows   int32
+	UpdatedRows   int32
+	SkippedRows   int32
+	FailedRows    int32
+	Error         pgtype.Text
+	Actor         string
+	RequestID     string
+	Framework     string
+	CreatedAt     pgtype.Timestamptz
+	StartedAt     pgtype.Timestamptz
+	FinishedAt    pgtype.Timestamptz
+}
+
+type UserImportError struct {
+	ID        int64
+	ImportID  int32
+	RowNumber int32
+	Email     string
+	Error     string
+}
+
+type UserImportFile struct {
+	ImportID int32
+	Data     []byte
+}
+
+type Webhook struct {
+	ID           int32
+	Url          string
+	Secret       string
+	Events       []string
+	Active       bool
+	FailureCount int32
+	DisabledAt   pgtype.Timestamptz
+	CreatedAt    pgtype.Timestamptz
+}
+
+type WebhookDelivery struct {
+	ID               int64
+	WebhookID        int32
+	EventID          int64
+	EventType        string
+	Payload          []byte
+	Status           string
+	Attempts         int32
+	LastResponseCode pgtype.Int4
+	LastError        pgtype.Text
+	NextAttemptAt    pgtype.Timestamptz
+	CreatedAt        pgtype.Timestamptz
+}
+
+type WebhookDeliveryAttempt struct {
+	ID           int64
+	DeliveryID   int64
+	ResponseCode pgtype.Int4
+	Error        pgtype.Text
+	DurationMs   int32
+	CreatedAt    pgtype.Timestamptz
 }
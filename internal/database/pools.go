@@ -0,0 +1,76 @@
+package database
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/metrics"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// pools are the connection pools whose statistics are reported, by the name
+// of the database they connect to. Each server has pools of its own, so the
+// statistics of a name add up those of its pools.
+var pools struct {
+	sync.Mutex
+	byName map[string][]*pgxpool.Pool
+}
+
+// ObservePool reports the statistics of pool under the name of its
+// database, such as primary.
+func ObservePool(name string, pool *pgxpool.Pool) {
+	pools.Lock()
+	defer pools.Unlock()
+	if pools.byName == nil {
+		pools.byName = map[string][]*pgxpool.Pool{}
+	}
+	pools.byName[name] = append(pools.byName[name], pool)
+}
+
+// UnobservePool stops reporting the statistics of pool, once it is closed.
+func UnobservePool(name string, pool *pgxpool.Pool) {
+	pools.Lock()
+	defer pools.Unlock()
+	all := slices.DeleteFunc(pools.byName[name], func(p *pgxpool.Pool) bool { return p == pool })
+	if len(all) == 0 {
+		delete(pools.byName, name)
+		return
+	}
+	pools.byName[name] = all
+}
+
+func init() {
+	// collect reports value, added up over the pools of each name.
+	collect := func(value func(s *pgxpool.Stat) float64) func(report func(float64, ...string)) {
+		return func(report func(float64, ...string)) {
+			pools.Lock()
+			defer pools.Unlock()
+			for name, all := range pools.byName {
+				total := 0.0
+				for _, pool := range all {
+					total += value(pool.Stat())
+				}
+				report(total, name)
+			}
+		}
+	}
+
+	metrics.NewGaugeFunc("db_pool_connections", "Connections of the pools of each database, by state.",
+		[]string{"pool", "state"}, func(report func(float64, ...string)) {
+			for state, value := range map[string]func(s *pgxpool.Stat) float64{
+				"acquired":     func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) },
+				"idle":         func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) },
+				"constructing": func(s *pgxpool.Stat) float64 { return float64(s.ConstructingConns()) },
+			} {
+				collect(value)(func(v float64, labels ...string) { report(v, labels[0], state) })
+			}
+		})
+	metrics.NewGaugeFunc("db_pool_max_connections", "The most connections the pools of each database may open.",
+		[]string{"pool"}, collect(func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) }))
+	metrics.NewCounterFunc("db_pool_acquires_total", "Connections acquired from the pools of each database.",
+		[]string{"pool"}, collect(func(s *pgxpool.Stat) float64 { return float64(s.AcquireCount()) }))
+	metrics.NewCounterFunc("db_pool_empty_acquires_total", "Connections acquired from the pools of each database that had to wait for one.",
+		[]string{"pool"}, collect(func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) }))
+	metrics.NewCounterFunc("db_pool_acquire_wait_seconds_total", "Time spent waiting for connections from the pools of each database.",
+		[]string{"pool"}, collect(func(s *pgxpool.Stat) float64 { return s.AcquireDuration().Seconds() }))
+}
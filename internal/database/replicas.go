@@ -0,0 +1,279 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/metrics"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var routedReads = metrics.NewCounter("db_routed_reads_total",
+	"Reads that may go to a replica, by the pool they went to and why: replica, or for the primary sticky, unusable when no replica is within its lag, or unavailable when the replica failed.",
+	"pool", "reason")
+
+// replicas are every Replica, for the gauges.
+var replicas struct {
+	sync.Mutex
+	all []*Replica
+}
+
+func init() {
+	gauge := func(name, help string, value func(r *Replica) float64) {
+		metrics.NewGaugeFunc(name, help, []string{"pool"}, func(report func(float64, ...string)) {
+			replicas.Lock()
+			defer replicas.Unlock()
+			for _, r := range replicas.all {
+				report(value(r), r.name)
+			}
+		})
+	}
+	gauge("db_replica_lag_seconds", "How far each replica was behind the primary when last checked.",
+		func(r *Replica) float64 { lag, _ := r.Status(); return lag.Seconds() })
+	gauge("db_replica_usable", "Whether reads go to each replica, 1 if they do.",
+		func(r *Replica) float64 {
+			if _, usable := r.Status(); usable {
+				return 1
+			}
+			return 0
+		})
+}
+
+// lagQuery returns how many seconds a replica is behind: none if it has
+// replayed all it has received, otherwise the age of the last transaction
+// it replayed. On a primary it is always none.
+const lagQuery = `
+SELECT CASE
+    WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+    ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END::float8
+`
+
+// Replica is the health of a read replica, as checked in the background,
+// shared by the pools that connect to it.
+type Replica struct {
+	name    string
+	maxLag  time.Duration
+	breaker *Breaker
+
+	mu        sync.Mutex
+	interval  time.Duration
+	lag       time.Duration
+	checkedAt time.Time
+	err       error
+}
+
+// NewReplica returns the health of the named replica, which reads go to
+// while it is no more than maxLag behind the primary. Until Monitor has
+// checked it, none do.
+func NewReplica(name string, maxLag time.Duration, breaker *Breaker) *Replica {
+	r := &Replica{name: name, maxLag: maxLag, breaker: breaker}
+	replicas.Lock()
+	replicas.all = append(replicas.all, r)
+	replicas.Unlock()
+	return r
+}
+
+// Close stops reporting r in the gauges, once it is no longer used.
+func (r *Replica) Close() {
+	replicas.Lock()
+	defer replicas.Unlock()
+	replicas.all = slices.DeleteFunc(replicas.all, func(other *Replica) bool { return other == r })
+}
+
+func (r *Replica) Name() string {
+	return r.name
+}
+
+func (r *Replica) Breaker() *Breaker {
+	return r.breaker
+}
+
+// Monitor checks the lag of the replica through db every interval until ctx
+// is done.
+func (r *Replica) Monitor(ctx context.Context, db DBTX, interval time.Duration) {
+	r.mu.Lock()
+	r.interval = interval
+	r.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		var seconds float64
+		err := db.QueryRow(checkCtx, lagQuery).Scan(&seconds)
+		cancel()
+
+		r.mu.Lock()
+		r.checkedAt, r.err = time.Now(), err
+		if err == nil {
+			r.lag = time.Duration(seconds * float64(time.Second))
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns the lag of the replica when last measured, and whether
+// reads go to it: its last check succeeded, no more than a few intervals
+// ago, and found it within the maximum lag.
+func (r *Replica) Status() (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	usable := r.err == nil && !r.checkedAt.IsZero() && time.Since(r.checkedAt) < 3*r.interval && r.lag <= r.maxLag
+	return r.lag, usable
+}
+
+type usePrimaryKey struct{}
+
+// UsePrimary returns a context whose reads all go to the primary, for a
+// client that must see its own writes.
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, usePrimaryKey{}, true)
+}
+
+// ReplicaDB is a replica and what its statements are run on.
+type ReplicaDB struct {
+	Replica *Replica
+	DB      DBTX
+}
+
+// WithReplicas wraps primary so that the sqlc queries named in reads go to
+// the usable replicas in turn, unless their context asks for the primary
+// with UsePrimary. A read is run on the primary instead if no replica is
+// usable, or if its replica turns out to be unavailable. Everything else
+// goes to the primary.
+func WithReplicas(primary DBTX, replicas []ReplicaDB, reads ...string) DBTX {
+	if len(replicas) == 0 {
+		return primary
+	}
+	rr := &replicaRouter{primary: primary, replicas: replicas, reads: map[string]bool{}}
+	for _, name := range reads {
+		rr.reads[name] = true
+	}
+	return rr
+}
+
+type replicaRouter struct {
+	primary  DBTX
+	replicas []ReplicaDB
+	reads    map[string]bool
+	next     atomic.Uint32
+}
+
+// route returns the replica a statement goes to, or nil and why it goes to
+// the primary instead, which is "" for statements that never go to one.
+func (rr *replicaRouter) route(ctx context.Context, sql string) (*ReplicaDB, string) {
+	if !rr.reads[queryName(sql)] {
+		return nil, ""
+	}
+	if primary, _ := ctx.Value(usePrimaryKey{}).(bool); primary {
+		return nil, "sticky"
+	}
+	start := int(rr.next.Add(1))
+	for i := range rr.replicas {
+		replica := &rr.replicas[(start+i)%len(rr.replicas)]
+		if _, usable := replica.Replica.Status(); usable {
+			return replica, ""
+		}
+	}
+	return nil, "unusable"
+}
+
+// onReplica runs a read on replica, reporting whether it should be run on
+// the primary instead. A failure on the replica is not noted as the
+// database being unavailable, since the primary may serve the read.
+func (rr *replicaRouter) onReplica(ctx context.Context, replica *ReplicaDB, read func(context.Context, DBTX) error) bool {
+	err := read(TrackUnavailable(ctx), replica.DB)
+	if errors.Is(err, ErrCircuitOpen) || IsUnavailable(err) {
+		return false
+	}
+	routedReads.Inc(replica.Replica.name, "replica")
+	return true
+}
+
+func (rr *replicaRouter) onPrimary(reason string) DBTX {
+	if reason != "" {
+		routedReads.Inc("primary", reason)
+	}
+	return rr.primary
+}
+
+func (rr *replicaRouter) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	replica, reason := rr.route(ctx, sql)
+	if replica != nil {
+		var rows pgx.Rows
+		var err error
+		if rr.onReplica(ctx, replica, func(ctx context.Context, db DBTX) error {
+			rows, err = db.Query(ctx, sql, args...)
+			return err
+		}) {
+			return rows, err
+		}
+		reason = "unavailable"
+	}
+	return rr.onPrimary(reason).Query(ctx, sql, args...)
+}
+
+func (rr *replicaRouter) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &routedRow{rr: rr, ctx: ctx, sql: sql, args: args}
+}
+
+// The statement of a row runs when it is scanned, so that is when it is
+// routed.
+type routedRow struct {
+	rr   *replicaRouter
+	ctx  context.Context
+	sql  string
+	args []interface{}
+}
+
+func (row *routedRow) Scan(dest ...any) error {
+	replica, reason := row.rr.route(row.ctx, row.sql)
+	if replica != nil {
+		var err error
+		if row.rr.onReplica(row.ctx, replica, func(ctx context.Context, db DBTX) error {
+			err = db.QueryRow(ctx, row.sql, row.args...).Scan(dest...)
+			return err
+		}) {
+			return err
+		}
+		reason = "unavailable"
+	}
+	return row.rr.onPrimary(reason).QueryRow(row.ctx, row.sql, row.args...).Scan(dest...)
+}
+
+func (rr *replicaRouter) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return rr.primary.Exec(ctx, sql, args...)
+}
+
+func (rr *replicaRouter) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return rr.primary.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (rr *replicaRouter) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return rr.primary.SendBatch(ctx, b)
+}
+
+// queryName returns the name sqlc gives a query in its first line, as in
+// "-- name: GetUser :one", or "" if it has none.
+func queryName(sql string) string {
+	line, _, _ := strings.Cut(strings.TrimLeft(sql, " \t\r\n"), "\n")
+	name, ok := strings.CutPrefix(line, "-- name: ")
+	if !ok {
+		return ""
+	}
+	name, _, _ = strings.Cut(name, " ")
+	return name
+}
@@ -0,0 +1,369 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/metrics"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var retries = metrics.NewCounter("db_retries_total",
+	"Database statements and transactions run again after a transient failure, by why.",
+	"pool", "kind", "reason")
+
+// RetryPolicy controls how statements and transactions that fail for a
+// transient reason are run again: with an exponentially growing, jittered
+// delay between attempts.
+type RetryPolicy struct {
+	// Attempts is the number of attempts, including the first. 1 disables
+	// retries.
+	Attempts   int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// backoff returns how long to wait before the attempt after attempt: a
+// random delay between half and all of an exponentially growing backoff.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff << (attempt - 1)
+	if d > p.MaxBackoff || d <= 0 {
+		d = p.MaxBackoff
+	}
+	if d > 0 {
+		d = d/2 + rand.N(d/2+1)
+	}
+	return d
+}
+
+// run calls fn until it succeeds, fails with an error reason does not give
+// a reason to retry for, has been called p.Attempts times, or ctx is done.
+func (p RetryPolicy) run(ctx context.Context, pool, kind string, reason func(error) string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || ctx.Err() != nil {
+			return err
+		}
+		why := reason(err)
+		if why == "" {
+			return err
+		}
+
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		retries.Inc(pool, kind, why)
+	}
+}
+
+// retryReason says why a failed statement may be run again, or "" if it may
+// not. Reads may always be; anything else only if it never reached the
+// server.
+func retryReason(read bool) func(error) string {
+	return func(err error) string {
+		switch {
+		case errors.Is(err, ErrCircuitOpen):
+			return ""
+		case IsUnavailable(err) && (read || notSent(err)):
+			return "unavailable"
+		case isConflict(err) && read:
+			return "conflict"
+		}
+		return ""
+	}
+}
+
+// reads are the queries, by name, that only read, and so can be run again
+// without changing anything. Being a SELECT is not enough, as a SELECT may
+// call a function with effects, such as the pg_notify of Notify, and
+// statements without a name are not known to be reads.
+var reads = map[string]bool{
+	"GetActiveUsersByEmail":    true,
+	"GetIdempotencyKey":        true,
+	"GetJob":                   true,
+	"GetUser":                  true,
+	"GetUserImport":            true,
+	"GetUserImportFile":        true,
+	"GetUserIncludingDeleted":  true,
+	"GetUsers":                 true,
+	"GetUsersIncludingDeleted": true,
+	"GetWebhook":               true,
+	"ListAuditEvents":          true,
+	"ListJobs":                 true,
+	"ListUserImportErrors":     true,
+	"ListUsers":                true,
+	"ListWebhookDeliveries":    true,
+	"ListWebhooks":             true,
+	"StreamUsers":              true,
+}
+
+// isRead reports whether sql is one of the reads.
+func isRead(sql string) bool {
+	return reads[queryName(sql)]
+}
+
+type unavailableKey struct{}
+
+// TrackUnavailable returns a context in which a statement that fails for
+// the database being unavailable, or its circuit breaker being open, is
+// noted, for Unavailable.
+func TrackUnavailable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unavailableKey{}, new(atomic.Int64))
+}
+
+// Unavailable reports whether a statement run with ctx, or a context derived
+// from it, has failed for the database being unavailable since
+// TrackUnavailable, and how long until it is worth trying again, if known.
+func Unavailable(ctx context.Context) (time.Duration, bool) {
+	noted, ok := ctx.Value(unavailableKey{}).(*atomic.Int64)
+	if !ok || noted.Load() == 0 {
+		return 0, false
+	}
+	return time.Duration(noted.Load() - 1), true
+}
+
+func noteUnavailable(ctx context.Context, breaker *Breaker, err error) error {
+	if errors.Is(err, ErrCircuitOpen) || IsUnavailable(err) {
+		if noted, ok := ctx.Value(unavailableKey{}).(*atomic.Int64); ok {
+			noted.Store(int64(breaker.RetryAfter()) + 1)
+		}
+	}
+	return err
+}
+
+// WithResilience wraps db so that statements fail fast with ErrCircuitOpen
+// while breaker is open, and are run again by policy when they fail for a
+// transient reason. Reads are retried when the database is unavailable or
+// they lose a serialization race or a deadlock; writes only when they never
+// reached the server, so that none is applied twice. Rows are only retried
+// until the first one has been read.
+func WithResilience(db DBTX, policy RetryPolicy, breaker *Breaker) DBTX {
+	return &resilientDB{db: db, policy: policy, breaker: breaker}
+}
+
+type resilientDB struct {
+	db      DBTX
+	policy  RetryPolicy
+	breaker *Breaker
+}
+
+// do runs a statement by the retry policy, through the breaker.
+func (r *resilientDB) do(ctx context.Context, read bool, statement func() error) error {
+	err := r.policy.run(ctx, r.breaker.name, "statement", retryReason(read), func() error {
+		if err := r.breaker.Allow(); err != nil {
+			return err
+		}
+		err := statement()
+		r.breaker.record(ctx, err)
+		return err
+	})
+	return noteUnavailable(ctx, r.breaker, err)
+}
+
+func (r *resilientDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := r.do(ctx, isRead(sql), func() error {
+		var err error
+		tag, err = r.db.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+func (r *resilientDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := r.do(ctx, isRead(sql), func() error {
+		var err error
+		rows, err = r.db.Query(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+
+		// The error of a statement that fails as it starts only shows once
+		// its first row is read, so that is read here, where the statement
+		// can still be run again
+		peeked := &peekedRows{Rows: rows, next: rows.Next()}
+		if !peeked.next && rows.Err() != nil {
+			rows.Close()
+			return rows.Err()
+		}
+		rows = peeked
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *resilientDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &resilientRow{r: r, ctx: ctx, sql: sql, args: args}
+}
+
+// CopyFrom and SendBatch are never retried, as the rows of a copy are read
+// as they are sent and the results of a batch are read by the caller.
+
+func (r *resilientDB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	if err := r.breaker.Allow(); err != nil {
+		return 0, noteUnavailable(ctx, r.breaker, err)
+	}
+	n, err := r.db.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	r.breaker.record(ctx, err)
+	return n, noteUnavailable(ctx, r.breaker, err)
+}
+
+func (r *resilientDB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if err := r.breaker.Allow(); err != nil {
+		return failedBatch{noteUnavailable(ctx, r.breaker, err)}
+	}
+	return &resilientBatch{BatchResults: r.db.SendBatch(ctx, b), r: r, ctx: ctx}
+}
+
+// peekedRows are rows whose first row has already been read.
+type peekedRows struct {
+	pgx.Rows
+	next    bool
+	started bool
+}
+
+func (p *peekedRows) Next() bool {
+	if !p.started {
+		p.started = true
+		return p.next
+	}
+	return p.Rows.Next()
+}
+
+// The statement of a row runs when it is scanned, so that is when it is
+// retried.
+type resilientRow struct {
+	r    *resilientDB
+	ctx  context.Context
+	sql  string
+	args []interface{}
+}
+
+func (row *resilientRow) Scan(dest ...any) error {
+	return row.r.do(row.ctx, isRead(row.sql), func() error {
+		return row.r.db.QueryRow(row.ctx, row.sql, row.args...).Scan(dest...)
+	})
+}
+
+type resilientBatch struct {
+	pgx.BatchResults
+	r   *resilientDB
+	ctx context.Context
+}
+
+func (b *resilientBatch) Close() error {
+	err := b.BatchResults.Close()
+	b.r.breaker.record(b.ctx, err)
+	return noteUnavailable(b.ctx, b.r.breaker, err)
+}
+
+// failedBatch is the result of a batch that was not sent.
+type failedBatch struct{ err error }
+
+func (b failedBatch) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, b.err }
+func (b failedBatch) Query() (pgx.Rows, error)         { return nil, b.err }
+func (b failedBatch) QueryRow() pgx.Row                { return failedRow(b) }
+func (b failedBatch) Close() error                     { return b.err }
+
+type failedRow struct{ err error }
+
+func (r failedRow) Scan(...any) error { return r.err }
+
+// TxBeginner starts transactions, as a pool does.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// RunTx runs fn in a transaction of db, committing only if fn returns nil.
+// Its statements, run as fn runs them on the transaction it is given, each
+// have statementTimeout, which the server enforces with SET LOCAL
+// statement_timeout as well as the client with a deadline. They go through
+// breaker, but are not retried on their own, as a failed statement aborts
+// the transaction. Instead, the whole transaction
+// is run again by policy when it fails for the database being unavailable,
+// or loses a serialization race or a deadlock, before it commits. One that
+// fails as it commits is only run again for the last two, as it may have
+// been committed otherwise. fn must be safe to run more than once unless
+// the policy has a single attempt.
+func RunTx(ctx context.Context, db TxBeginner, policy RetryPolicy, breaker *Breaker, statementTimeout time.Duration, fn func(pgx.Tx) error) error {
+	committing := false
+	reason := func(err error) string {
+		switch {
+		case isConflict(err):
+			return "conflict"
+		case IsUnavailable(err) && !committing:
+			return "unavailable"
+		}
+		return ""
+	}
+
+	err := policy.run(ctx, breaker.name, "transaction", reason, func() error {
+		committing = false
+		if err := breaker.Allow(); err != nil {
+			return err
+		}
+		tx, err := db.Begin(ctx)
+		breaker.record(ctx, err)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		statements := WithStatementTimeout(WithResilience(tx, RetryPolicy{Attempts: 1}, breaker), statementTimeout)
+		if statementTimeout > 0 {
+			_, err := statements.Exec(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", max(statementTimeout.Milliseconds(), 1)))
+			if err != nil {
+				return err
+			}
+		}
+		if err := fn(&boundTx{Tx: tx, db: statements}); err != nil {
+			return err
+		}
+
+		committing = true
+		err = tx.Commit(ctx)
+		breaker.record(ctx, err)
+		return err
+	})
+	return noteUnavailable(ctx, breaker, err)
+}
+
+// boundTx is a transaction whose statements run through db, so that queries
+// bound to it with Queries.WithTx have the timeout and breaker of db.
+type boundTx struct {
+	pgx.Tx
+	db DBTX
+}
+
+func (t *boundTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return t.db.Exec(ctx, sql, args...)
+}
+
+func (t *boundTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return t.db.Query(ctx, sql, args...)
+}
+
+func (t *boundTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return t.db.QueryRow(ctx, sql, args...)
+}
+
+func (t *boundTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return t.db.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (t *boundTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return t.db.SendBatch(ctx, b)
+}
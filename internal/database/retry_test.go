@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database/dbtest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRunTxBindsQueriesToTheTransaction(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.New()
+	db.Handle("GetUserForUpdate", func(args ...any) ([][]any, error) {
+		return dbtest.Rows(User{ID: args[0].(int32), Name: "Jane"}), nil
+	})
+	db.Handle("DeleteUser", func(args ...any) ([][]any, error) {
+		return dbtest.Rows(User{ID: args[0].(int32)}), nil
+	})
+	breaker := NewBreaker("tx-test", 5, time.Second)
+	policy := RetryPolicy{Attempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	// The first run loses a serialization race, so the transaction is run
+	// again, and only that run commits
+	runs := 0
+	err := RunTx(ctx, db, policy, breaker, time.Second, func(tx pgx.Tx) error {
+		runs++
+		q := New(db).WithTx(tx)
+		if _, err := q.GetUserForUpdate(ctx, 1); err != nil {
+			return err
+		}
+		if runs == 1 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		_, err := q.DeleteUser(ctx, 1)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txs := db.Txs()
+	if len(txs) != 2 {
+		t.Fatalf("%d transactions, want 2", len(txs))
+	}
+	if txs[0].Committed() || !txs[0].RolledBack() {
+		t.Error("the failed run was not rolled back")
+	}
+	if !txs[1].Committed() {
+		t.Error("the second run was not committed")
+	}
+	// Both queries ran in the transaction, after its statement timeout
+	if n := len(txs[1].Statements()); n != 3 {
+		t.Errorf("%d statements ran in the transaction, want both queries and the timeout", n)
+	}
+}
+
+func TestRunTxSetsTheStatementTimeoutOnTheServer(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    []string
+	}{
+		{1500 * time.Millisecond, []string{"SET LOCAL statement_timeout = 1500"}},
+		// statement_timeout = 0 would be no limit at all
+		{500 * time.Microsecond, []string{"SET LOCAL statement_timeout = 1"}},
+		{0, nil},
+	}
+	for _, tt := range tests {
+		db := dbtest.New()
+		err := RunTx(context.Background(), db, RetryPolicy{Attempts: 1}, NewBreaker("tx-test", 5, time.Second), tt.timeout, func(pgx.Tx) error {
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := db.Txs()[0].Statements(); !slices.Equal(got, tt.want) {
+			t.Errorf("timeout %v: ran %q, want %q", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestRunTxRollsBackOnError(t *testing.T) {
+	db := dbtest.New()
+	failed := errors.New("failed")
+	err := RunTx(context.Background(), db, RetryPolicy{Attempts: 3}, NewBreaker("tx-test", 5, time.Second), 0, func(pgx.Tx) error {
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("got %v, want the error of fn", err)
+	}
+	// An error that is not transient is not retried
+	if txs := db.Txs(); len(txs) != 1 || txs[0].Committed() || !txs[0].RolledBack() {
+		t.Fatalf("got %d transactions, want one rolled back", len(txs))
+	}
+}
+
+// Every read is a query that only selects, without taking locks.
+func TestReadsAreSelects(t *testing.T) {
+	files, err := filepath.Glob("../sql/queries/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no queries found: %v", err)
+	}
+	statements := map[string]string{"StreamUsers": streamUsers}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, query := range strings.Split(string(data), "-- name: ")[1:] {
+			name, _, _ := strings.Cut(query, " ")
+			statements[name] = "-- name: " + query
+		}
+	}
+
+	for name := range reads {
+		sql, ok := statements[name]
+		if !ok {
+			t.Errorf("%s: no such query", name)
+			continue
+		}
+		_, body, _ := strings.Cut(sql, "\n")
+		if !strings.HasPrefix(strings.TrimSpace(body), "SELECT") || strings.Contains(body, "FOR UPDATE") {
+			t.Errorf("%s: not a plain SELECT", name)
+		}
+	}
+
+	for _, sql := range []string{notify, "SELECT 1", createUser, getUserForUpdate} {
+		if isRead(sql) {
+			t.Errorf("%q counts as a read", sql)
+		}
+	}
+}
+
+// A read is run again after the database was unavailable, but a statement
+// with effects that reached the server is not, even when it is a SELECT.
+func TestOnlyReadsAreRetriedAfterReachingTheServer(t *testing.T) {
+	ctx := context.Background()
+	calls := map[string]int{}
+	unavailableOnce := func(name string, rows [][]any) dbtest.Handler {
+		return func(args ...any) ([][]any, error) {
+			calls[name]++
+			if calls[name] == 1 {
+				return nil, &pgconn.PgError{Code: "57P01"}
+			}
+			return rows, nil
+		}
+	}
+	db := dbtest.New()
+	db.Handle("GetUser", unavailableOnce("GetUser", dbtest.Rows(User{ID: 1})))
+	db.Handle("Notify", unavailableOnce("Notify", nil))
+
+	policy := RetryPolicy{Attempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	q := New(WithResilience(db, policy, NewBreaker("retry-test", 5, time.Second)))
+
+	if _, err := q.GetUser(ctx, 1); err != nil {
+		t.Errorf("GetUser: %v", err)
+	}
+	if err := q.Notify(ctx, NotifyParams{Channel: "users", Payload: "{}"}); err == nil {
+		t.Error("Notify succeeded, want its failure")
+	}
+	if calls["GetUser"] != 2 || calls["Notify"] != 1 {
+		t.Errorf("ran GetUser %d and Notify %d times, want 2 and 1", calls["GetUser"], calls["Notify"])
+	}
+}
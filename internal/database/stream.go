@@ -0,0 +1,42 @@
+package database
+
+import "context"
+
+// sqlc can only collect :many results into a slice, so queries that must
+// stream rows of unbounded size live here instead.
+
+const streamUsers = `-- name: StreamUsers :many
+SELECT id, name, email, age, created_at, deleted_at FROM users
+WHERE $1::boolean OR deleted_at IS NULL
+ORDER BY created_at DESC
+`
+
+// StreamUsers calls fn with every user, newest first, as rows arrive from the
+// server, so memory use does not grow with the table. Soft-deleted users are
+// included when includeDeleted is true. Iteration stops at the first error
+// fn returns. The query runs for as long as fn takes, so it is bounded by
+// ctx alone, and not by the statement timeout.
+func (q *Queries) StreamUsers(ctx context.Context, includeDeleted bool, fn func(User) error) error {
+	rows, err := q.db.Query(WithoutStatementTimeout(ctx), streamUsers, includeDeleted)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.Age,
+			&i.CreatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
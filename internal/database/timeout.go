@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/metrics"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var statementTimeouts = metrics.NewCounter("db_statement_timeouts_total",
+	"Database statements canceled for running out of time, by whether the statement or the request it ran for ran out.",
+	"deadline")
+
+// WithStatementTimeout wraps db so that every statement is canceled once it
+// has run for timeout, or once its context is done, whichever comes first.
+// This is a deadline on the client: when it passes, the connection sends
+// the server a cancel request, as set up by Connect. The server enforces
+// the timeout itself only in transactions, which RunTx begins with SET
+// LOCAL statement_timeout. A timeout of 0 leaves statements to their
+// context. Statements that time out are counted, and noted for TimedOut.
+func WithStatementTimeout(db DBTX, timeout time.Duration) DBTX {
+	return &timedDB{db: db, timeout: timeout}
+}
+
+type noStatementTimeoutKey struct{}
+
+// WithoutStatementTimeout returns a context whose statements are bounded by
+// the context alone, for a query that streams its rows for as long as the
+// client reads them.
+func WithoutStatementTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noStatementTimeoutKey{}, true)
+}
+
+type timedOutKey struct{}
+
+// TrackTimeouts returns a context in which a statement that times out is
+// noted, for TimedOut.
+func TrackTimeouts(ctx context.Context) context.Context {
+	return context.WithValue(ctx, timedOutKey{}, new(atomic.Bool))
+}
+
+// TimedOut reports whether a statement run with ctx, or a context derived
+// from it, has timed out since TrackTimeouts.
+func TimedOut(ctx context.Context) bool {
+	timedOut, ok := ctx.Value(timedOutKey{}).(*atomic.Bool)
+	return ok && timedOut.Load()
+}
+
+// IsTimeout reports whether err was caused by a statement running out of
+// time, on the client or as canceled by the server.
+func IsTimeout(err error) bool {
+	var pgErr *pgconn.PgError
+	return pgconn.Timeout(err) || errors.Is(err, context.DeadlineExceeded) ||
+		(errors.As(err, &pgErr) && pgErr.Code == "57014")
+}
+
+type timedDB struct {
+	db      DBTX
+	timeout time.Duration
+}
+
+// start returns the context to run a statement with, and the function that
+// ends it, noting whether it timed out.
+func (t *timedDB) start(ctx context.Context) (context.Context, func(error) error) {
+	statementCtx, cancel := ctx, context.CancelFunc(func() {})
+	if t.timeout > 0 && ctx.Value(noStatementTimeoutKey{}) == nil {
+		statementCtx, cancel = context.WithTimeout(ctx, t.timeout)
+	}
+	return statementCtx, func(err error) error {
+		cancel()
+		if err != nil && IsTimeout(err) {
+			deadline := "statement"
+			if ctx.Err() != nil {
+				deadline = "request"
+			}
+			statementTimeouts.Inc(deadline)
+			if timedOut, ok := ctx.Value(timedOutKey{}).(*atomic.Bool); ok {
+				timedOut.Store(true)
+			}
+		}
+		return err
+	}
+}
+
+func (t *timedDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, end := t.start(ctx)
+	tag, err := t.db.Exec(ctx, sql, args...)
+	return tag, end(err)
+}
+
+func (t *timedDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, end := t.start(ctx)
+	rows, err := t.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, end(err)
+	}
+	return &timedRows{Rows: rows, end: end}, nil
+}
+
+func (t *timedDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, end := t.start(ctx)
+	return &timedRow{row: t.db.QueryRow(ctx, sql, args...), end: end}
+}
+
+func (t *timedDB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	ctx, end := t.start(ctx)
+	n, err := t.db.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	return n, end(err)
+}
+
+func (t *timedDB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	ctx, end := t.start(ctx)
+	return &timedBatch{BatchResults: t.db.SendBatch(ctx, b), end: end}
+}
+
+// The statement of rows, a row or a batch runs until they are closed or
+// scanned, so that is when it ends.
+
+type timedRows struct {
+	pgx.Rows
+	end    func(error) error
+	closed bool
+}
+
+func (r *timedRows) Close() {
+	r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		r.end(r.Rows.Err())
+	}
+}
+
+type timedRow struct {
+	row pgx.Row
+	end func(error) error
+}
+
+func (r *timedRow) Scan(dest ...any) error {
+	return r.end(r.row.Scan(dest...))
+}
+
+type timedBatch struct {
+	pgx.BatchResults
+	end func(error) error
+}
+
+func (b *timedBatch) Close() error {
+	return b.end(b.BatchResults.Close())
+}
@@ -7,6 +7,8 @@ package database
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -14,7 +16,7 @@ INSERT INTO users (
     name, email, age
 ) VALUES (
     $1, $2, $3
-) RETURNING id, name, email, age, created_at
+) RETURNING id, name, email, age, created_at, deleted_at
 `
 
 type CreateUserParams struct {
@@ -32,23 +34,105 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.Age,
 		&i.CreatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const deleteUser = `-- name: DeleteUser :exec
-DELETE FROM users
-WHERE id = $1
+type CreateUsersParams struct {
+	Name  string
+	Email string
+	Age   int32
+}
+
+const deleteUser = `-- name: DeleteUser :one
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL RETURNING id, name, email, age, created_at, deleted_at
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRow(ctx, deleteUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Age,
+		&i.CreatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const deleteUsers = `-- name: DeleteUsers :many
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = ANY($1::int[]) AND deleted_at IS NULL RETURNING id, name, email, age, created_at, deleted_at
 `
 
-func (q *Queries) DeleteUser(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, deleteUser, id)
-	return err
+func (q *Queries) DeleteUsers(ctx context.Context, ids []int32) ([]User, error) {
+	rows, err := q.db.Query(ctx, deleteUsers, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.Age,
+			&i.CreatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getActiveUsersByEmail = `-- name: GetActiveUsersByEmail :many
+SELECT id, name, email, age, created_at, deleted_at FROM users
+WHERE email = ANY($1::text[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetActiveUsersByEmail(ctx context.Context, emails []string) ([]User, error) {
+	rows, err := q.db.Query(ctx, getActiveUsersByEmail, emails)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.Age,
+			&i.CreatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, name, email, age, created_at FROM users
-WHERE id = $1 LIMIT 1
+SELECT id, name, email, age, created_at, deleted_at FROM users
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1
 `
 
 func (q *Queries) GetUser(ctx context.Context, id int32) (User, error) {
@@ -60,12 +144,53 @@ func (q *Queries) GetUser(ctx context.Context, id int32) (User, error) {
 		&i.Email,
 		&i.Age,
 		&i.CreatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getUserForUpdate = `-- name: GetUserForUpdate :one
+SELECT id, name, email, age, created_at, deleted_at FROM users
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) GetUserForUpdate(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRow(ctx, getUserForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Age,
+		&i.CreatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getUserIncludingDeleted = `-- name: GetUserIncludingDeleted :one
+SELECT id, name, email, age, created_at, deleted_at FROM users
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserIncludingDeleted(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRow(ctx, getUserIncludingDeleted, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Age,
+		&i.CreatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const getUsers = `-- name: GetUsers :many
-SELECT id, name, email, age, created_at FROM users
+SELECT id, name, email, age, created_at, deleted_at FROM users
+WHERE deleted_at IS NULL
 ORDER BY created_at DESC
 `
 
@@ -84,6 +209,113 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.Email,
 			&i.Age,
 			&i.CreatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsersForUpdateByIDs = `-- name: GetUsersForUpdateByIDs :many
+SELECT id, name, email, age, created_at, deleted_at FROM users
+WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+ORDER BY id
+FOR UPDATE
+`
+
+func (q *Queries) GetUsersForUpdateByIDs(ctx context.Context, ids []int32) ([]User, error) {
+	rows, err := q.db.Query(ctx, getUsersForUpdateByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.Age,
+			&i.CreatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUsersIncludingDeleted = `-- name: GetUsersIncludingDeleted :many
+SELECT id, name, email, age, created_at, deleted_at FROM users
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetUsersIncludingDeleted(ctx context.Context) ([]User, error) {
+	rows, err := q.db.Query(ctx, getUsersIncludingDeleted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.Age,
+			&i.CreatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, name, email, age, created_at, deleted_at FROM users
+WHERE $1::boolean OR deleted_at IS NULL
+ORDER BY id
+LIMIT $2 OFFSET $3
+`
+
+type ListUsersParams struct {
+	IncludeDeleted bool
+	Limit          int32
+	Offset         int32
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, listUsers, arg.IncludeDeleted, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.Age,
+			&i.CreatedAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -95,12 +327,65 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
+const purgeDeletedUsers = `-- name: PurgeDeletedUsers :many
+DELETE FROM users
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+RETURNING id, name, email, age, created_at, deleted_at
+`
+
+func (q *Queries) PurgeDeletedUsers(ctx context.Context, deletedAt pgtype.Timestamptz) ([]User, error) {
+	rows, err := q.db.Query(ctx, purgeDeletedUsers, deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.Age,
+			&i.CreatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreUser = `-- name: RestoreUser :one
+UPDATE users
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL RETURNING id, name, email, age, created_at, deleted_at
+`
+
+func (q *Queries) RestoreUser(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRow(ctx, restoreUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Age,
+		&i.CreatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET name = $2,
     email = $3,
     age = $4
-WHERE id = $1 RETURNING id, name, email, age, created_at
+WHERE id = $1 AND deleted_at IS NULL RETURNING id, name, email, age, created_at, deleted_at
 `
 
 type UpdateUserParams struct {
@@ -124,6 +409,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.Age,
 		&i.CreatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
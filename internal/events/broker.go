@@ -0,0 +1,182 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+	"github.com/jackc/pgx/v5"
+)
+
+// Broker fans user events received over PostgreSQL LISTEN out to in-process
+// subscribers. Because the outbox publishes with NOTIFY, a change made through
+// any server reaches the subscribers of every server.
+type Broker struct {
+	cfg *config.APIConfig
+
+	mu          sync.Mutex
+	recent      []outbox.Event
+	subscribers map[*Subscriber]struct{}
+	closed      bool
+}
+
+// Subscriber receives events on a buffered channel. A subscriber that falls
+// too far behind is dropped and its channel closed; the client is expected to
+// reconnect and resume from the last event it saw.
+type Subscriber struct {
+	events chan outbox.Event
+	closed bool
+}
+
+func (s *Subscriber) Events() <-chan outbox.Event {
+	return s.events
+}
+
+func NewBroker(cfg *config.APIConfig) *Broker {
+	return &Broker{
+		cfg:         cfg,
+		subscribers: map[*Subscriber]struct{}{},
+	}
+}
+
+// Subscribe registers a subscriber and returns the buffered events it missed
+// since lastEventID, or none when lastEventID is zero.
+func (b *Broker) Subscribe(lastEventID int64) (*Subscriber, []outbox.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscriber{events: make(chan outbox.Event, b.cfg.Events.ClientBuffer)}
+	if b.closed {
+		sub.closed = true
+		close(sub.events)
+		return sub, nil
+	}
+	b.subscribers[sub] = struct{}{}
+
+	if lastEventID == 0 {
+		return sub, nil
+	}
+
+	// Events from different users can arrive out of id order, so resume
+	// from the position of the last seen event when it is still buffered.
+	for i, event := range b.recent {
+		if event.ID == lastEventID {
+			return sub, append([]outbox.Event(nil), b.recent[i+1:]...)
+		}
+	}
+
+	var missed []outbox.Event
+	for _, event := range b.recent {
+		if event.ID > lastEventID {
+			missed = append(missed, event)
+		}
+	}
+	return sub, missed
+}
+
+func (b *Broker) Unsubscribe(sub *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.drop(sub)
+}
+
+// drop must be called with b.mu held.
+func (b *Broker) drop(sub *Subscriber) {
+	delete(b.subscribers, sub)
+	if !sub.closed {
+		sub.closed = true
+		close(sub.events)
+	}
+}
+
+// Publish buffers event for replay and hands it to every subscriber.
+func (b *Broker) Publish(event outbox.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.recent = append(b.recent, event)
+	if len(b.recent) > b.cfg.Events.ReplayBuffer {
+		b.recent = b.recent[len(b.recent)-b.cfg.Events.ReplayBuffer:]
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			b.drop(sub)
+		}
+	}
+}
+
+// Close disconnects every subscriber. Subscribers added afterwards are
+// closed straight away.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.drop(sub)
+	}
+}
+
+// Closed reports whether Close has been called, telling a subscriber whose
+// channel was closed apart from one dropped for being too slow.
+func (b *Broker) Closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.closed
+}
+
+// Run listens for notifications until ctx is done, reconnecting after errors.
+func (b *Broker) Run(ctx context.Context) {
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Error listening for user events: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (b *Broker) listen(ctx context.Context) error {
+	pooled, err := b.cfg.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Take the connection out of the pool for good so a LISTENing session
+	// is never handed to anyone else.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.cfg.Outbox.NotifyChannel}.Sanitize()); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event outbox.Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			log.Printf("Ignoring malformed user event: %v", err)
+			continue
+		}
+		b.Publish(event)
+	}
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+)
+
+func newBroker(replayBuffer, clientBuffer int) *Broker {
+	return NewBroker(&config.APIConfig{Events: config.EventsConfig{ReplayBuffer: replayBuffer, ClientBuffer: clientBuffer}})
+}
+
+func ids(events []outbox.Event) []int64 {
+	var ids []int64
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func TestSubscribeReplaysMissedEvents(t *testing.T) {
+	b := newBroker(3, 10)
+	// Events of different users can arrive out of id order
+	for _, id := range []int64{1, 2, 4, 3, 5} {
+		b.Publish(outbox.Event{ID: id})
+	}
+
+	tests := []struct {
+		lastEventID int64
+		want        []int64
+	}{
+		// A fresh client gets only new events
+		{0, nil},
+		// Resumed from its position in the buffer, so event 3 is not missed
+		{4, []int64{3, 5}},
+		{5, nil},
+		// Event 1 has left the buffer, so every later event still in it
+		{1, []int64{4, 3, 5}},
+	}
+	for _, tt := range tests {
+		sub, missed := b.Subscribe(tt.lastEventID)
+		if got := ids(missed); !slices.Equal(got, tt.want) {
+			t.Errorf("Last-Event-ID %d: replayed %v, want %v", tt.lastEventID, got, tt.want)
+		}
+		b.Unsubscribe(sub)
+	}
+}
+
+func TestSlowSubscribersAreDropped(t *testing.T) {
+	b := newBroker(10, 2)
+	slow, _ := b.Subscribe(0)
+	fast, _ := b.Subscribe(0)
+
+	var received []int64
+	for id := int64(1); id <= 3; id++ {
+		b.Publish(outbox.Event{ID: id})
+		received = append(received, (<-fast.Events()).ID)
+	}
+
+	// The slow subscriber got what fit in its buffer, and was then dropped
+	var got []int64
+	for event := range slow.Events() {
+		got = append(got, event.ID)
+	}
+	if !slices.Equal(got, []int64{1, 2}) {
+		t.Errorf("slow subscriber got %v before being dropped, want 1, 2", got)
+	}
+	if !slices.Equal(received, []int64{1, 2, 3}) {
+		t.Errorf("fast subscriber got %v, want 1, 2, 3", received)
+	}
+	if b.Closed() {
+		t.Error("dropping a subscriber closed the broker")
+	}
+
+	b.Publish(outbox.Event{ID: 4})
+	if event := <-fast.Events(); event.ID != 4 {
+		t.Errorf("fast subscriber got %d after the drop, want 4", event.ID)
+	}
+}
+
+func TestCloseDisconnectsSubscribers(t *testing.T) {
+	b := newBroker(10, 2)
+	before, _ := b.Subscribe(0)
+	b.Close()
+	after, _ := b.Subscribe(0)
+
+	for name, sub := range map[string]*Subscriber{"before": before, "after": after} {
+		if _, ok := <-sub.Events(); ok {
+			t.Errorf("subscriber added %s Close still open", name)
+		}
+	}
+	if !b.Closed() {
+		t.Error("Closed is false after Close")
+	}
+}
@@ -0,0 +1,118 @@
+// Package failfast answers the requests the database could not serve,
+// because it could not be reached or its circuit breaker is open, with a
+// 503 the client can retry, rather than the 500 of the handler.
+package failfast
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/metrics"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/route"
+)
+
+var unavailable = metrics.NewCounter("http_requests_db_unavailable_total",
+	"Requests answered with 503 for the database being unavailable.",
+	"server", "method", "route")
+
+// FailFast replaces the responses of one server.
+type FailFast struct {
+	server string
+}
+
+func New(server string) *FailFast {
+	return &FailFast{server: server}
+}
+
+// Middleware replaces an error response with a 503, with Retry-After and a
+// problem details body, if a statement the request ran failed for the
+// database being unavailable, since whatever the handler made of that
+// failure, such as a 404 for a user it could not look up, is wrong. A 503
+// is left as it is. Once the response has started it cannot be replaced.
+func (f *FailFast) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(database.TrackUnavailable(r.Context()))
+		next.ServeHTTP(&failFastWriter{ResponseWriter: w, r: r, unavailable: func() {
+			unavailable.Inc(f.server, r.Method, route.Pattern(r))
+		}}, r)
+	})
+}
+
+// failFastWriter replaces an error response with a 503 if the database was
+// unavailable.
+type failFastWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	unavailable func()
+
+	wroteHeader bool
+	// replaced is set once the response has been replaced, after which the
+	// writes of the handler are dropped.
+	replaced bool
+}
+
+func (fw *failFastWriter) WriteHeader(status int) {
+	if fw.wroteHeader || status < http.StatusOK {
+		fw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	fw.wroteHeader = true
+
+	if retryAfter, ok := database.Unavailable(fw.r.Context()); ok && status >= http.StatusBadRequest && status != http.StatusServiceUnavailable {
+		fw.replace(retryAfter)
+		return
+	}
+	fw.ResponseWriter.WriteHeader(status)
+}
+
+func (fw *failFastWriter) replace(retryAfter time.Duration) {
+	fw.replaced = true
+	fw.unavailable()
+
+	h := fw.ResponseWriter.Header()
+	for _, name := range []string{"Content-Length", "Content-Encoding", "Content-Disposition", "ETag", "Last-Modified", "Location"} {
+		h.Del(name)
+	}
+	h.Set("Content-Type", "application/problem+json")
+	h.Set("Retry-After", strconv.Itoa(max(1, int((retryAfter+time.Second-1)/time.Second))))
+	fw.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(fw.ResponseWriter).Encode(models.Problem{
+		Type:   "about:blank",
+		Title:  "Database unavailable",
+		Status: http.StatusServiceUnavailable,
+		Detail: "The database could not be reached. Retry the request later.",
+	})
+}
+
+func (fw *failFastWriter) Write(b []byte) (int, error) {
+	if !fw.wroteHeader {
+		fw.WriteHeader(http.StatusOK)
+	}
+	if fw.replaced {
+		return len(b), nil
+	}
+	return fw.ResponseWriter.Write(b)
+}
+
+func (fw *failFastWriter) Flush() {
+	if !fw.wroteHeader {
+		fw.WriteHeader(http.StatusOK)
+	}
+	if !fw.replaced {
+		http.NewResponseController(fw.ResponseWriter).Flush()
+	}
+}
+
+func (fw *failFastWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(fw.ResponseWriter).Hijack()
+}
+
+func (fw *failFastWriter) Unwrap() http.ResponseWriter {
+	return fw.ResponseWriter
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/service"
+)
+
+const (
+	bulkModeAtomic     = "atomic"
+	bulkModeBestEffort = "best_effort"
+)
+
+// The functions below implement the bulk endpoints for every framework. They
+// return the status and body of a successful response, or the status of an
+// error and its message.
+
+func bulkCreateUsers(cfg *config.APIConfig, r *http.Request, framework string) (int, models.BulkResponse, error) {
+	mode, items, status, err := bulkRequest(cfg, r)
+	if err != nil {
+		return status, models.BulkResponse{}, err
+	}
+
+	results := make([]models.BulkItemResult, len(items))
+	var params []database.CreateUserParams
+	var indexes []int
+	for i, item := range items {
+		results[i].Index = i
+		if item.Name == nil || *item.Name == "" || item.Email == nil || *item.Email == "" || item.Age == nil {
+			results[i].Status, results[i].Error = http.StatusBadRequest, "Bad Request: Empty values"
+			continue
+		}
+		if *item.Age < 0 {
+			results[i].Status, results[i].Error = http.StatusBadRequest, "Bad Request: Invalid age"
+			continue
+		}
+		params = append(params, database.CreateUserParams{
+			Name:  *item.Name,
+			Email: *item.Email,
+			Age:   *item.Age,
+		})
+		indexes = append(indexes, i)
+	}
+
+	if mode == bulkModeAtomic && len(params) < len(items) {
+		return bulkResponse(mode, results, nil, indexes, http.StatusCreated)
+	}
+
+	outcomes, err := service.BulkCreateUsers(r.Context(), cfg, audit.FromRequest(cfg, r, framework), params, mode == bulkModeAtomic)
+	if err != nil {
+		return http.StatusInternalServerError, models.BulkResponse{}, errors.New("Error creating users")
+	}
+
+	return bulkResponse(mode, results, outcomes, indexes, http.StatusCreated)
+}
+
+func bulkUpdateUsers(cfg *config.APIConfig, r *http.Request, framework string) (int, models.BulkResponse, error) {
+	mode, items, status, err := bulkRequest(cfg, r)
+	if err != nil {
+		return status, models.BulkResponse{}, err
+	}
+
+	results := make([]models.BulkItemResult, len(items))
+	seen := make(map[int32]bool, len(items))
+	var patches []service.UserPatch
+	var indexes []int
+	for i, item := range items {
+		results[i].Index = i
+		switch {
+		case item.ID == nil:
+			results[i].Status, results[i].Error = http.StatusBadRequest, "Bad Request: Missing id"
+		case seen[*item.ID]:
+			results[i].Status, results[i].Error = http.StatusBadRequest, "Bad Request: Duplicate id"
+		case item.Name == nil && item.Email == nil && item.Age == nil:
+			results[i].Status, results[i].Error = http.StatusBadRequest, "Bad Request: Nothing to update"
+		case item.Name != nil && *item.Name == "", item.Email != nil && *item.Email == "":
+			results[i].Status, results[i].Error = http.StatusBadRequest, "Bad Request: Empty values"
+		case item.Age != nil && *item.Age < 0:
+			results[i].Status, results[i].Error = http.StatusBadRequest, "Bad Request: Invalid age"
+		default:
+			seen[*item.ID] = true
+			patches = append(patches, service.UserPatch{
+				ID:    *item.ID,
+				Name:  item.Name,
+				Email: item.Email,
+				Age:   item.Age,
+			})
+			indexes = append(indexes, i)
+		}
+	}
+
+	if mode == bulkModeAtomic && len(patches) < len(items) {
+		return bulkResponse(mode, results, nil, indexes, http.StatusOK)
+	}
+
+	outcomes, err := service.BulkUpdateUsers(r.Context(), cfg, audit.FromRequest(cfg, r, framework), patches, mode == bulkModeAtomic)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			return http.StatusConflict, models.BulkResponse{}, errors.New("Email already in use")
+		}
+		return http.StatusInternalServerError, models.BulkResponse{}, errors.New("Error updating users")
+	}
+
+	return bulkResponse(mode, results, outcomes, indexes, http.StatusOK)
+}
+
+func bulkDeleteUsers(cfg *config.APIConfig, r *http.Request, framework string) (int, models.BulkResponse, error) {
+	mode, items, status, err := bulkRequest(cfg, r)
+	if err != nil {
+		return status, models.BulkResponse{}, err
+	}
+
+	results := make([]models.BulkItemResult, len(items))
+	seen := make(map[int32]bool, len(items))
+	var ids []int32
+	var indexes []int
+	for i, item := range items {
+		results[i].Index = i
+		switch {
+		case item.ID == nil:
+			results[i].Status, results[i].Error = http.StatusBadRequest, "Bad Request: Missing id"
+		case seen[*item.ID]:
+			results[i].Status, results[i].Error = http.StatusBadRequest, "Bad Request: Duplicate id"
+		default:
+			seen[*item.ID] = true
+			ids = append(ids, *item.ID)
+			indexes = append(indexes, i)
+		}
+	}
+
+	if mode == bulkModeAtomic && len(ids) < len(items) {
+		return bulkResponse(mode, results, nil, indexes, http.StatusOK)
+	}
+
+	outcomes, err := service.BulkDeleteUsers(r.Context(), cfg, audit.FromRequest(cfg, r, framework), ids, mode == bulkModeAtomic)
+	if err != nil {
+		return http.StatusInternalServerError, models.BulkResponse{}, errors.New("Error deleting users")
+	}
+
+	return bulkResponse(mode, results, outcomes, indexes, http.StatusOK)
+}
+
+// bulkRequest reads the mode query parameter and the items of a bulk request,
+// sent either as a JSON array or, with an application/x-ndjson content type,
+// as one JSON object per line.
+func bulkRequest(cfg *config.APIConfig, r *http.Request) (string, []models.BulkItem, int, error) {
+	mode := r.URL.Query().Get("mode")
+	switch mode {
+	case "":
+		mode = bulkModeAtomic
+	case bulkModeAtomic, bulkModeBestEffort:
+	default:
+		return "", nil, http.StatusBadRequest, errors.New("Bad Request: mode must be atomic or best_effort")
+	}
+
+	body := http.MaxBytesReader(nil, r.Body, int64(cfg.Bulk.MaxBodyBytes))
+	dec := json.NewDecoder(body)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	ndjson := mediaType == "application/x-ndjson" || mediaType == "application/jsonl"
+	if !ndjson {
+		if token, err := dec.Token(); err != nil || token != json.Delim('[') {
+			return "", nil, bulkReadStatus(err), errors.New("Bad Request: Expected a JSON array")
+		}
+	}
+
+	var items []models.BulkItem
+	for ndjson || dec.More() {
+		var item models.BulkItem
+		err := dec.Decode(&item)
+		if ndjson && err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, bulkReadStatus(err), fmt.Errorf("Bad Request: Invalid item %d", len(items))
+		}
+
+		items = append(items, item)
+		if len(items) > cfg.Bulk.MaxItems {
+			return "", nil, http.StatusRequestEntityTooLarge, fmt.Errorf("Too many items, the maximum is %d", cfg.Bulk.MaxItems)
+		}
+	}
+
+	if len(items) == 0 {
+		return "", nil, http.StatusBadRequest, errors.New("Bad Request: No items")
+	}
+
+	return mode, items, http.StatusOK, nil
+}
+
+func bulkReadStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// bulkResponse fills in the results of the items that reached the service,
+// whose positions are given by indexes, and picks the response status. A
+// nil outcomes means the items were never sent because others were invalid.
+func bulkResponse(mode string, results []models.BulkItemResult, outcomes []service.BulkResult, indexes []int, okStatus int) (int, models.BulkResponse, error) {
+	for j, i := range indexes {
+		if outcomes == nil {
+			results[i].Status, results[i].Error = http.StatusFailedDependency, service.ErrNotApplied.Error()
+			continue
+		}
+
+		outcome := outcomes[j]
+		switch {
+		case outcome.Err == nil:
+			user := models.FromDatabaseUser(outcome.User)
+			results[i].Status, results[i].User = okStatus, &user
+		case errors.Is(outcome.Err, service.ErrUserNotFound):
+			results[i].Status, results[i].Error = http.StatusNotFound, outcome.Err.Error()
+		case errors.Is(outcome.Err, service.ErrEmailTaken):
+			results[i].Status, results[i].Error = http.StatusConflict, outcome.Err.Error()
+		case errors.Is(outcome.Err, service.ErrDuplicateID):
+			results[i].Status, results[i].Error = http.StatusBadRequest, "Bad Request: "+outcome.Err.Error()
+		default:
+			results[i].Status, results[i].Error = http.StatusFailedDependency, outcome.Err.Error()
+		}
+	}
+
+	response := models.BulkResponse{Mode: mode, Results: results}
+	failureStatus := 0
+	for _, result := range results {
+		if result.Error == "" {
+			response.Succeeded++
+			continue
+		}
+		response.Failed++
+		if failureStatus == 0 && result.Status != http.StatusFailedDependency {
+			failureStatus = result.Status
+		}
+	}
+
+	switch {
+	case response.Failed == 0:
+		return okStatus, response, nil
+	case mode == bulkModeAtomic:
+		// Nothing was applied, so answer like the first item that failed.
+		if failureStatus == 0 {
+			failureStatus = http.StatusConflict
+		}
+		return failureStatus, response, nil
+	default:
+		return http.StatusMultiStatus, response, nil
+	}
+}
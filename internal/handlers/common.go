@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// includeDeleted reads the include_deleted query parameter. Soft-deleted
+// users are only visible to admins, so anyone else asking for them gets a 403.
+func includeDeleted(cfg *config.APIConfig, r *http.Request) (bool, int, error) {
+	value := r.URL.Query().Get("include_deleted")
+	if value == "" {
+		return false, http.StatusOK, nil
+	}
+
+	include, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, http.StatusBadRequest, errors.New("Bad Request: Invalid include_deleted value")
+	}
+
+	if include && !cfg.IsAdmin(r) {
+		return false, http.StatusForbidden, errors.New("Forbidden: Admin access required")
+	}
+
+	return include, http.StatusOK, nil
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// auditFilter reads the audit log filters and pagination from the query
+// string. The user_id filter is ignored by the per-user history endpoints,
+// which set it from the path instead.
+func auditFilter(r *http.Request) (database.ListAuditEventsParams, error) {
+	query := r.URL.Query()
+	params := database.ListAuditEventsParams{}
+
+	if value := query.Get("user_id"); value != "" {
+		userId, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return params, errors.New("Bad Request: Invalid user_id")
+		}
+		params.UserID = pgtype.Int4{Int32: int32(userId), Valid: true}
+	}
+
+	for name, field := range map[string]*pgtype.Text{
+		"actor":     &params.Actor,
+		"action":    &params.Action,
+		"framework": &params.Framework,
+	} {
+		if value := query.Get(name); value != "" {
+			*field = pgtype.Text{String: value, Valid: true}
+		}
+	}
+
+	for name, field := range map[string]*pgtype.Timestamptz{
+		"since": &params.Since,
+		"until": &params.Until,
+	} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return params, fmt.Errorf("Bad Request: Invalid %s, expected RFC 3339 time", name)
+			}
+			*field = pgtype.Timestamptz{Time: t, Valid: true}
+		}
+	}
+
+	limit, offset, err := pagination(r)
+	if err != nil {
+		return params, err
+	}
+	params.Limit, params.Offset = limit, offset
+
+	return params, nil
+}
+
+// pagination reads the limit and offset query parameters.
+func pagination(r *http.Request) (int32, int32, error) {
+	query := r.URL.Query()
+	limit, offset := int32(defaultPageSize), int32(0)
+
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || n == 0 || n > maxPageSize {
+			return 0, 0, fmt.Errorf("Bad Request: limit must be between 1 and %d", maxPageSize)
+		}
+		limit = int32(n)
+	}
+
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 31)
+		if err != nil {
+			return 0, 0, errors.New("Bad Request: Invalid offset")
+		}
+		offset = int32(n)
+	}
+
+	return limit, offset, nil
+}
+
+// listUsers returns the users for GET /users. The whole list is returned
+// unless limit or offset is given, in which case one page ordered by ID is.
+func listUsers(cfg *config.APIConfig, r *http.Request) (int, []models.User, error) {
+	include, status, err := includeDeleted(cfg, r)
+	if err != nil {
+		return status, nil, err
+	}
+
+	var users []database.User
+	query := r.URL.Query()
+	switch {
+	case query.Has("limit") || query.Has("offset"):
+		var limit, offset int32
+		limit, offset, err = pagination(r)
+		if err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		users, err = cfg.DB.ListUsers(r.Context(), database.ListUsersParams{
+			IncludeDeleted: include,
+			Limit:          limit,
+			Offset:         offset,
+		})
+	case include:
+		users, err = cfg.DB.GetUsersIncludingDeleted(r.Context())
+	default:
+		users, err = cfg.DB.GetUsers(r.Context())
+	}
+	if err != nil {
+		return http.StatusInternalServerError, nil, errors.New("Internal Server Error")
+	}
+
+	return http.StatusOK, models.FromDatabaseUsers(users), nil
+}
+
+// parseJSONForm reads a JSON object body into the request's form, so the
+// handlers read JSON bodies the same way as form bodies. Arrays become
+// repeated values and null fields are left out. Other bodies are left for
+// ParseForm.
+func parseJSONForm(r *http.Request) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" || r.PostForm != nil {
+		return nil
+	}
+
+	var fields map[string]any
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return errors.New("Bad Request: Invalid JSON body")
+	}
+
+	form := url.Values{}
+	for name, value := range fields {
+		switch v := value.(type) {
+		case nil:
+		case map[string]any:
+			return fmt.Errorf("Bad Request: %s must not be an object", name)
+		case []any:
+			// An empty array reads as an empty value
+			if len(v) == 0 {
+				form.Set(name, "")
+			}
+			for _, item := range v {
+				form.Add(name, fmt.Sprint(item))
+			}
+		default:
+			form.Set(name, fmt.Sprint(v))
+		}
+	}
+
+	r.PostForm = form
+	r.Form = url.Values{}
+	for name, values := range form {
+		r.Form[name] = append(r.Form[name], values...)
+	}
+	for name, values := range r.URL.Query() {
+		r.Form[name] = append(r.Form[name], values...)
+	}
+	return nil
+}
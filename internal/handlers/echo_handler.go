@@ -1,17 +1,31 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/cors"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/service"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/websocket"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 )
 
 // CREATE USER
 func EchoCreateUser(cfg *config.APIConfig) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		// Read a JSON body into the form
+		if err := parseJSONForm(c.Request()); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
 		name := c.FormValue("name")
 		email := c.FormValue("email")
 		ageStr := c.FormValue("age")
@@ -26,7 +40,7 @@ func EchoCreateUser(cfg *config.APIConfig) echo.HandlerFunc {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid age"})
 		}
 
-		user, err := cfg.DB.CreateUser(c.Request().Context(), database.CreateUserParams{
+		user, err := service.CreateUser(c.Request().Context(), cfg, audit.FromRequest(cfg, c.Request(), "echo"), database.CreateUserParams{
 			Name:  name,
 			Email: email,
 			Age:   int32(age),
@@ -35,19 +49,27 @@ func EchoCreateUser(cfg *config.APIConfig) echo.HandlerFunc {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error creating user"})
 		}
 
-		return c.JSON(http.StatusCreated, user)
+		return c.JSON(http.StatusCreated, models.FromDatabaseUser(user))
 	}
 }
 
 // GET ALL USERS
 func EchoGetUsers(cfg *config.APIConfig) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		users, err := cfg.DB.GetUsers(c.Request().Context())
+		status, users, err := listUsers(cfg, c.Request())
 		if err != nil {
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+			return c.JSON(status, map[string]string{"error": err.Error()})
 		}
 
-		return c.JSON(http.StatusOK, users)
+		return c.JSON(status, users)
+	}
+}
+
+// EXPORT USERS
+func EchoExportUsers(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		exportUsers(cfg, c.Response(), c.Request())
+		return nil
 	}
 }
 
@@ -61,12 +83,22 @@ func EchoGetUser(cfg *config.APIConfig) echo.HandlerFunc {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid user ID"})
 		}
 
-		user, err := cfg.DB.GetUser(c.Request().Context(), int32(userId))
+		include, status, err := includeDeleted(cfg, c.Request())
+		if err != nil {
+			return c.JSON(status, map[string]string{"error": err.Error()})
+		}
+
+		var user database.User
+		if include {
+			user, err = cfg.DB.GetUserIncludingDeleted(c.Request().Context(), int32(userId))
+		} else {
+			user, err = cfg.DB.GetUser(c.Request().Context(), int32(userId))
+		}
 		if err != nil {
 			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
 		}
 
-		return c.JSON(http.StatusOK, user)
+		return c.JSON(http.StatusOK, models.FromDatabaseUser(user))
 	}
 }
 
@@ -85,6 +117,11 @@ func EchoUpdateUser(cfg *config.APIConfig) echo.HandlerFunc {
 			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
 		}
 
+		// Read a JSON body into the form
+		if err := parseJSONForm(c.Request()); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
 		name := c.FormValue("name")
 		email := c.FormValue("email")
 		ageStr := c.FormValue("age")
@@ -104,7 +141,7 @@ func EchoUpdateUser(cfg *config.APIConfig) echo.HandlerFunc {
 			existingUser.Age = int32(age)
 		}
 
-		updatedUser, err := cfg.DB.UpdateUser(c.Request().Context(), database.UpdateUserParams{
+		updatedUser, err := service.UpdateUser(c.Request().Context(), cfg, audit.FromRequest(cfg, c.Request(), "echo"), database.UpdateUserParams{
 			ID:    existingUser.ID,
 			Name:  existingUser.Name,
 			Email: existingUser.Email,
@@ -114,7 +151,7 @@ func EchoUpdateUser(cfg *config.APIConfig) echo.HandlerFunc {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
 		}
 
-		return c.JSON(http.StatusOK, updatedUser)
+		return c.JSON(http.StatusOK, models.FromDatabaseUser(updatedUser))
 	}
 }
 
@@ -133,10 +170,221 @@ func EchoDeleteUser(cfg *config.APIConfig) echo.HandlerFunc {
 			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
 		}
 
-		if err := cfg.DB.DeleteUser(c.Request().Context(), int32(userId)); err != nil {
+		if err := service.DeleteUser(c.Request().Context(), cfg, audit.FromRequest(cfg, c.Request(), "echo"), int32(userId)); err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
 		}
 
 		return c.JSON(http.StatusNoContent, map[string]string{"message": "Successfully deleted the user"})
 	}
 }
+
+// BULK CREATE USERS
+func EchoBulkCreateUsers(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status, response, err := bulkCreateUsers(cfg, c.Request(), "echo")
+		if err != nil {
+			return c.JSON(status, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(status, response)
+	}
+}
+
+// BULK UPDATE USERS
+func EchoBulkUpdateUsers(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status, response, err := bulkUpdateUsers(cfg, c.Request(), "echo")
+		if err != nil {
+			return c.JSON(status, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(status, response)
+	}
+}
+
+// BULK DELETE USERS
+func EchoBulkDeleteUsers(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status, response, err := bulkDeleteUsers(cfg, c.Request(), "echo")
+		if err != nil {
+			return c.JSON(status, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(status, response)
+	}
+}
+
+// IMPORT USERS
+func EchoImportUsers(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status, response, err := createUserImport(cfg, c.Request(), "echo")
+		if err != nil {
+			return c.JSON(status, map[string]string{"error": err.Error()})
+		}
+
+		c.Response().Header().Set("Location", importLocation(response))
+		return c.JSON(status, response)
+	}
+}
+
+// RESTORE USER
+func EchoRestoreUser(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		userIdStr := c.Param("id")
+
+		userId, err := strconv.ParseUint(userIdStr, 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid user ID"})
+		}
+
+		user, err := service.RestoreUser(c.Request().Context(), cfg, audit.FromRequest(cfg, c.Request(), "echo"), int32(userId))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return c.JSON(http.StatusNotFound, map[string]string{"error": "Deleted user not found"})
+			}
+			if database.IsUniqueViolation(err) {
+				return c.JSON(http.StatusConflict, map[string]string{"error": "Conflict: Email is in use by another user"})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+
+		return c.JSON(http.StatusOK, models.FromDatabaseUser(user))
+	}
+}
+
+// USER HISTORY
+func EchoGetUserHistory(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		userIdStr := c.Param("id")
+
+		userId, err := strconv.ParseUint(userIdStr, 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid user ID"})
+		}
+
+		params, err := auditFilter(c.Request())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		params.UserID = pgtype.Int4{Int32: int32(userId), Valid: true}
+
+		events, err := cfg.DB.ListAuditEvents(c.Request().Context(), params)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+
+		return c.JSON(http.StatusOK, models.FromDatabaseAuditEvents(events, params.Limit, params.Offset))
+	}
+}
+
+// AUDIT LOG
+func EchoGetAuditEvents(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		params, err := auditFilter(c.Request())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		events, err := cfg.DB.ListAuditEvents(c.Request().Context(), params)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+
+		return c.JSON(http.StatusOK, models.FromDatabaseAuditEvents(events, params.Limit, params.Offset))
+	}
+}
+
+// USER EVENTS
+func EchoUserEvents(cfg *config.APIConfig, broker *events.Broker) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		streamUserEvents(cfg, broker, c.Response(), c.Request())
+		return nil
+	}
+}
+
+// USER WEBSOCKET
+func EchoUserSocket(cfg *config.APIConfig, broker *events.Broker) echo.HandlerFunc {
+	policy := cors.New(cfg.CORS)
+	return func(c echo.Context) error {
+		key, status, err := checkUserSocket(policy, c.Response(), c.Request())
+		if err != nil {
+			return c.JSON(status, map[string]string{"error": err.Error()})
+		}
+
+		netConn, rw, err := c.Response().Hijack()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+
+		conn, err := websocket.Accept(netConn, rw, key)
+		if err != nil {
+			return nil
+		}
+		runUserSocket(cfg, broker, conn)
+		return nil
+	}
+}
+
+// GET IMPORT
+func EchoGetImport(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status, response, err := getUserImport(cfg, c.Request(), c.Param("id"))
+		if err != nil {
+			return c.JSON(status, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(status, response)
+	}
+}
+
+// IMPORT ERRORS
+func EchoGetImportErrors(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userImportErrors(cfg, c.Response(), c.Request(), c.Param("id"))
+		return nil
+	}
+}
+
+// OPENAPI DOCUMENT
+func EchoOpenAPI() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		serveOpenAPI(c.Response())
+		return nil
+	}
+}
+
+// API DOCS
+func EchoDocs() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		serveDocs(c.Response())
+		return nil
+	}
+}
+
+// HEALTH CHECK
+func EchoHealth() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		serveHealth(c.Response())
+		return nil
+	}
+}
+
+// READINESS CHECK
+func EchoReadiness(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		serveReadiness(cfg, c.Response(), c.Request())
+		return nil
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// GET ALL JOBS
+func EchoGetJobs(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		params, err := jobFilter(c.Request())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		jobs, err := cfg.DB.ListJobs(c.Request().Context(), params)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+
+		return c.JSON(http.StatusOK, models.FromDatabaseJobs(jobs, params.Limit, params.Offset))
+	}
+}
+
+// GET ONE JOB
+func EchoGetJob(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		jobId, err := strconv.ParseUint(c.Param("id"), 10, 63)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid job ID"})
+		}
+
+		job, err := cfg.DB.GetJob(c.Request().Context(), int64(jobId))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Job not found"})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+
+		return c.JSON(http.StatusOK, models.FromDatabaseJob(job))
+	}
+}
+
+// RETRY JOB
+func EchoRetryJob(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		jobId, err := strconv.ParseUint(c.Param("id"), 10, 63)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid job ID"})
+		}
+
+		job, err := cfg.DB.RetryJob(c.Request().Context(), int64(jobId))
+		if errors.Is(err, pgx.ErrNoRows) {
+			status, err := jobUnchanged(c.Request().Context(), cfg, int64(jobId), "Conflict: Only failed or cancelled jobs can be retried")
+			return c.JSON(status, map[string]string{"error": err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+
+		return c.JSON(http.StatusOK, models.FromDatabaseJob(job))
+	}
+}
+
+// CANCEL JOB
+func EchoCancelJob(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		jobId, err := strconv.ParseUint(c.Param("id"), 10, 63)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid job ID"})
+		}
+
+		job, err := cfg.DB.CancelJob(c.Request().Context(), int64(jobId))
+		if errors.Is(err, pgx.ErrNoRows) {
+			status, err := jobUnchanged(c.Request().Context(), cfg, int64(jobId), "Conflict: Only queued or running jobs can be cancelled")
+			return c.JSON(status, map[string]string{"error": err.Error()})
+		}
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+
+		return c.JSON(http.StatusOK, models.FromDatabaseJob(job))
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// GET ALL WEBHOOKS
+func EchoGetWebhooks(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		webhooks, err := cfg.DB.ListWebhooks(c.Request().Context())
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+
+		return c.JSON(http.StatusOK, models.FromDatabaseWebhooks(webhooks))
+	}
+}
+
+// CREATE WEBHOOK
+func EchoCreateWebhook(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		form, err := parseWebhookForm(c.Request())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		params, err := form.createParams()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		webhook, err := cfg.DB.CreateWebhook(c.Request().Context(), params)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error creating webhook"})
+		}
+
+		// Secrets are only returned when set, so the receiver can store it now
+		response := models.FromDatabaseWebhook(webhook)
+		response.Secret = webhook.Secret
+
+		return c.JSON(http.StatusCreated, response)
+	}
+}
+
+// GET ONE WEBHOOK
+func EchoGetWebhook(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		webhookId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid webhook ID"})
+		}
+
+		webhook, err := cfg.DB.GetWebhook(c.Request().Context(), int32(webhookId))
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Webhook not found"})
+		}
+
+		return c.JSON(http.StatusOK, models.FromDatabaseWebhook(webhook))
+	}
+}
+
+// UPDATE WEBHOOK
+func EchoUpdateWebhook(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		webhookId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid webhook ID"})
+		}
+
+		existingWebhook, err := cfg.DB.GetWebhook(c.Request().Context(), int32(webhookId))
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Webhook not found"})
+		}
+
+		form, err := parseWebhookForm(c.Request())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		webhook, err := cfg.DB.UpdateWebhook(c.Request().Context(), form.updateParams(existingWebhook))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+
+		response := models.FromDatabaseWebhook(webhook)
+		if form.secret != nil {
+			response.Secret = webhook.Secret
+		}
+
+		return c.JSON(http.StatusOK, response)
+	}
+}
+
+// DELETE WEBHOOK
+func EchoDeleteWebhook(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		webhookId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid webhook ID"})
+		}
+
+		deleted, err := cfg.DB.DeleteWebhook(c.Request().Context(), int32(webhookId))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+		if deleted == 0 {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Webhook not found"})
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// GET WEBHOOK DELIVERIES
+func EchoGetWebhookDeliveries(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		webhookId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid webhook ID"})
+		}
+
+		if _, err := cfg.DB.GetWebhook(c.Request().Context(), int32(webhookId)); err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Webhook not found"})
+		}
+
+		limit, offset, err := pagination(c.Request())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		deliveries, err := cfg.DB.ListWebhookDeliveries(c.Request().Context(), database.ListWebhookDeliveriesParams{
+			WebhookID: int32(webhookId),
+			Limit:     limit,
+			Offset:    offset,
+		})
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+
+		return c.JSON(http.StatusOK, models.FromDatabaseWebhookDeliveries(deliveries, limit, offset))
+	}
+}
+
+// REDELIVER WEBHOOK DELIVERY
+func EchoRedeliverWebhook(cfg *config.APIConfig) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !cfg.IsAdmin(c.Request()) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden: Admin access required"})
+		}
+
+		webhookId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid webhook ID"})
+		}
+
+		deliveryId, err := strconv.ParseUint(c.Param("delivery_id"), 10, 63)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad Request: Invalid delivery ID"})
+		}
+
+		// Queue the delivery for another attempt straight away
+		delivery, err := cfg.DB.RedeliverWebhookDelivery(c.Request().Context(), database.RedeliverWebhookDeliveryParams{
+			ID:        int64(deliveryId),
+			WebhookID: int32(webhookId),
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return c.JSON(http.StatusNotFound, map[string]string{"error": "Delivery not found"})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+		}
+
+		return c.JSON(http.StatusAccepted, models.FromDatabaseWebhookDelivery(delivery))
+	}
+}
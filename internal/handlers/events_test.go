@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+)
+
+// eventStream serves the user events of broker and returns a function that
+// opens the stream, with the header Last-Event-ID when lastEventID is set.
+func eventStream(t *testing.T, cfg *config.APIConfig, broker *events.Broker) func(query, lastEventID string) *bufio.Reader {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streamUserEvents(cfg, broker, w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return func(query, lastEventID string) *bufio.Reader {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Content-Type %q, want text/event-stream", ct)
+		}
+		return bufio.NewReader(res.Body)
+	}
+}
+
+// nextFrame reads the stream up to the next blank line, returning what
+// came before it.
+func nextFrame(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var frame strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		if line == "\n" {
+			return frame.String()
+		}
+		frame.WriteString(line)
+	}
+}
+
+func eventsConfig(heartbeat time.Duration) *config.APIConfig {
+	return &config.APIConfig{Events: config.EventsConfig{ReplayBuffer: 10, ClientBuffer: 10, Heartbeat: heartbeat}}
+}
+
+func TestEventStreamResumesFromLastEventID(t *testing.T) {
+	// By the header a browser resends, or by the query parameter
+	for _, resume := range [][2]string{{"", "1"}, {"last_event_id=1", ""}} {
+		cfg := eventsConfig(time.Hour)
+		broker := events.NewBroker(cfg)
+		for id := int64(1); id <= 3; id++ {
+			broker.Publish(outbox.Event{ID: id, Type: outbox.UserCreated})
+		}
+		stream := eventStream(t, cfg, broker)(resume[0], resume[1])
+
+		if frame := nextFrame(t, stream); frame != "retry: 3000\n" {
+			t.Fatalf("first frame %q, want the retry interval", frame)
+		}
+		for _, id := range []string{"2", "3"} {
+			frame := nextFrame(t, stream)
+			if !strings.HasPrefix(frame, "id: "+id+"\nevent: user.created\ndata: {") {
+				t.Fatalf("%v: replayed %q, want event %s", resume, frame, id)
+			}
+		}
+
+		// Then live events follow
+		broker.Publish(outbox.Event{ID: 4, Type: outbox.UserUpdated})
+		if frame := nextFrame(t, stream); !strings.HasPrefix(frame, "id: 4\nevent: user.updated\n") {
+			t.Fatalf("%v: live event %q, want event 4", resume, frame)
+		}
+	}
+}
+
+func TestEventStreamSendsHeartbeats(t *testing.T) {
+	cfg := eventsConfig(10 * time.Millisecond)
+	stream := eventStream(t, cfg, events.NewBroker(cfg))("", "")
+
+	nextFrame(t, stream)
+	for range 2 {
+		if frame := nextFrame(t, stream); frame != ": heartbeat\n" {
+			t.Fatalf("got %q, want a heartbeat", frame)
+		}
+	}
+}
+
+func TestEventStreamEndsWhenTheSubscriberIsClosed(t *testing.T) {
+	cfg := eventsConfig(time.Hour)
+	broker := events.NewBroker(cfg)
+	stream := eventStream(t, cfg, broker)("", "")
+	nextFrame(t, stream)
+
+	// As when the broker drops a client that fell behind, or shuts down
+	broker.Close()
+	if _, err := io.ReadAll(stream); err != nil {
+		t.Fatalf("stream failed rather than ending: %v", err)
+	}
+}
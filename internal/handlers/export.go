@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/utils"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+)
+
+// exportColumns are the columns an export may select, in their default order.
+var exportColumns = []string{"id", "name", "email", "age", "created_at", "deleted_at"}
+
+// exportUsers writes every user matching the list filters as CSV or NDJSON,
+// straight from the database cursor. It is shared by the EXPORT USERS handler
+// of every framework.
+func exportUsers(cfg *config.APIConfig, w http.ResponseWriter, r *http.Request) {
+	include, status, err := includeDeleted(cfg, r)
+	if err != nil {
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	format, err := exportFormat(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	columns, err := exportColumnList(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// An export is every matching user; a page of them is GET /users.
+	if query := r.URL.Query(); query.Has("limit") || query.Has("offset") {
+		utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Exports include every user and take no limit or offset")
+		return
+	}
+
+	excel, err := exportForExcel(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ew := &exportWriter{w: w, format: format}
+	if format == exportFormatCSV {
+		err = exportCSV(cfg, ew, r, include, columns, excel)
+	} else {
+		err = exportNDJSON(cfg, ew, r, include, columns)
+	}
+	if err == nil {
+		ew.start()
+		return
+	}
+
+	log.Printf("Error exporting users: %v", err)
+	if !ew.started {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Error exporting users")
+	}
+	// Otherwise the header is already out and the export simply ends early.
+}
+
+// exportWriter sends the response header along with the first bytes of the
+// export, so a query that fails before producing any row is still reported
+// as an error.
+type exportWriter struct {
+	w       http.ResponseWriter
+	format  string
+	started bool
+}
+
+func (ew *exportWriter) start() {
+	if ew.started {
+		return
+	}
+	ew.started = true
+
+	if ew.format == exportFormatCSV {
+		ew.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		ew.w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	filename := "users-" + time.Now().UTC().Format("20060102T150405Z") + "." + ew.format
+	ew.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ew.w.WriteHeader(http.StatusOK)
+}
+
+func (ew *exportWriter) Write(p []byte) (int, error) {
+	ew.start()
+	return ew.w.Write(p)
+}
+
+// exportCSV writes the users as CSV. With excel set, text is escaped so that
+// spreadsheets show it rather than run it; otherwise it is exported as is,
+// so the file can be imported again unchanged.
+func exportCSV(cfg *config.APIConfig, w io.Writer, r *http.Request, include bool, columns []string, excel bool) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	err := cfg.DB.StreamUsers(r.Context(), include, func(user database.User) error {
+		for i, column := range columns {
+			record[i] = csvValue(user, column, excel)
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func exportNDJSON(cfg *config.APIConfig, w io.Writer, r *http.Request, include bool, columns []string) error {
+	bw := bufio.NewWriter(w)
+	err := cfg.DB.StreamUsers(r.Context(), include, func(user database.User) error {
+		// Write the object by hand to keep the selected column order.
+		bw.WriteByte('{')
+		for i, column := range columns {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			value, err := json.Marshal(jsonValue(user, column))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(bw, "%q:%s", column, value)
+		}
+		_, err := bw.WriteString("}\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// exportFormat picks the format from the format query parameter, then the
+// Accept header, and falls back to CSV.
+func exportFormat(r *http.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case exportFormatCSV, exportFormatNDJSON:
+		return format, nil
+	case "":
+	default:
+		return "", fmt.Errorf("Bad Request: format must be %s or %s", exportFormatCSV, exportFormatNDJSON)
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(accept), ";")
+		switch mediaType {
+		case "text/csv":
+			return exportFormatCSV, nil
+		case "application/x-ndjson", "application/jsonl":
+			return exportFormatNDJSON, nil
+		}
+	}
+
+	return exportFormatCSV, nil
+}
+
+// exportForExcel reads the excel query parameter.
+func exportForExcel(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("excel")
+	if value == "" {
+		return false, nil
+	}
+
+	excel, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("Bad Request: Invalid excel value")
+	}
+	return excel, nil
+}
+
+// exportColumnList reads the comma separated columns query parameter.
+func exportColumnList(r *http.Request) ([]string, error) {
+	value := r.URL.Query().Get("columns")
+	if value == "" {
+		return exportColumns, nil
+	}
+
+	var columns []string
+	for _, column := range strings.Split(value, ",") {
+		column = strings.TrimSpace(column)
+		found := false
+		for _, known := range exportColumns {
+			found = found || column == known
+		}
+		if !found {
+			return nil, fmt.Errorf("Bad Request: Unknown column %q, expected %s", column, strings.Join(exportColumns, ", "))
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+func csvValue(user database.User, column string, excel bool) string {
+	switch column {
+	case "id":
+		return strconv.Itoa(int(user.ID))
+	case "name":
+		if excel {
+			return csvText(user.Name)
+		}
+		return user.Name
+	case "email":
+		if excel {
+			return csvText(user.Email)
+		}
+		return user.Email
+	case "age":
+		return strconv.Itoa(int(user.Age))
+	case "created_at":
+		return csvTime(user.CreatedAt.Time, user.CreatedAt.Valid)
+	case "deleted_at":
+		return csvTime(user.DeletedAt.Time, user.DeletedAt.Valid)
+	}
+	return ""
+}
+
+// csvText keeps spreadsheets from running user supplied text as a formula.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func csvTime(t time.Time, valid bool) string {
+	if !valid {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func jsonValue(user database.User, column string) interface{} {
+	switch column {
+	case "id":
+		return user.ID
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	case "age":
+		return user.Age
+	case "created_at":
+		return user.CreatedAt
+	case "deleted_at":
+		return user.DeletedAt
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+)
+
+func TestCSVValueEscapesFormulasOnlyForExcel(t *testing.T) {
+	user := database.User{Name: "=SUM(A1:A2)", Email: "-a@example.com"}
+
+	tests := []struct {
+		column string
+		excel  bool
+		want   string
+	}{
+		{"name", false, "=SUM(A1:A2)"},
+		{"email", false, "-a@example.com"},
+		{"name", true, "'=SUM(A1:A2)"},
+		{"email", true, "'-a@example.com"},
+	}
+	for _, tt := range tests {
+		if got := csvValue(user, tt.column, tt.excel); got != tt.want {
+			t.Errorf("csvValue(%s, excel=%t) = %q, want %q", tt.column, tt.excel, got, tt.want)
+		}
+	}
+}
@@ -1,18 +1,32 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/cors"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/service"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/websocket"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // CREATE USER
 func GinCreateUser(cfg *config.APIConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Read a JSON body into the form
+		if err := parseJSONForm(c.Request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Parse form values
 		name := c.PostForm("name")
 		email := c.PostForm("email")
@@ -32,7 +46,7 @@ func GinCreateUser(cfg *config.APIConfig) gin.HandlerFunc {
 		}
 
 		// Create user
-		user, err := cfg.DB.CreateUser(c.Request.Context(), database.CreateUserParams{
+		user, err := service.CreateUser(c.Request.Context(), cfg, audit.FromRequest(cfg, c.Request, "gin"), database.CreateUserParams{
 			Name:  name,
 			Email: email,
 			Age:   int32(age),
@@ -49,13 +63,20 @@ func GinCreateUser(cfg *config.APIConfig) gin.HandlerFunc {
 // GET ALL USERS
 func GinGetUsers(cfg *config.APIConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		users, err := cfg.DB.GetUsers(c.Request.Context())
+		status, users, err := listUsers(cfg, c.Request)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			c.JSON(status, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, models.FromDatabaseUsers(users))
+		c.JSON(status, users)
+	}
+}
+
+// EXPORT USERS
+func GinExportUsers(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		exportUsers(cfg, c.Writer, c.Request)
 	}
 }
 
@@ -71,8 +92,19 @@ func GinGetUser(cfg *config.APIConfig) gin.HandlerFunc {
 			return
 		}
 
+		include, status, err := includeDeleted(cfg, c.Request)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Get user by ID
-		user, err := cfg.DB.GetUser(c.Request.Context(), int32(userId))
+		var user database.User
+		if include {
+			user, err = cfg.DB.GetUserIncludingDeleted(c.Request.Context(), int32(userId))
+		} else {
+			user, err = cfg.DB.GetUser(c.Request.Context(), int32(userId))
+		}
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 			return
@@ -101,6 +133,12 @@ func GinUpdateUser(cfg *config.APIConfig) gin.HandlerFunc {
 			return
 		}
 
+		// Read a JSON body into the form
+		if err := parseJSONForm(c.Request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Parse form values
 		name := c.PostForm("name")
 		email := c.PostForm("email")
@@ -123,7 +161,7 @@ func GinUpdateUser(cfg *config.APIConfig) gin.HandlerFunc {
 		}
 
 		// Save updated user
-		updatedUser, err := cfg.DB.UpdateUser(c.Request.Context(), database.UpdateUserParams{
+		updatedUser, err := service.UpdateUser(c.Request.Context(), cfg, audit.FromRequest(cfg, c.Request, "gin"), database.UpdateUserParams{
 			ID:    existingUser.ID,
 			Name:  existingUser.Name,
 			Email: existingUser.Email,
@@ -158,7 +196,7 @@ func GinDeleteUser(cfg *config.APIConfig) gin.HandlerFunc {
 		}
 
 		// Delete the user
-		if err := cfg.DB.DeleteUser(c.Request.Context(), int32(userId)); err != nil {
+		if err := service.DeleteUser(c.Request.Context(), cfg, audit.FromRequest(cfg, c.Request, "gin"), int32(userId)); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 			return
 		}
@@ -166,3 +204,231 @@ func GinDeleteUser(cfg *config.APIConfig) gin.HandlerFunc {
 		c.JSON(http.StatusNoContent, gin.H{"message": "Successfully deleted the user"})
 	}
 }
+
+// BULK CREATE USERS
+func GinBulkCreateUsers(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, response, err := bulkCreateUsers(cfg, c.Request, "gin")
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(status, response)
+	}
+}
+
+// BULK UPDATE USERS
+func GinBulkUpdateUsers(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, response, err := bulkUpdateUsers(cfg, c.Request, "gin")
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(status, response)
+	}
+}
+
+// BULK DELETE USERS
+func GinBulkDeleteUsers(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, response, err := bulkDeleteUsers(cfg, c.Request, "gin")
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(status, response)
+	}
+}
+
+// IMPORT USERS
+func GinImportUsers(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, response, err := createUserImport(cfg, c.Request, "gin")
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Location", importLocation(response))
+		c.JSON(status, response)
+	}
+}
+
+// RESTORE USER
+func GinRestoreUser(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		// Extract user ID from the path
+		userIdStr := c.Param("id")
+
+		userId, err := strconv.ParseUint(userIdStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request: Invalid user ID format"})
+			return
+		}
+
+		// Clear the deletion marker
+		user, err := service.RestoreUser(c.Request.Context(), cfg, audit.FromRequest(cfg, c.Request, "gin"), int32(userId))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Deleted user not found"})
+				return
+			}
+			if database.IsUniqueViolation(err) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Conflict: Email is in use by another user"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.FromDatabaseUser(user))
+	}
+}
+
+// USER HISTORY
+func GinGetUserHistory(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		// Extract user ID from the path
+		userIdStr := c.Param("id")
+
+		userId, err := strconv.ParseUint(userIdStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request: Invalid user ID format"})
+			return
+		}
+
+		// Parse filters and pagination
+		params, err := auditFilter(c.Request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		params.UserID = pgtype.Int4{Int32: int32(userId), Valid: true}
+
+		events, err := cfg.DB.ListAuditEvents(c.Request.Context(), params)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.FromDatabaseAuditEvents(events, params.Limit, params.Offset))
+	}
+}
+
+// AUDIT LOG
+func GinGetAuditEvents(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		// Parse filters and pagination
+		params, err := auditFilter(c.Request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		events, err := cfg.DB.ListAuditEvents(c.Request.Context(), params)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.FromDatabaseAuditEvents(events, params.Limit, params.Offset))
+	}
+}
+
+// USER EVENTS
+func GinUserEvents(cfg *config.APIConfig, broker *events.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		streamUserEvents(cfg, broker, c.Writer, c.Request)
+	}
+}
+
+// USER WEBSOCKET
+func GinUserSocket(cfg *config.APIConfig, broker *events.Broker) gin.HandlerFunc {
+	policy := cors.New(cfg.CORS)
+	return func(c *gin.Context) {
+		key, status, err := checkUserSocket(policy, c.Writer, c.Request)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		netConn, rw, err := c.Writer.Hijack()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		conn, err := websocket.Accept(netConn, rw, key)
+		if err != nil {
+			return
+		}
+		runUserSocket(cfg, broker, conn)
+	}
+}
+
+// GET IMPORT
+func GinGetImport(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, response, err := getUserImport(cfg, c.Request, c.Param("id"))
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(status, response)
+	}
+}
+
+// IMPORT ERRORS
+func GinGetImportErrors(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userImportErrors(cfg, c.Writer, c.Request, c.Param("id"))
+	}
+}
+
+// OPENAPI DOCUMENT
+func GinOpenAPI() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serveOpenAPI(c.Writer)
+	}
+}
+
+// API DOCS
+func GinDocs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serveDocs(c.Writer)
+	}
+}
+
+// HEALTH CHECK
+func GinHealth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serveHealth(c.Writer)
+	}
+}
+
+// READINESS CHECK
+func GinReadiness(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serveReadiness(cfg, c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// GET ALL JOBS
+func GinGetJobs(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		params, err := jobFilter(c.Request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		jobs, err := cfg.DB.ListJobs(c.Request.Context(), params)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.FromDatabaseJobs(jobs, params.Limit, params.Offset))
+	}
+}
+
+// GET ONE JOB
+func GinGetJob(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		jobId, err := strconv.ParseUint(c.Param("id"), 10, 63)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request: Invalid job ID"})
+			return
+		}
+
+		job, err := cfg.DB.GetJob(c.Request.Context(), int64(jobId))
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.FromDatabaseJob(job))
+	}
+}
+
+// RETRY JOB
+func GinRetryJob(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		jobId, err := strconv.ParseUint(c.Param("id"), 10, 63)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request: Invalid job ID"})
+			return
+		}
+
+		job, err := cfg.DB.RetryJob(c.Request.Context(), int64(jobId))
+		if errors.Is(err, pgx.ErrNoRows) {
+			status, err := jobUnchanged(c.Request.Context(), cfg, int64(jobId), "Conflict: Only failed or cancelled jobs can be retried")
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.FromDatabaseJob(job))
+	}
+}
+
+// CANCEL JOB
+func GinCancelJob(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		jobId, err := strconv.ParseUint(c.Param("id"), 10, 63)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request: Invalid job ID"})
+			return
+		}
+
+		job, err := cfg.DB.CancelJob(c.Request.Context(), int64(jobId))
+		if errors.Is(err, pgx.ErrNoRows) {
+			status, err := jobUnchanged(c.Request.Context(), cfg, int64(jobId), "Conflict: Only queued or running jobs can be cancelled")
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.FromDatabaseJob(job))
+	}
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// GET ALL WEBHOOKS
+func GinGetWebhooks(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		webhooks, err := cfg.DB.ListWebhooks(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.FromDatabaseWebhooks(webhooks))
+	}
+}
+
+// CREATE WEBHOOK
+func GinCreateWebhook(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		form, err := parseWebhookForm(c.Request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		params, err := form.createParams()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		webhook, err := cfg.DB.CreateWebhook(c.Request.Context(), params)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating webhook"})
+			return
+		}
+
+		// Secrets are only returned when set, so the receiver can store it now
+		response := models.FromDatabaseWebhook(webhook)
+		response.Secret = webhook.Secret
+
+		c.JSON(http.StatusCreated, response)
+	}
+}
+
+// GET ONE WEBHOOK
+func GinGetWebhook(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		webhookId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request: Invalid webhook ID"})
+			return
+		}
+
+		webhook, err := cfg.DB.GetWebhook(c.Request.Context(), int32(webhookId))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.FromDatabaseWebhook(webhook))
+	}
+}
+
+// UPDATE WEBHOOK
+func GinUpdateWebhook(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		webhookId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request: Invalid webhook ID"})
+			return
+		}
+
+		existingWebhook, err := cfg.DB.GetWebhook(c.Request.Context(), int32(webhookId))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+
+		form, err := parseWebhookForm(c.Request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		webhook, err := cfg.DB.UpdateWebhook(c.Request.Context(), form.updateParams(existingWebhook))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		response := models.FromDatabaseWebhook(webhook)
+		if form.secret != nil {
+			response.Secret = webhook.Secret
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// DELETE WEBHOOK
+func GinDeleteWebhook(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		webhookId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request: Invalid webhook ID"})
+			return
+		}
+
+		deleted, err := cfg.DB.DeleteWebhook(c.Request.Context(), int32(webhookId))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+		if deleted == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// GET WEBHOOK DELIVERIES
+func GinGetWebhookDeliveries(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		webhookId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request: Invalid webhook ID"})
+			return
+		}
+
+		if _, err := cfg.DB.GetWebhook(c.Request.Context(), int32(webhookId)); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+			return
+		}
+
+		limit, offset, err := pagination(c.Request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		deliveries, err := cfg.DB.ListWebhookDeliveries(c.Request.Context(), database.ListWebhookDeliveriesParams{
+			WebhookID: int32(webhookId),
+			Limit:     limit,
+			Offset:    offset,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.FromDatabaseWebhookDeliveries(deliveries, limit, offset))
+	}
+}
+
+// REDELIVER WEBHOOK DELIVERY
+func GinRedeliverWebhook(cfg *config.APIConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.IsAdmin(c.Request) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: Admin access required"})
+			return
+		}
+
+		webhookId, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request: Invalid webhook ID"})
+			return
+		}
+
+		deliveryId, err := strconv.ParseUint(c.Param("delivery_id"), 10, 63)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request: Invalid delivery ID"})
+			return
+		}
+
+		// Queue the delivery for another attempt straight away
+		delivery, err := cfg.DB.RedeliverWebhookDelivery(c.Request.Context(), database.RedeliverWebhookDeliveryParams{
+			ID:        int64(deliveryId),
+			WebhookID: int32(webhookId),
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, models.FromDatabaseWebhookDelivery(delivery))
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+)
+
+// readinessTimeout bounds the statement the readiness check runs, so that a
+// database that does not answer fails the check rather than the probe.
+const readinessTimeout = 2 * time.Second
+
+// serveHealth answers the liveness check, which only shows that the server
+// is up and answering requests.
+func serveHealth(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.Health{Status: "ok"})
+}
+
+// serveReadiness answers the readiness check, which shows whether the server
+// can serve requests that use the database, along with the state of the
+// replicas. It fails without touching the database while its circuit
+// breaker is open.
+func serveReadiness(cfg *config.APIConfig, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	state, err := cfg.CheckDatabase(ctx)
+	readiness := models.Readiness{Status: "ready", Database: models.DatabaseStatus{Breaker: state.String()}}
+	for _, replica := range cfg.Replicas() {
+		lag, usable := replica.Status()
+		readiness.Replicas = append(readiness.Replicas, models.ReplicaStatus{
+			Name:       replica.Name(),
+			Usable:     usable,
+			LagSeconds: lag.Seconds(),
+			Breaker:    replica.Breaker().State().String(),
+		})
+	}
+	status := http.StatusOK
+	if err != nil {
+		readiness.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(readiness)
+}
@@ -1,18 +1,32 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/cors"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/service"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/julienschmidt/httprouter"
 )
 
 // CREATE USER
 func HttpCreateUser(cfg *config.APIConfig) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		// Read a JSON body into the form
+		if err := parseJSONForm(r); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		err := r.ParseForm()
 		if err != nil {
 			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Invalid form data")
@@ -34,7 +48,7 @@ func HttpCreateUser(cfg *config.APIConfig) httprouter.Handle {
 			return
 		}
 
-		user, err := cfg.DB.CreateUser(r.Context(), database.CreateUserParams{
+		user, err := service.CreateUser(r.Context(), cfg, audit.FromRequest(cfg, r, "httprouter"), database.CreateUserParams{
 			Name:  name,
 			Email: email,
 			Age:   int32(age),
@@ -44,20 +58,27 @@ func HttpCreateUser(cfg *config.APIConfig) httprouter.Handle {
 			return
 		}
 
-		utils.RespondWithJSON(w, http.StatusCreated, user)
+		utils.RespondWithJSON(w, http.StatusCreated, models.FromDatabaseUser(user))
 	}
 }
 
 // GET ALL USERS
 func HttpGetUsers(cfg *config.APIConfig) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		users, err := cfg.DB.GetUsers(r.Context())
+		status, users, err := listUsers(cfg, r)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			utils.RespondWithError(w, status, err.Error())
 			return
 		}
 
-		utils.RespondWithJSON(w, http.StatusOK, users)
+		utils.RespondWithJSON(w, status, users)
+	}
+}
+
+// EXPORT USERS
+func HttpExportUsers(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		exportUsers(cfg, w, r)
 	}
 }
 
@@ -71,13 +92,24 @@ func HttpGetUser(cfg *config.APIConfig) httprouter.Handle {
 			return
 		}
 
-		user, err := cfg.DB.GetUser(r.Context(), int32(userId))
+		include, status, err := includeDeleted(cfg, r)
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		var user database.User
+		if include {
+			user, err = cfg.DB.GetUserIncludingDeleted(r.Context(), int32(userId))
+		} else {
+			user, err = cfg.DB.GetUser(r.Context(), int32(userId))
+		}
 		if err != nil {
 			utils.RespondWithError(w, http.StatusNotFound, "User not found")
 			return
 		}
 
-		utils.RespondWithJSON(w, http.StatusOK, user)
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseUser(user))
 	}
 }
 
@@ -97,6 +129,12 @@ func HttpUpdateUser(cfg *config.APIConfig) httprouter.Handle {
 			return
 		}
 
+		// Read a JSON body into the form
+		if err := parseJSONForm(r); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		name := r.FormValue("name")
 		email := r.FormValue("email")
 		ageStr := r.FormValue("age")
@@ -116,7 +154,7 @@ func HttpUpdateUser(cfg *config.APIConfig) httprouter.Handle {
 			existingUser.Age = int32(age)
 		}
 
-		updatedUser, err := cfg.DB.UpdateUser(r.Context(), database.UpdateUserParams{
+		updatedUser, err := service.UpdateUser(r.Context(), cfg, audit.FromRequest(cfg, r, "httprouter"), database.UpdateUserParams{
 			ID:    existingUser.ID,
 			Name:  existingUser.Name,
 			Email: existingUser.Email,
@@ -127,7 +165,7 @@ func HttpUpdateUser(cfg *config.APIConfig) httprouter.Handle {
 			return
 		}
 
-		utils.RespondWithJSON(w, http.StatusOK, updatedUser)
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseUser(updatedUser))
 	}
 }
 
@@ -147,7 +185,7 @@ func HttpDeleteUser(cfg *config.APIConfig) httprouter.Handle {
 			return
 		}
 
-		err = cfg.DB.DeleteUser(r.Context(), int32(userId))
+		err = service.DeleteUser(r.Context(), cfg, audit.FromRequest(cfg, r, "httprouter"), int32(userId))
 		if err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 			return
@@ -156,3 +194,208 @@ func HttpDeleteUser(cfg *config.APIConfig) httprouter.Handle {
 		utils.RespondWithJSON(w, http.StatusNoContent, "Successfully deleted the user")
 	}
 }
+
+// BULK CREATE USERS
+func HttpBulkCreateUsers(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		status, response, err := bulkCreateUsers(cfg, r, "httprouter")
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// BULK UPDATE USERS
+func HttpBulkUpdateUsers(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		status, response, err := bulkUpdateUsers(cfg, r, "httprouter")
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// BULK DELETE USERS
+func HttpBulkDeleteUsers(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		status, response, err := bulkDeleteUsers(cfg, r, "httprouter")
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// IMPORT USERS
+func HttpImportUsers(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		status, response, err := createUserImport(cfg, r, "httprouter")
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		w.Header().Set("Location", importLocation(response))
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// RESTORE USER
+func HttpRestoreUser(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		userIdStr := ps.ByName("id")
+		userId, err := strconv.ParseUint(userIdStr, 10, 32)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Invalid user ID")
+			return
+		}
+
+		user, err := service.RestoreUser(r.Context(), cfg, audit.FromRequest(cfg, r, "httprouter"), int32(userId))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				utils.RespondWithError(w, http.StatusNotFound, "Deleted user not found")
+				return
+			}
+			if database.IsUniqueViolation(err) {
+				utils.RespondWithError(w, http.StatusConflict, "Conflict: Email is in use by another user")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseUser(user))
+	}
+}
+
+// USER HISTORY
+func HttpGetUserHistory(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		userIdStr := ps.ByName("id")
+		userId, err := strconv.ParseUint(userIdStr, 10, 32)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Invalid user ID")
+			return
+		}
+
+		params, err := auditFilter(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		params.UserID = pgtype.Int4{Int32: int32(userId), Valid: true}
+
+		events, err := cfg.DB.ListAuditEvents(r.Context(), params)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseAuditEvents(events, params.Limit, params.Offset))
+	}
+}
+
+// AUDIT LOG
+func HttpGetAuditEvents(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		params, err := auditFilter(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		events, err := cfg.DB.ListAuditEvents(r.Context(), params)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseAuditEvents(events, params.Limit, params.Offset))
+	}
+}
+
+// USER EVENTS
+func HttpUserEvents(cfg *config.APIConfig, broker *events.Broker) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		streamUserEvents(cfg, broker, w, r)
+	}
+}
+
+// USER WEBSOCKET
+func HttpUserSocket(cfg *config.APIConfig, broker *events.Broker) httprouter.Handle {
+	policy := cors.New(cfg.CORS)
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		serveUserSocket(cfg, policy, broker, w, r)
+	}
+}
+
+// GET IMPORT
+func HttpGetImport(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		status, response, err := getUserImport(cfg, r, ps.ByName("id"))
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// IMPORT ERRORS
+func HttpGetImportErrors(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		userImportErrors(cfg, w, r, ps.ByName("id"))
+	}
+}
+
+// OPENAPI DOCUMENT
+func HttpOpenAPI() httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		serveOpenAPI(w)
+	}
+}
+
+// API DOCS
+func HttpDocs() httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		serveDocs(w)
+	}
+}
+
+// HEALTH CHECK
+func HttpHealth() httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		serveHealth(w)
+	}
+}
+
+// READINESS CHECK
+func HttpReadiness(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		serveReadiness(cfg, w, r)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GET ALL JOBS
+func HttpGetJobs(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		params, err := jobFilter(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		jobs, err := cfg.DB.ListJobs(r.Context(), params)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseJobs(jobs, params.Limit, params.Offset))
+	}
+}
+
+// GET ONE JOB
+func HttpGetJob(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		jobId, err := strconv.ParseUint(ps.ByName("id"), 10, 63)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Invalid job ID")
+			return
+		}
+
+		job, err := cfg.DB.GetJob(r.Context(), int64(jobId))
+		if errors.Is(err, pgx.ErrNoRows) {
+			utils.RespondWithError(w, http.StatusNotFound, "Job not found")
+			return
+		}
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseJob(job))
+	}
+}
+
+// RETRY JOB
+func HttpRetryJob(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		jobId, err := strconv.ParseUint(ps.ByName("id"), 10, 63)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Invalid job ID")
+			return
+		}
+
+		job, err := cfg.DB.RetryJob(r.Context(), int64(jobId))
+		if errors.Is(err, pgx.ErrNoRows) {
+			status, err := jobUnchanged(r.Context(), cfg, int64(jobId), "Conflict: Only failed or cancelled jobs can be retried")
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseJob(job))
+	}
+}
+
+// CANCEL JOB
+func HttpCancelJob(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		jobId, err := strconv.ParseUint(ps.ByName("id"), 10, 63)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Invalid job ID")
+			return
+		}
+
+		job, err := cfg.DB.CancelJob(r.Context(), int64(jobId))
+		if errors.Is(err, pgx.ErrNoRows) {
+			status, err := jobUnchanged(r.Context(), cfg, int64(jobId), "Conflict: Only queued or running jobs can be cancelled")
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseJob(job))
+	}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GET ALL WEBHOOKS
+func HttpGetWebhooks(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		webhooks, err := cfg.DB.ListWebhooks(r.Context())
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseWebhooks(webhooks))
+	}
+}
+
+// CREATE WEBHOOK
+func HttpCreateWebhook(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		form, err := parseWebhookForm(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		params, err := form.createParams()
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		webhook, err := cfg.DB.CreateWebhook(r.Context(), params)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Error creating webhook")
+			return
+		}
+
+		// Secrets are only returned when set, so the receiver can store it now
+		response := models.FromDatabaseWebhook(webhook)
+		response.Secret = webhook.Secret
+
+		utils.RespondWithJSON(w, http.StatusCreated, response)
+	}
+}
+
+// GET ONE WEBHOOK
+func HttpGetWebhook(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		webhookId, err := strconv.ParseUint(ps.ByName("id"), 10, 32)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Invalid webhook ID")
+			return
+		}
+
+		webhook, err := cfg.DB.GetWebhook(r.Context(), int32(webhookId))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseWebhook(webhook))
+	}
+}
+
+// UPDATE WEBHOOK
+func HttpUpdateWebhook(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		webhookId, err := strconv.ParseUint(ps.ByName("id"), 10, 32)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Invalid webhook ID")
+			return
+		}
+
+		existingWebhook, err := cfg.DB.GetWebhook(r.Context(), int32(webhookId))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+
+		form, err := parseWebhookForm(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		webhook, err := cfg.DB.UpdateWebhook(r.Context(), form.updateParams(existingWebhook))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		response := models.FromDatabaseWebhook(webhook)
+		if form.secret != nil {
+			response.Secret = webhook.Secret
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, response)
+	}
+}
+
+// DELETE WEBHOOK
+func HttpDeleteWebhook(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		webhookId, err := strconv.ParseUint(ps.ByName("id"), 10, 32)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Invalid webhook ID")
+			return
+		}
+
+		deleted, err := cfg.DB.DeleteWebhook(r.Context(), int32(webhookId))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if deleted == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GET WEBHOOK DELIVERIES
+func HttpGetWebhookDeliveries(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		webhookId, err := strconv.ParseUint(ps.ByName("id"), 10, 32)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Invalid webhook ID")
+			return
+		}
+
+		if _, err := cfg.DB.GetWebhook(r.Context(), int32(webhookId)); err != nil {
+			utils.RespondWithError(w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+
+		limit, offset, err := pagination(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		deliveries, err := cfg.DB.ListWebhookDeliveries(r.Context(), database.ListWebhookDeliveriesParams{
+			WebhookID: int32(webhookId),
+			Limit:     limit,
+			Offset:    offset,
+		})
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseWebhookDeliveries(deliveries, limit, offset))
+	}
+}
+
+// REDELIVER WEBHOOK DELIVERY
+func HttpRedeliverWebhook(cfg *config.APIConfig) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		webhookId, err := strconv.ParseUint(ps.ByName("id"), 10, 32)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Invalid webhook ID")
+			return
+		}
+
+		deliveryId, err := strconv.ParseUint(ps.ByName("delivery_id"), 10, 63)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request: Invalid delivery ID")
+			return
+		}
+
+		// Queue the delivery for another attempt straight away
+		delivery, err := cfg.DB.RedeliverWebhookDelivery(r.Context(), database.RedeliverWebhookDeliveryParams{
+			ID:        int64(deliveryId),
+			WebhookID: int32(webhookId),
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				utils.RespondWithError(w, http.StatusNotFound, "Delivery not found")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusAccepted, models.FromDatabaseWebhookDelivery(delivery))
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/imports"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+// The functions below implement the import endpoints for every framework.
+// Like the bulk ones they return the status and body of a successful
+// response, or the status of an error and its message.
+
+// createUserImport stores an uploaded file, sent either as the file field of
+// a multipart form or as the raw request body, and queues it for processing.
+func createUserImport(cfg *config.APIConfig, r *http.Request, framework string) (int, models.UserImport, error) {
+	if !cfg.IsAdmin(r) {
+		return http.StatusForbidden, models.UserImport{}, errors.New("Forbidden: Admin access required")
+	}
+
+	query := r.URL.Query()
+	opts := imports.Options{
+		Format:      query.Get("format"),
+		OnDuplicate: query.Get("on_duplicate"),
+	}
+
+	if value := query.Get("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			return http.StatusBadRequest, models.UserImport{}, errors.New("Bad Request: Invalid dry_run value")
+		}
+		opts.DryRun = dryRun
+	}
+
+	columnMap, err := imports.ParseColumnMap(query.Get("mapping"))
+	if err != nil {
+		return http.StatusBadRequest, models.UserImport{}, fmt.Errorf("Bad Request: %w", err)
+	}
+	opts.ColumnMap = columnMap
+
+	data, format, status, err := importUpload(cfg, r)
+	if err != nil {
+		return status, models.UserImport{}, err
+	}
+	if opts.Format == "" {
+		opts.Format = format
+	}
+
+	if err := opts.Validate(); err != nil {
+		return http.StatusBadRequest, models.UserImport{}, fmt.Errorf("Bad Request: %w", err)
+	}
+
+	imp, err := imports.Create(r.Context(), cfg, audit.FromRequest(cfg, r, framework), opts, data, false)
+	if err != nil {
+		return http.StatusInternalServerError, models.UserImport{}, errors.New("Error creating import")
+	}
+
+	return http.StatusAccepted, models.FromDatabaseUserImport(imp), nil
+}
+
+// importUpload reads the uploaded file, along with the format its media type
+// or file name suggests.
+func importUpload(cfg *config.APIConfig, r *http.Request) ([]byte, string, int, error) {
+	body := http.MaxBytesReader(nil, r.Body, int64(cfg.Imports.MaxBytes))
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var file io.Reader = body
+	var filename string
+	if mediaType == "multipart/form-data" {
+		r.Body = body
+		reader, err := r.MultipartReader()
+		if err != nil {
+			return nil, "", http.StatusBadRequest, errors.New("Bad Request: Invalid multipart form")
+		}
+
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil, "", http.StatusBadRequest, errors.New("Bad Request: Missing file")
+			}
+			if err != nil {
+				return nil, "", bulkReadStatus(err), errors.New("Bad Request: Invalid multipart form")
+			}
+			if part.FormName() == "file" {
+				file, filename = part, part.FileName()
+				mediaType, _, _ = mime.ParseMediaType(part.Header.Get("Content-Type"))
+				break
+			}
+		}
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		status := bulkReadStatus(err)
+		if status == http.StatusRequestEntityTooLarge {
+			return nil, "", status, fmt.Errorf("File too large, the maximum is %d bytes", cfg.Imports.MaxBytes)
+		}
+		return nil, "", status, errors.New("Bad Request: Error reading file")
+	}
+	if len(data) == 0 {
+		return nil, "", http.StatusBadRequest, errors.New("Bad Request: Empty file")
+	}
+
+	return data, imports.FormatFromContentType(mediaType, filename), http.StatusOK, nil
+}
+
+func getUserImport(cfg *config.APIConfig, r *http.Request, idStr string) (int, models.UserImport, error) {
+	if !cfg.IsAdmin(r) {
+		return http.StatusForbidden, models.UserImport{}, errors.New("Forbidden: Admin access required")
+	}
+
+	id, err := strconv.ParseUint(idStr, 10, 31)
+	if err != nil {
+		return http.StatusBadRequest, models.UserImport{}, errors.New("Bad Request: Invalid import ID")
+	}
+
+	imp, err := cfg.DB.GetUserImport(r.Context(), int32(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return http.StatusNotFound, models.UserImport{}, errors.New("Import not found")
+	}
+	if err != nil {
+		return http.StatusInternalServerError, models.UserImport{}, errors.New("Error retrieving import")
+	}
+
+	return http.StatusOK, models.FromDatabaseUserImport(imp), nil
+}
+
+// userImportErrors writes the rows an import rejected as a CSV report. It is
+// shared by the IMPORT ERRORS handler of every framework.
+func userImportErrors(cfg *config.APIConfig, w http.ResponseWriter, r *http.Request, idStr string) {
+	status, imp, err := getUserImport(cfg, r, idStr)
+	if err != nil {
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	rowErrors, err := cfg.DB.ListUserImportErrors(r.Context(), imp.ID)
+	if err != nil {
+		log.Printf("Error listing errors of import %d: %v", imp.ID, err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Error retrieving import errors")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("import-%d-errors.csv", imp.ID)))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"row", "email", "error"})
+	for _, rowError := range rowErrors {
+		cw.Write(importErrorRecord(rowError))
+	}
+	cw.Flush()
+}
+
+func importErrorRecord(rowError database.UserImportError) []string {
+	return []string{
+		strconv.Itoa(int(rowError.RowNumber)),
+		csvText(rowError.Email),
+		csvText(rowError.Error),
+	}
+}
+
+func importLocation(imp models.UserImport) string {
+	return fmt.Sprintf("/imports/%d", imp.ID)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/jobs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// jobFilter reads the job list filters and pagination from the query string.
+func jobFilter(r *http.Request) (database.ListJobsParams, error) {
+	query := r.URL.Query()
+	params := database.ListJobsParams{}
+
+	if value := query.Get("status"); value != "" {
+		if !slices.Contains(jobs.Statuses, value) {
+			return params, fmt.Errorf("Bad Request: status must be one of %s", strings.Join(jobs.Statuses, ", "))
+		}
+		params.Status = pgtype.Text{String: value, Valid: true}
+	}
+
+	if value := query.Get("kind"); value != "" {
+		params.Kind = pgtype.Text{String: value, Valid: true}
+	}
+
+	limit, offset, err := pagination(r)
+	if err != nil {
+		return params, err
+	}
+	params.Limit, params.Offset = limit, offset
+
+	return params, nil
+}
+
+// jobUnchanged explains why a retry or cancel matched no job: either it does
+// not exist, or it is in a status the action does not apply to.
+func jobUnchanged(ctx context.Context, cfg *config.APIConfig, jobId int64, conflict string) (int, error) {
+	_, err := cfg.DB.GetJob(ctx, jobId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return http.StatusNotFound, errors.New("Job not found")
+	}
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Internal Server Error")
+	}
+	return http.StatusConflict, errors.New(conflict)
+}
@@ -1,19 +1,32 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/cors"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/service"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/utils"
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // CREATE USER
 func MuxCreateUser(cfg *config.APIConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// Read a JSON body into the form
+		if err := parseJSONForm(r); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		// Parse form data
 		err := r.ParseForm()
 		if err != nil {
@@ -40,7 +53,7 @@ func MuxCreateUser(cfg *config.APIConfig) http.HandlerFunc {
 		}
 
 		// Create user
-		user, err := cfg.DB.CreateUser(r.Context(), database.CreateUserParams{
+		user, err := service.CreateUser(r.Context(), cfg, audit.FromRequest(cfg, r, "mux"), database.CreateUserParams{
 			Name:  name,
 			Email: email,
 			Age:   int32(age),
@@ -58,13 +71,20 @@ func MuxCreateUser(cfg *config.APIConfig) http.HandlerFunc {
 func MuxGetUsers(cfg *config.APIConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		users, err := cfg.DB.GetUsers(r.Context())
+		status, users, err := listUsers(cfg, r)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			utils.RespondWithError(w, status, err.Error())
 			return
 		}
 
-		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseUsers(users))
+		utils.RespondWithJSON(w, status, users)
+	}
+}
+
+// EXPORT USERS
+func MuxExportUsers(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		exportUsers(cfg, w, r)
 	}
 }
 
@@ -85,10 +105,21 @@ func MuxGetUser(cfg *config.APIConfig) http.HandlerFunc {
 			return
 		}
 
+		include, status, err := includeDeleted(cfg, r)
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
 		// Query the DB for the user with the specified ID
-		user, err := cfg.DB.GetUser(r.Context(), int32(userId))
+		var user database.User
+		if include {
+			user, err = cfg.DB.GetUserIncludingDeleted(r.Context(), int32(userId))
+		} else {
+			user, err = cfg.DB.GetUser(r.Context(), int32(userId))
+		}
 		if err != nil {
-			http.Error(w, "User not found", http.StatusNotFound)
+			utils.RespondWithError(w, http.StatusNotFound, "User not found")
 			return
 		}
 
@@ -120,6 +151,12 @@ func MuxUpdateUser(cfg *config.APIConfig) http.HandlerFunc {
 			return
 		}
 
+		// Read a JSON body into the form
+		if err := parseJSONForm(r); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		// Get form values
 		name := r.FormValue("name")
 		email := r.FormValue("email")
@@ -142,7 +179,7 @@ func MuxUpdateUser(cfg *config.APIConfig) http.HandlerFunc {
 		}
 
 		// Save the updated user to the database
-		updatedUser, err := cfg.DB.UpdateUser(r.Context(), database.UpdateUserParams{
+		updatedUser, err := service.UpdateUser(r.Context(), cfg, audit.FromRequest(cfg, r, "mux"), database.UpdateUserParams{
 			ID:    existingUser.ID,
 			Name:  existingUser.Name,
 			Email: existingUser.Email,
@@ -180,7 +217,7 @@ func MuxDeleteUser(cfg *config.APIConfig) http.HandlerFunc {
 			return
 		}
 
-		if err := cfg.DB.DeleteUser(r.Context(), int32(userId)); err != nil {
+		if err := service.DeleteUser(r.Context(), cfg, audit.FromRequest(cfg, r, "mux"), int32(userId)); err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
@@ -188,3 +225,222 @@ func MuxDeleteUser(cfg *config.APIConfig) http.HandlerFunc {
 		utils.RespondWithJSON(w, http.StatusNoContent, "Successfully deleted the user")
 	}
 }
+
+// BULK CREATE USERS
+func MuxBulkCreateUsers(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, response, err := bulkCreateUsers(cfg, r, "mux")
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// BULK UPDATE USERS
+func MuxBulkUpdateUsers(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, response, err := bulkUpdateUsers(cfg, r, "mux")
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// BULK DELETE USERS
+func MuxBulkDeleteUsers(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, response, err := bulkDeleteUsers(cfg, r, "mux")
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// IMPORT USERS
+func MuxImportUsers(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, response, err := createUserImport(cfg, r, "mux")
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		w.Header().Set("Location", importLocation(response))
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// RESTORE USER
+func MuxRestoreUser(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		// Extract user ID from request URL parameters
+		vars := mux.Vars(r)
+		userIdStr, ok := vars["id"]
+		if !ok {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		userId, err := strconv.ParseUint(userIdStr, 10, 32)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		user, err := service.RestoreUser(r.Context(), cfg, audit.FromRequest(cfg, r, "mux"), int32(userId))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				utils.RespondWithError(w, http.StatusNotFound, "Deleted user not found")
+				return
+			}
+			if database.IsUniqueViolation(err) {
+				utils.RespondWithError(w, http.StatusConflict, "Conflict: Email is in use by another user")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseUser(user))
+	}
+}
+
+// USER HISTORY
+func MuxGetUserHistory(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		// Extract user ID from request URL parameters
+		vars := mux.Vars(r)
+		userIdStr, ok := vars["id"]
+		if !ok {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		userId, err := strconv.ParseUint(userIdStr, 10, 32)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		params, err := auditFilter(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		params.UserID = pgtype.Int4{Int32: int32(userId), Valid: true}
+
+		events, err := cfg.DB.ListAuditEvents(r.Context(), params)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseAuditEvents(events, params.Limit, params.Offset))
+	}
+}
+
+// AUDIT LOG
+func MuxGetAuditEvents(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		params, err := auditFilter(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		events, err := cfg.DB.ListAuditEvents(r.Context(), params)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseAuditEvents(events, params.Limit, params.Offset))
+	}
+}
+
+// USER EVENTS
+func MuxUserEvents(cfg *config.APIConfig, broker *events.Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		streamUserEvents(cfg, broker, w, r)
+	}
+}
+
+// USER WEBSOCKET
+func MuxUserSocket(cfg *config.APIConfig, broker *events.Broker) http.HandlerFunc {
+	policy := cors.New(cfg.CORS)
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveUserSocket(cfg, policy, broker, w, r)
+	}
+}
+
+// GET IMPORT
+func MuxGetImport(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, response, err := getUserImport(cfg, r, mux.Vars(r)["id"])
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// IMPORT ERRORS
+func MuxGetImportErrors(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userImportErrors(cfg, w, r, mux.Vars(r)["id"])
+	}
+}
+
+// OPENAPI DOCUMENT
+func MuxOpenAPI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveOpenAPI(w)
+	}
+}
+
+// API DOCS
+func MuxDocs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveDocs(w)
+	}
+}
+
+// HEALTH CHECK
+func MuxHealth() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveHealth(w)
+	}
+}
+
+// READINESS CHECK
+func MuxReadiness(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveReadiness(cfg, w, r)
+	}
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/openapi"
+)
+
+// serveOpenAPI writes the OpenAPI document, which is the same for every
+// router.
+func serveOpenAPI(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openapi.JSON())
+}
+
+// serveDocs writes the page rendering the OpenAPI document.
+func serveDocs(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openapi.DocsHTML)
+}
@@ -1,18 +1,31 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/cors"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/service"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // CREATE USER
 func StandardCreateUser(cfg *config.APIConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// Read a JSON body into the form
+		if err := parseJSONForm(r); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		// Parse form data
 		err := r.ParseForm()
 		if err != nil {
@@ -39,7 +52,7 @@ func StandardCreateUser(cfg *config.APIConfig) http.HandlerFunc {
 		}
 
 		// Create user
-		user, err := cfg.DB.CreateUser(r.Context(), database.CreateUserParams{
+		user, err := service.CreateUser(r.Context(), cfg, audit.FromRequest(cfg, r, "standard"), database.CreateUserParams{
 			Name:  name,
 			Email: email,
 			Age:   int32(age),
@@ -57,13 +70,20 @@ func StandardCreateUser(cfg *config.APIConfig) http.HandlerFunc {
 func StandardGetUsers(cfg *config.APIConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		users, err := cfg.DB.GetUsers(r.Context())
+		status, users, err := listUsers(cfg, r)
 		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			utils.RespondWithError(w, status, err.Error())
 			return
 		}
 
-		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseUsers(users))
+		utils.RespondWithJSON(w, status, users)
+	}
+}
+
+// EXPORT USERS
+func StandardExportUsers(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		exportUsers(cfg, w, r)
 	}
 }
 
@@ -79,10 +99,21 @@ func StandardGetUser(cfg *config.APIConfig) http.HandlerFunc {
 			return
 		}
 
+		include, status, err := includeDeleted(cfg, r)
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
 		// Query the DB for the user with the specified ID
-		user, err := cfg.DB.GetUser(r.Context(), int32(userId))
+		var user database.User
+		if include {
+			user, err = cfg.DB.GetUserIncludingDeleted(r.Context(), int32(userId))
+		} else {
+			user, err = cfg.DB.GetUser(r.Context(), int32(userId))
+		}
 		if err != nil {
-			http.Error(w, "User not found", http.StatusNotFound)
+			utils.RespondWithError(w, http.StatusNotFound, "User not found")
 			return
 		}
 
@@ -109,6 +140,12 @@ func StandardUpdateUser(cfg *config.APIConfig) http.HandlerFunc {
 			return
 		}
 
+		// Read a JSON body into the form
+		if err := parseJSONForm(r); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		// Get form values
 		name := r.FormValue("name")
 		email := r.FormValue("email")
@@ -131,7 +168,7 @@ func StandardUpdateUser(cfg *config.APIConfig) http.HandlerFunc {
 		}
 
 		// Save the updated user to the database
-		updatedUser, err := cfg.DB.UpdateUser(r.Context(), database.UpdateUserParams{
+		updatedUser, err := service.UpdateUser(r.Context(), cfg, audit.FromRequest(cfg, r, "standard"), database.UpdateUserParams{
 			ID:    existingUser.ID,
 			Name:  existingUser.Name,
 			Email: existingUser.Email,
@@ -164,7 +201,7 @@ func StandardDeleteUser(cfg *config.APIConfig) http.HandlerFunc {
 			return
 		}
 
-		if err := cfg.DB.DeleteUser(r.Context(), int32(userId)); err != nil {
+		if err := service.DeleteUser(r.Context(), cfg, audit.FromRequest(cfg, r, "standard"), int32(userId)); err != nil {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
@@ -172,3 +209,212 @@ func StandardDeleteUser(cfg *config.APIConfig) http.HandlerFunc {
 		utils.RespondWithJSON(w, http.StatusNoContent, "Successfully deleted the user")
 	}
 }
+
+// BULK CREATE USERS
+func StandardBulkCreateUsers(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, response, err := bulkCreateUsers(cfg, r, "standard")
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// BULK UPDATE USERS
+func StandardBulkUpdateUsers(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, response, err := bulkUpdateUsers(cfg, r, "standard")
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// BULK DELETE USERS
+func StandardBulkDeleteUsers(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, response, err := bulkDeleteUsers(cfg, r, "standard")
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// IMPORT USERS
+func StandardImportUsers(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, response, err := createUserImport(cfg, r, "standard")
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		w.Header().Set("Location", importLocation(response))
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// RESTORE USER
+func StandardRestoreUser(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		// Extract user ID from request URL parameters
+		userIdStr := r.PathValue("id")
+
+		userId, err := strconv.ParseUint(userIdStr, 10, 32)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		user, err := service.RestoreUser(r.Context(), cfg, audit.FromRequest(cfg, r, "standard"), int32(userId))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				utils.RespondWithError(w, http.StatusNotFound, "Deleted user not found")
+				return
+			}
+			if database.IsUniqueViolation(err) {
+				utils.RespondWithError(w, http.StatusConflict, "Conflict: Email is in use by another user")
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseUser(user))
+	}
+}
+
+// USER HISTORY
+func StandardGetUserHistory(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		// Extract user ID from request URL parameters
+		userIdStr := r.PathValue("id")
+
+		userId, err := strconv.ParseUint(userIdStr, 10, 32)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Bad Request")
+			return
+		}
+
+		params, err := auditFilter(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		params.UserID = pgtype.Int4{Int32: int32(userId), Valid: true}
+
+		events, err := cfg.DB.ListAuditEvents(r.Context(), params)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseAuditEvents(events, params.Limit, params.Offset))
+	}
+}
+
+// AUDIT LOG
+func StandardGetAuditEvents(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.IsAdmin(r) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: Admin access required")
+			return
+		}
+
+		params, err := auditFilter(r)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		events, err := cfg.DB.ListAuditEvents(r.Context(), params)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, models.FromDatabaseAuditEvents(events, params.Limit, params.Offset))
+	}
+}
+
+// USER EVENTS
+func StandardUserEvents(cfg *config.APIConfig, broker *events.Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		streamUserEvents(cfg, broker, w, r)
+	}
+}
+
+// USER WEBSOCKET
+func StandardUserSocket(cfg *config.APIConfig, broker *events.Broker) http.HandlerFunc {
+	policy := cors.New(cfg.CORS)
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveUserSocket(cfg, policy, broker, w, r)
+	}
+}
+
+// GET IMPORT
+func StandardGetImport(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, response, err := getUserImport(cfg, r, r.PathValue("id"))
+		if err != nil {
+			utils.RespondWithError(w, status, err.Error())
+			return
+		}
+
+		utils.RespondWithJSON(w, status, response)
+	}
+}
+
+// IMPORT ERRORS
+func StandardGetImportErrors(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userImportErrors(cfg, w, r, r.PathValue("id"))
+	}
+}
+
+// OPENAPI DOCUMENT
+func StandardOpenAPI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveOpenAPI(w)
+	}
+}
+
+// API DOCS
+func StandardDocs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveDocs(w)
+	}
+}
+
+// HEALTH CHECK
+func StandardHealth() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveHealth(w)
+	}
+}
+
+// READINESS CHECK
+func StandardReadiness(cfg *config.APIConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveReadiness(cfg, w, r)
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/webhooks"
+)
+
+// webhookForm holds the form fields of a webhook create or update. Fields
+// left out of an update are nil and keep their current value.
+type webhookForm struct {
+	url    *string
+	secret *string
+	events *[]string
+	active *bool
+}
+
+func parseWebhookForm(r *http.Request) (webhookForm, error) {
+	var form webhookForm
+
+	if err := parseJSONForm(r); err != nil {
+		return form, err
+	}
+	if err := r.ParseForm(); err != nil {
+		return form, errors.New("Bad Request: Invalid form data")
+	}
+
+	if values, ok := r.Form["url"]; ok {
+		u, err := url.Parse(values[0])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return form, errors.New("Bad Request: url must be an absolute http or https URL")
+		}
+		form.url = &values[0]
+	}
+
+	if values, ok := r.Form["secret"]; ok {
+		if values[0] == "" {
+			return form, errors.New("Bad Request: Empty secret")
+		}
+		form.secret = &values[0]
+	}
+
+	// events may be repeated or comma separated; an empty value means all.
+	if values, ok := r.Form["events"]; ok {
+		events := []string{}
+		for _, value := range values {
+			for _, event := range strings.Split(value, ",") {
+				event = strings.TrimSpace(event)
+				if event == "" {
+					continue
+				}
+				if !slices.Contains(outbox.EventTypes, event) {
+					return form, fmt.Errorf("Bad Request: Unknown event %q", event)
+				}
+				if !slices.Contains(events, event) {
+					events = append(events, event)
+				}
+			}
+		}
+		form.events = &events
+	}
+
+	if values, ok := r.Form["active"]; ok {
+		active, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return form, errors.New("Bad Request: Invalid active value")
+		}
+		form.active = &active
+	}
+
+	return form, nil
+}
+
+// createParams turns a form into a new webhook, generating a secret when
+// none was given.
+func (form webhookForm) createParams() (database.CreateWebhookParams, error) {
+	if form.url == nil {
+		return database.CreateWebhookParams{}, errors.New("Bad Request: Empty url")
+	}
+
+	params := database.CreateWebhookParams{Url: *form.url, Secret: webhooks.NewSecret(), Events: []string{}}
+	if form.secret != nil {
+		params.Secret = *form.secret
+	}
+	if form.events != nil {
+		params.Events = *form.events
+	}
+
+	return params, nil
+}
+
+// updateParams applies a form on top of an existing webhook.
+func (form webhookForm) updateParams(existing database.Webhook) database.UpdateWebhookParams {
+	params := database.UpdateWebhookParams{
+		ID:     existing.ID,
+		Url:    existing.Url,
+		Secret: existing.Secret,
+		Events: existing.Events,
+		Active: existing.Active,
+	}
+
+	if form.url != nil {
+		params.Url = *form.url
+	}
+	if form.secret != nil {
+		params.Secret = *form.secret
+	}
+	if form.events != nil {
+		params.Events = *form.events
+	}
+	if form.active != nil {
+		params.Active = *form.active
+	}
+
+	return params
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/cors"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/utils"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/websocket"
+)
+
+// How long to wait for the client to answer a close frame before dropping
+// the connection.
+const wsCloseWait = 5 * time.Second
+
+// socketRequest is a message sent by a WebSocket client, for example
+// {"action": "subscribe", "user_ids": [1, 2]} or {"action": "subscribe", "all": true}.
+type socketRequest struct {
+	Action  string  `json:"action"`
+	All     bool    `json:"all"`
+	UserIDs []int32 `json:"user_ids"`
+}
+
+// socketMessage is a message sent to a WebSocket client: a user event, the
+// current subscription after a request, or an error.
+type socketMessage struct {
+	Type    string        `json:"type"`
+	Event   *outbox.Event `json:"event,omitempty"`
+	All     bool          `json:"all,omitempty"`
+	UserIDs []int32       `json:"user_ids,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+type socketSubscription struct {
+	mu      sync.Mutex
+	all     bool
+	userIDs map[int32]struct{}
+}
+
+func (s *socketSubscription) apply(req socketRequest) socketMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Action {
+	case "subscribe":
+		if req.All {
+			s.all = true
+		}
+		for _, id := range req.UserIDs {
+			s.userIDs[id] = struct{}{}
+		}
+	case "unsubscribe":
+		if req.All {
+			s.all = false
+			clear(s.userIDs)
+		}
+		for _, id := range req.UserIDs {
+			delete(s.userIDs, id)
+		}
+	default:
+		return socketMessage{Type: "error", Error: "Unknown action, expected subscribe or unsubscribe"}
+	}
+
+	userIDs := make([]int32, 0, len(s.userIDs))
+	for id := range s.userIDs {
+		userIDs = append(userIDs, id)
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	return socketMessage{Type: "subscribed", All: s.all, UserIDs: userIDs}
+}
+
+func (s *socketSubscription) matches(userID int32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.userIDs[userID]
+	return s.all || ok
+}
+
+// allowSocketOrigin reports whether the page the WebSocket request of r comes
+// from may open one. Browsers send cookies and client certificates with the
+// handshake whatever page opens the socket, and WebSockets are not subject
+// to CORS, so only pages on the origin of the server itself and on the
+// allowed CORS origins may. Requests without an Origin header do not come
+// from a page.
+func allowSocketOrigin(policy *cors.Policy, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return policy.AllowsOrigin(origin)
+}
+
+// checkUserSocket checks a WebSocket request, returning the key to accept it
+// with, or the status and error to answer it with.
+func checkUserSocket(policy *cors.Policy, w http.ResponseWriter, r *http.Request) (string, int, error) {
+	if !allowSocketOrigin(policy, r) {
+		return "", http.StatusForbidden, errors.New("Origin not allowed")
+	}
+
+	key, err := websocket.CheckHandshake(w, r)
+	if err != nil {
+		return "", http.StatusBadRequest, err
+	}
+
+	return key, http.StatusSwitchingProtocols, nil
+}
+
+// serveUserSocket upgrades the request to a WebSocket and serves it, for the
+// routers built on net/http handlers.
+func serveUserSocket(cfg *config.APIConfig, policy *cors.Policy, broker *events.Broker, w http.ResponseWriter, r *http.Request) {
+	key, status, err := checkUserSocket(policy, w, r)
+	if err != nil {
+		utils.RespondWithError(w, status, err.Error())
+		return
+	}
+
+	netConn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	conn, err := websocket.Accept(netConn, rw, key)
+	if err != nil {
+		return
+	}
+	runUserSocket(cfg, broker, conn)
+}
+
+// runUserSocket sends the client the user events it subscribes to until
+// either side closes the connection. It is shared by the USER WEBSOCKET
+// handler of every framework.
+func runUserSocket(cfg *config.APIConfig, broker *events.Broker, conn *websocket.Conn) {
+	defer conn.Close()
+
+	// The subscriber channel is the client's send buffer; a client that
+	// lets it fill up is disconnected by the broker.
+	sub, _ := broker.Subscribe(0)
+	defer broker.Unsubscribe(sub)
+
+	subscription := &socketSubscription{userIDs: map[int32]struct{}{}}
+
+	// Clients must answer pings, so a connection that stays silent for two
+	// heartbeats is dead.
+	pongWait := 2 * cfg.Events.Heartbeat
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func() {
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(pongWait))
+
+			var req socketRequest
+			reply := socketMessage{Type: "error", Error: "Invalid message"}
+			if err := json.Unmarshal(data, &req); err == nil {
+				reply = subscription.apply(req)
+			}
+			if err := writeSocketMessage(conn, reply); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(cfg.Events.Heartbeat)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				if broker.Closed() {
+					conn.WriteClose(websocket.CloseGoingAway, "Server shutting down")
+				} else {
+					conn.WriteClose(websocket.CloseTryAgainLater, "Client too slow")
+				}
+				select {
+				case <-done:
+				case <-time.After(wsCloseWait):
+				}
+				return
+			}
+			if !subscription.matches(event.UserID) {
+				continue
+			}
+			if err := writeSocketMessage(conn, socketMessage{Type: "event", Event: &event}); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WritePing(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeSocketMessage(conn *websocket.Conn, message socketMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/cors"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/echo/v4"
+)
+
+var socketCORS = config.CORSConfig{AllowedOrigins: []string{"https://app.example.com", "http://localhost:*"}}
+
+func TestAllowSocketOrigin(t *testing.T) {
+	policy := cors.New(socketCORS)
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		// Not sent from a page
+		{"", true},
+		// The origin of the server itself
+		{"https://api.example.com", true},
+		{"http://API.example.com", true},
+		// Allowed CORS origins
+		{"https://app.example.com", true},
+		{"http://localhost:3000", true},
+		{"https://evil.example.com", false},
+		{"https://api.example.com.evil.com", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "https://api.example.com/ws", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := allowSocketOrigin(policy, r); got != tt.want {
+			t.Errorf("Origin %q: allowed %t, want %t", tt.origin, got, tt.want)
+		}
+	}
+}
+
+// socketClient is the client end of a WebSocket connection.
+type socketClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dialSocket opens a WebSocket to the server at url with the given Origin,
+// returning the response to the handshake, and the client when it is 101.
+func dialSocket(t *testing.T, url, origin string) (*http.Response, *socketClient) {
+	t.Helper()
+	addr := strings.TrimPrefix(url, "http://")
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	handshake := "GET /ws HTTP/1.1\r\nHost: " + addr + "\r\n" +
+		"Connection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+	if origin != "" {
+		handshake += "Origin: " + origin + "\r\n"
+	}
+	if _, err := io.WriteString(conn, handshake+"\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		return res, nil
+	}
+	return res, &socketClient{conn: conn, br: br}
+}
+
+// send sends v as a masked text frame.
+func (c *socketClient) send(t *testing.T, v any) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask := [4]byte{1, 2, 3, 4}
+	frame := append([]byte{0x81, 0x80 | byte(len(data))}, mask[:]...)
+	for i, b := range data {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// receive reads the next text message of the server, skipping pings.
+func (c *socketClient) receive(t *testing.T) socketMessage {
+	t.Helper()
+	for {
+		var head [2]byte
+		if _, err := io.ReadFull(c.br, head[:]); err != nil {
+			t.Fatalf("reading a frame: %v", err)
+		}
+		length := int(head[1] & 0x7f)
+		if length == 126 {
+			var ext [2]byte
+			io.ReadFull(c.br, ext[:])
+			length = int(binary.BigEndian.Uint16(ext[:]))
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.br, payload); err != nil {
+			t.Fatalf("reading a frame: %v", err)
+		}
+		if head[0]&0x0f != 1 {
+			continue
+		}
+
+		var message socketMessage
+		if err := json.Unmarshal(payload, &message); err != nil {
+			t.Fatalf("%q: %v", payload, err)
+		}
+		return message
+	}
+}
+
+// socketServers serves the user socket as each kind of handler does: over
+// net/http, and by hijacking the connection of echo and gin.
+func socketServers(cfg *config.APIConfig, broker *events.Broker) map[string]http.Handler {
+	policy := cors.New(cfg.CORS)
+	standard := http.NewServeMux()
+	standard.HandleFunc("GET /ws", func(w http.ResponseWriter, r *http.Request) {
+		serveUserSocket(cfg, policy, broker, w, r)
+	})
+
+	e := echo.New()
+	e.GET("/ws", EchoUserSocket(cfg, broker))
+
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.GET("/ws", GinUserSocket(cfg, broker))
+
+	return map[string]http.Handler{"standard": standard, "echo": e, "gin": g}
+}
+
+func TestUserSocketSendsSubscribedEvents(t *testing.T) {
+	cfg := &config.APIConfig{CORS: socketCORS, Events: config.EventsConfig{ReplayBuffer: 10, ClientBuffer: 10, Heartbeat: time.Hour}}
+	broker := events.NewBroker(cfg)
+
+	for name, handler := range socketServers(cfg, broker) {
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		res, client := dialSocket(t, srv.URL, "https://app.example.com")
+		if client == nil {
+			t.Fatalf("%s: handshake answered with %s, want 101", name, res.Status)
+		}
+
+		replies := []struct {
+			request socketRequest
+			want    socketMessage
+		}{
+			{socketRequest{Action: "subscribe", UserIDs: []int32{3, 1, 2}}, socketMessage{Type: "subscribed", UserIDs: []int32{1, 2, 3}}},
+			{socketRequest{Action: "unsubscribe", UserIDs: []int32{1, 3}}, socketMessage{Type: "subscribed", UserIDs: []int32{2}}},
+			{socketRequest{Action: "watch"}, socketMessage{Type: "error", Error: "Unknown action, expected subscribe or unsubscribe"}},
+		}
+		for _, reply := range replies {
+			client.send(t, reply.request)
+			if got := client.receive(t); !reflect.DeepEqual(got, reply.want) {
+				t.Fatalf("%s: %+v answered with %+v, want %+v", name, reply.request, got, reply.want)
+			}
+		}
+		client.send(t, "subscribe")
+		if got := client.receive(t); got.Type != "error" || got.Error != "Invalid message" {
+			t.Fatalf("%s: invalid message answered with %+v", name, got)
+		}
+
+		// Only the events of the users subscribed to are sent
+		broker.Publish(outbox.Event{ID: 1, UserID: 1, Type: outbox.UserUpdated})
+		broker.Publish(outbox.Event{ID: 2, UserID: 2, Type: outbox.UserUpdated})
+		if got := client.receive(t); got.Type != "event" || got.Event == nil || got.Event.ID != 2 {
+			t.Fatalf("%s: got %+v, want event 2", name, got)
+		}
+
+		// Subscribing to all sends the events of every user
+		client.send(t, socketRequest{Action: "subscribe", All: true})
+		if got := client.receive(t); !got.All {
+			t.Fatalf("%s: got %+v, want a subscription to all", name, got)
+		}
+		broker.Publish(outbox.Event{ID: 3, UserID: 7, Type: outbox.UserDeleted})
+		if got := client.receive(t); got.Type != "event" || got.Event == nil || got.Event.ID != 3 {
+			t.Fatalf("%s: got %+v, want event 3", name, got)
+		}
+	}
+}
+
+func TestUserSocketRejectsOtherOrigins(t *testing.T) {
+	cfg := &config.APIConfig{CORS: socketCORS, Events: config.EventsConfig{ReplayBuffer: 10, ClientBuffer: 10, Heartbeat: time.Hour}}
+	broker := events.NewBroker(cfg)
+
+	for name, handler := range socketServers(cfg, broker) {
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+
+		res, client := dialSocket(t, srv.URL, "https://evil.example.com")
+		if client != nil || res.StatusCode != http.StatusForbidden {
+			t.Errorf("%s: handshake from another origin answered with %s, want 403", name, res.Status)
+		}
+
+		// A page on the origin of the server itself may connect
+		if res, client := dialSocket(t, srv.URL, srv.URL); client == nil {
+			t.Errorf("%s: same-origin handshake answered with %s, want 101", name, res.Status)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+// Package idempotency makes POST and PATCH requests safe to retry. A request
+// sent with an Idempotency-Key header runs once; repeats with the same key
+// get the stored response of the first instead of running again. Keys are
+// scoped to the client and route of the request, so clients cannot take or
+// block each other's keys.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/jobs"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// Header is the request header carrying the key.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from an earlier request.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Keys stores the keys and responses of idempotent requests.
+type Keys struct {
+	db  *database.Queries
+	cfg config.IdempotencyConfig
+}
+
+func New(cfg *config.APIConfig) *Keys {
+	return &Keys{db: cfg.DB, cfg: cfg.Idempotency}
+}
+
+// Middleware runs POST and PATCH requests that carry a key at most once per
+// key, client and route. A repeat gets the stored response, a 409 while the first is still in
+// progress, or a 422 if it is not the same request as the first. Responses
+// with a 5xx status are not stored, so the request can be retried.
+func (k *Keys) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !validKey(key) {
+			writeProblem(w, http.StatusBadRequest, "Invalid Idempotency-Key",
+				"The key must be 1 to 255 printable ASCII characters.")
+			return
+		}
+
+		body, err := readBody(r, k.cfg.MaxBodyBytes)
+		if errors.Is(err, errTooLarge) {
+			writeProblem(w, http.StatusRequestEntityTooLarge, "Request body too large for an Idempotency-Key",
+				"Send the request without an Idempotency-Key, or with a smaller body.")
+			return
+		}
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, "Could not read the request body", "")
+			return
+		}
+		scope, fingerprint := scope(r), fingerprint(r, body)
+
+		now := time.Now()
+		claim, err := k.db.ClaimIdempotencyKey(r.Context(), database.ClaimIdempotencyKeyParams{
+			Scope:       scope,
+			Key:         key,
+			Fingerprint: fingerprint,
+			ClaimToken:  newClaimToken(),
+			ExpiresAt:   pgtype.Timestamptz{Time: now.Add(k.cfg.TTL), Valid: true},
+			StaleBefore: pgtype.Timestamptz{Time: now.Add(-k.cfg.LockTimeout), Valid: true},
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			k.repeat(w, r, scope, key, fingerprint)
+			return
+		}
+		if err != nil {
+			log.Printf("Error claiming idempotency key: %v", err)
+			writeProblem(w, http.StatusInternalServerError, "Could not check the Idempotency-Key", "")
+			return
+		}
+
+		k.run(w, r, next, claim)
+	})
+}
+
+// repeat answers a request whose key is already taken.
+func (k *Keys) repeat(w http.ResponseWriter, r *http.Request, scope, key, fingerprint string) {
+	stored, err := k.db.GetIdempotencyKey(r.Context(), database.GetIdempotencyKeyParams{Scope: scope, Key: key})
+	if err != nil {
+		// The first request failed and released the key in the meantime
+		if errors.Is(err, pgx.ErrNoRows) {
+			w.Header().Set("Retry-After", "1")
+			writeProblem(w, http.StatusConflict, "Idempotency-Key is in use", "Retry the request.")
+			return
+		}
+		log.Printf("Error reading idempotency key: %v", err)
+		writeProblem(w, http.StatusInternalServerError, "Could not check the Idempotency-Key", "")
+		return
+	}
+
+	switch {
+	case stored.Fingerprint != fingerprint:
+		writeProblem(w, http.StatusUnprocessableEntity, "Idempotency-Key reused",
+			"The key was used for a different request. Use a new key for each request.")
+	case !stored.Status.Valid:
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, http.StatusConflict, "Idempotency-Key is in use",
+			"A request with this key is still in progress. Retry it once that has finished.")
+	default:
+		replay(w, stored)
+	}
+}
+
+// run passes the request on and stores its response under the key of claim.
+// If the request outlives the lock timeout, a retry may take the key over;
+// the claim token then keeps this request from storing its response over,
+// or releasing, the claim of the retry.
+func (k *Keys) run(w http.ResponseWriter, r *http.Request, next http.Handler, claim database.IdempotencyKey) {
+	// The key is settled even if the client goes away
+	ctx := context.WithoutCancel(r.Context())
+	rec := &recorder{ResponseWriter: w}
+
+	completed := false
+	defer func() {
+		// Unless the response was stored, as after a 5xx or a panic, a retry
+		// runs the request again
+		if !completed {
+			err := k.db.ReleaseIdempotencyKey(ctx, database.ReleaseIdempotencyKeyParams{
+				Scope:      claim.Scope,
+				Key:        claim.Key,
+				ClaimToken: claim.ClaimToken,
+			})
+			if err != nil {
+				log.Printf("Error releasing idempotency key: %v", err)
+			}
+		}
+	}()
+
+	next.ServeHTTP(rec, r)
+
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	if rec.status >= 500 {
+		return
+	}
+
+	headers, err := json.Marshal(rec.header)
+	if err != nil {
+		log.Printf("Error encoding idempotent response headers: %v", err)
+		return
+	}
+	stored, err := k.db.CompleteIdempotencyKey(ctx, database.CompleteIdempotencyKeyParams{
+		Scope:      claim.Scope,
+		Key:        claim.Key,
+		ClaimToken: claim.ClaimToken,
+		Status:     pgtype.Int4{Int32: int32(rec.status), Valid: true},
+		Headers:    headers,
+		Body:       rec.body.Bytes(),
+	})
+	if err != nil {
+		log.Printf("Error storing idempotent response: %v", err)
+		return
+	}
+	if stored == 0 {
+		log.Printf("Idempotency key %q was taken over by a retry before the response was stored", claim.Key)
+	}
+	completed = true
+}
+
+func replay(w http.ResponseWriter, stored database.IdempotencyKey) {
+	var header http.Header
+	if err := json.Unmarshal(stored.Headers, &header); err != nil {
+		log.Printf("Error decoding idempotent response headers: %v", err)
+	}
+	for name, values := range header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(int(stored.Status.Int32))
+	w.Write(stored.Body)
+}
+
+func validKey(key string) bool {
+	if len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < ' ' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+var errTooLarge = errors.New("body too large")
+
+// readBody reads the whole body, leaving a copy in its place for the handler.
+func readBody(r *http.Request, limit int) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > limit {
+		return nil, errTooLarge
+	}
+
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// scope is the namespace of the key of a request: the route and the client,
+// by its credentials, token or client certificate. Requests without
+// credentials share the scope of their route.
+func scope(r *http.Request) string {
+	h := sha256.New()
+	for _, part := range []string{
+		r.Method,
+		r.URL.Path,
+		r.Header.Get("Authorization"),
+		config.ClientIdentity(r),
+	} {
+		io.WriteString(h, part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// newClaimToken returns a random token identifying a claim of a key.
+func newClaimToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// fingerprint identifies a request by everything that affects its outcome.
+// The credentials, token and client certificate alike, are part of it, so a
+// key cannot be used to read the response to someone else's request.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	for _, part := range []string{
+		r.Method,
+		r.URL.Path,
+		r.URL.RawQuery,
+		r.Header.Get("Content-Type"),
+		r.Header.Get("Authorization"),
+		config.ClientIdentity(r),
+	} {
+		io.WriteString(h, part)
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeProblem(w http.ResponseWriter, status int, title, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.Problem{
+		Type:   "about:blank",
+		Title:  title,
+		Status: status,
+		Detail: detail,
+	})
+}
+
+// recorder passes a response through while keeping a copy of it.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 && status >= 200 {
+		r.status = status
+		r.header = r.Header().Clone()
+		r.header.Del("Date")
+		r.header.Del("Content-Length")
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) Flush() {
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// CleanupJobKind is the kind of the recurring job deleting expired keys.
+const CleanupJobKind = "delete_expired_idempotency_keys"
+
+// Register schedules the deletion of expired keys on r.
+func Register(r *jobs.Runner, cfg *config.APIConfig, schedule jobs.Schedule) {
+	jobs.Register(r, CleanupJobKind, func(ctx context.Context, _ struct{}) error {
+		deleted, err := cfg.DB.DeleteExpiredIdempotencyKeys(ctx)
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			log.Printf("Deleted %d expired idempotency keys", deleted)
+		}
+		return nil
+	})
+	r.Schedule(CleanupJobKind, schedule, struct{}{})
+}
@@ -0,0 +1,209 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database/dbtest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// table emulates the idempotency_keys table for the queries of the
+// middleware.
+type table struct {
+	mu   sync.Mutex
+	rows map[[2]string]*database.IdempotencyKey
+}
+
+func (t *table) db() *dbtest.DB {
+	db := dbtest.New()
+	db.Handle("ClaimIdempotencyKey", func(args ...any) ([][]any, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		id := [2]string{args[0].(string), args[1].(string)}
+		fingerprint, expiresAt := args[2].(string), args[4].(pgtype.Timestamptz)
+		staleBefore := args[5].(pgtype.Timestamptz)
+
+		now := time.Now()
+		if row, ok := t.rows[id]; ok {
+			expired := !row.ExpiresAt.Time.After(now)
+			stale := !row.Status.Valid && row.Fingerprint == fingerprint && row.CreatedAt.Time.Before(staleBefore.Time)
+			if !expired && !stale {
+				return nil, nil
+			}
+		}
+		row := &database.IdempotencyKey{
+			Scope:       id[0],
+			Key:         id[1],
+			Fingerprint: fingerprint,
+			ClaimToken:  args[3].(string),
+			CreatedAt:   pgtype.Timestamptz{Time: now, Valid: true},
+			ExpiresAt:   expiresAt,
+		}
+		t.rows[id] = row
+		return dbtest.Rows(*row), nil
+	})
+	db.Handle("GetIdempotencyKey", func(args ...any) ([][]any, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if row, ok := t.rows[[2]string{args[0].(string), args[1].(string)}]; ok {
+			return dbtest.Rows(*row), nil
+		}
+		return nil, nil
+	})
+	db.Handle("CompleteIdempotencyKey", func(args ...any) ([][]any, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		row, ok := t.rows[[2]string{args[0].(string), args[1].(string)}]
+		if !ok || row.ClaimToken != args[2].(string) || row.Status.Valid {
+			return nil, nil
+		}
+		row.Status, row.Headers, row.Body = args[3].(pgtype.Int4), args[4].([]byte), args[5].([]byte)
+		return [][]any{nil}, nil
+	})
+	db.Handle("ReleaseIdempotencyKey", func(args ...any) ([][]any, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		id := [2]string{args[0].(string), args[1].(string)}
+		row, ok := t.rows[id]
+		if !ok || row.ClaimToken != args[2].(string) || row.Status.Valid {
+			return nil, nil
+		}
+		delete(t.rows, id)
+		return [][]any{nil}, nil
+	})
+	return db
+}
+
+func newKeys(lockTimeout time.Duration) (*Keys, *table) {
+	t := &table{rows: map[[2]string]*database.IdempotencyKey{}}
+	return New(&config.APIConfig{
+		DB: database.New(t.db()),
+		Idempotency: config.IdempotencyConfig{
+			TTL:          time.Hour,
+			LockTimeout:  lockTimeout,
+			MaxBodyBytes: 1 << 20,
+		},
+	}), t
+}
+
+func request(key, token string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"Jane"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(Header, key)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestKeysAreScopedByClient(t *testing.T) {
+	keys, _ := newKeys(time.Minute)
+	runs := 0
+	h := keys.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	for _, token := range []string{"alice", "bob", "alice"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, request("same-key", token))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("%s: status %d, want %d", token, w.Code, http.StatusCreated)
+		}
+	}
+
+	// alice's repeat is replayed, but bob's request is his own
+	if runs != 2 {
+		t.Fatalf("handler ran %d times, want 2", runs)
+	}
+}
+
+// A request that outlives the lock timeout has its key taken over by a
+// retry. Whatever it ends with, it must leave the claim of the retry alone.
+func TestTakenOverClaimIsKeptByTheRetry(t *testing.T) {
+	for _, firstStatus := range []int{http.StatusInternalServerError, http.StatusAccepted} {
+		keys, table := newKeys(time.Millisecond)
+
+		firstRunning, retryRunning := make(chan struct{}), make(chan struct{})
+		finishRetry := make(chan struct{})
+		calls := 0
+		h := keys.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				close(firstRunning)
+				<-retryRunning
+				w.WriteHeader(firstStatus)
+				return
+			}
+			close(retryRunning)
+			<-finishRetry
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		firstDone := make(chan struct{})
+		go func() {
+			h.ServeHTTP(httptest.NewRecorder(), request("key", ""))
+			close(firstDone)
+		}()
+		<-firstRunning
+
+		// The retry arrives once the first request is stale, and is still
+		// running when the first ends
+		time.Sleep(5 * time.Millisecond)
+		retryDone := make(chan *httptest.ResponseRecorder)
+		go func() {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, request("key", ""))
+			retryDone <- w
+		}()
+		<-firstDone
+
+		table.mu.Lock()
+		for _, row := range table.rows {
+			if row.Status.Valid {
+				t.Errorf("first ending with %d: stored its status %d over the retry's claim", firstStatus, row.Status.Int32)
+			}
+		}
+		if len(table.rows) != 1 {
+			t.Errorf("first ending with %d: %d keys stored, want the retry's claim", firstStatus, len(table.rows))
+		}
+		table.mu.Unlock()
+
+		close(finishRetry)
+		if retry := <-retryDone; retry.Code != http.StatusCreated {
+			t.Errorf("first ending with %d: retry status %d, want %d", firstStatus, retry.Code, http.StatusCreated)
+		}
+		for _, row := range table.rows {
+			if row.Status.Int32 != http.StatusCreated {
+				t.Errorf("first ending with %d: stored status %d, want the retry's %d", firstStatus, row.Status.Int32, http.StatusCreated)
+			}
+		}
+	}
+}
+
+func TestReleasedKeyCanBeRetried(t *testing.T) {
+	keys, table := newKeys(time.Minute)
+	status := http.StatusServiceUnavailable
+	h := keys.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), request("key", ""))
+	if len(table.rows) != 0 {
+		t.Fatalf("%d keys stored after a 5xx, want it released", len(table.rows))
+	}
+
+	status = http.StatusCreated
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, request("key", ""))
+	if w.Code != http.StatusCreated || w.Header().Get(ReplayedHeader) != "" {
+		t.Fatalf("retry: status %d, replayed %q, want a fresh %d", w.Code, w.Header().Get(ReplayedHeader), http.StatusCreated)
+	}
+}
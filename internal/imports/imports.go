@@ -0,0 +1,309 @@
+package imports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/jobs"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/service"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Fields are the user fields an import fills in.
+var Fields = []string{"name", "email", "age"}
+
+// errDryRun rolls back the transaction of a dry run once every row is done.
+var errDryRun = errors.New("dry run")
+
+// Chunks written before an interruption are committed, so rather than write
+// them twice an interrupted import fails and is left to be run again.
+var errInterrupted = errors.New("Import was interrupted, import the file again with on_duplicate=skip to finish it")
+
+type Options struct {
+	Format      string
+	OnDuplicate string
+	DryRun      bool
+	// ColumnMap maps user fields to the source columns holding them. Fields
+	// left out are read from the column with their own name.
+	ColumnMap map[string]string
+}
+
+// Validate checks the options, filling in the default duplicate policy.
+func (o *Options) Validate() error {
+	if o.Format != FormatCSV && o.Format != FormatNDJSON {
+		return fmt.Errorf("format must be %s or %s", FormatCSV, FormatNDJSON)
+	}
+
+	switch o.OnDuplicate {
+	case "":
+		o.OnDuplicate = service.DuplicateFail
+	case service.DuplicateSkip, service.DuplicateFail, service.DuplicateUpsert:
+	default:
+		return fmt.Errorf("on_duplicate must be %s, %s or %s", service.DuplicateSkip, service.DuplicateFail, service.DuplicateUpsert)
+	}
+
+	for field := range o.ColumnMap {
+		if !slices.Contains(Fields, field) {
+			return fmt.Errorf("Unknown field %q in column mapping, expected %s", field, strings.Join(Fields, ", "))
+		}
+	}
+
+	return nil
+}
+
+// ParseColumnMap parses a column mapping such as "name:Full Name,age:Years".
+func ParseColumnMap(value string) (map[string]string, error) {
+	columnMap := map[string]string{}
+	if value == "" {
+		return columnMap, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		field, column, ok := strings.Cut(pair, ":")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("Invalid column mapping %q, expected field:column", pair)
+		}
+		columnMap[field] = column
+	}
+
+	return columnMap, nil
+}
+
+// FormatFromContentType guesses the format of an upload from its media type
+// or file name, returning "" when neither gives it away.
+func FormatFromContentType(mediaType, filename string) string {
+	switch {
+	case mediaType == "text/csv", strings.HasSuffix(strings.ToLower(filename), ".csv"):
+		return FormatCSV
+	case mediaType == "application/x-ndjson", mediaType == "application/jsonl",
+		strings.HasSuffix(strings.ToLower(filename), ".ndjson"), strings.HasSuffix(strings.ToLower(filename), ".jsonl"):
+		return FormatNDJSON
+	}
+	return ""
+}
+
+// Create stores an import and its file. When start is true the import is
+// marked running straight away, for callers that process it themselves;
+// otherwise a job is queued to process it.
+func Create(ctx context.Context, cfg *config.APIConfig, meta audit.Meta, opts Options, data []byte, start bool) (database.UserImport, error) {
+	columnMap, err := json.Marshal(opts.ColumnMap)
+	if err != nil {
+		return database.UserImport{}, err
+	}
+
+	var imp database.UserImport
+	err = cfg.WithRetryableTx(ctx, func(q *database.Queries) error {
+		imp, err = q.CreateUserImport(ctx, database.CreateUserImportParams{
+			Format:      opts.Format,
+			OnDuplicate: opts.OnDuplicate,
+			DryRun:      opts.DryRun,
+			ColumnMap:   columnMap,
+			Actor:       meta.Actor,
+			RequestID:   meta.RequestID,
+			Framework:   meta.Framework,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.CreateUserImportFile(ctx, database.CreateUserImportFileParams{
+			ImportID: imp.ID,
+			Data:     data,
+		})
+		if err != nil {
+			return err
+		}
+
+		if start {
+			imp, err = q.StartUserImport(ctx, imp.ID)
+			return err
+		}
+
+		_, err = jobs.Enqueue(ctx, q, JobKind, jobPayload{ImportID: imp.ID}, jobs.Options{MaxAttempts: 1})
+		return err
+	})
+	return imp, err
+}
+
+// JobKind is the kind of the job that processes an import.
+const JobKind = "user_import"
+
+type jobPayload struct {
+	ImportID int32 `json:"import_id"`
+}
+
+// Register makes r process the imports queued by Create.
+func Register(r *jobs.Runner, cfg *config.APIConfig) {
+	jobs.Register(r, JobKind, func(ctx context.Context, payload jobPayload) error {
+		imp, err := cfg.DB.StartUserImport(ctx, payload.ImportID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return interrupted(ctx, cfg, payload.ImportID)
+		}
+		if err != nil {
+			return err
+		}
+		return Process(ctx, cfg, imp)
+	})
+}
+
+// interrupted handles an import job run again after its import was already
+// started, failing the import if it never finished.
+func interrupted(ctx context.Context, cfg *config.APIConfig, id int32) error {
+	imp, err := cfg.DB.GetUserImport(ctx, id)
+	if err != nil || imp.Status != StatusRunning {
+		return err
+	}
+
+	return cfg.DB.FinishUserImport(ctx, database.FinishUserImportParams{
+		ID:     imp.ID,
+		Status: StatusFailed,
+		Error:  pgtype.Text{String: errInterrupted.Error(), Valid: true},
+	})
+}
+
+// Process runs a started import to the end, saving its progress and row
+// errors as it goes. Problems with the file itself fail the import; the
+// returned error is only for problems recording that.
+func Process(ctx context.Context, cfg *config.APIConfig, imp database.UserImport) error {
+	err := process(ctx, cfg, imp)
+	if err != nil && ctx.Err() != nil {
+		err = errInterrupted
+	}
+
+	finish := database.FinishUserImportParams{ID: imp.ID, Status: StatusSucceeded}
+	if err != nil {
+		finish.Status = StatusFailed
+		finish.Error = pgtype.Text{String: err.Error(), Valid: true}
+	}
+
+	// Record the outcome even when ctx was cancelled midway.
+	ctx = context.WithoutCancel(ctx)
+	if err := cfg.DB.FinishUserImport(ctx, finish); err != nil {
+		return err
+	}
+	return cfg.DB.DeleteUserImportFile(ctx, imp.ID)
+}
+
+func process(ctx context.Context, cfg *config.APIConfig, imp database.UserImport) error {
+	var columnMap map[string]string
+	if err := json.Unmarshal(imp.ColumnMap, &columnMap); err != nil {
+		return err
+	}
+
+	data, err := cfg.DB.GetUserImportFile(ctx, imp.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := parse(imp.Format, data, columnMap)
+	if err != nil {
+		return err
+	}
+
+	progress := database.UpdateUserImportProgressParams{ID: imp.ID, TotalRows: int32(len(rows))}
+	if err := cfg.DB.UpdateUserImportProgress(ctx, progress); err != nil {
+		return err
+	}
+
+	meta := audit.Meta{Actor: imp.Actor, RequestID: imp.RequestID, Framework: imp.Framework}
+	chunks := slices.Collect(slices.Chunk(rows, cfg.Imports.ChunkSize))
+
+	// A dry run writes everything in one transaction and rolls it back, so
+	// duplicates are detected exactly as in a real run.
+	if imp.DryRun {
+		err := cfg.WithTx(ctx, func(q *database.Queries) error {
+			for _, chunk := range chunks {
+				if err := processChunk(ctx, cfg, q, imp, meta, chunk, &progress); err != nil {
+					return err
+				}
+			}
+			return errDryRun
+		})
+		if errors.Is(err, errDryRun) {
+			return nil
+		}
+		return err
+	}
+
+	for _, chunk := range chunks {
+		err := cfg.WithTx(ctx, func(q *database.Queries) error {
+			return processChunk(ctx, cfg, q, imp, meta, chunk, &progress)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processChunk writes one chunk of rows through q, then records its row
+// errors and the import's progress outside of q's transaction.
+func processChunk(ctx context.Context, cfg *config.APIConfig, q *database.Queries, imp database.UserImport, meta audit.Meta, chunk []row, progress *database.UpdateUserImportProgressParams) error {
+	var rowErrors []database.CreateUserImportErrorsParams
+	rowError := func(r row, err error) {
+		rowErrors = append(rowErrors, database.CreateUserImportErrorsParams{
+			ImportID:  imp.ID,
+			RowNumber: int32(r.Number),
+			Email:     r.Email,
+			Error:     err.Error(),
+		})
+	}
+
+	var params []database.CreateUserParams
+	var valid []row
+	for _, r := range chunk {
+		user, err := validate(r)
+		if err != nil {
+			rowError(r, err)
+			continue
+		}
+		params = append(params, user)
+		valid = append(valid, r)
+	}
+
+	results, err := service.ImportUsers(ctx, q, meta, params, imp.OnDuplicate)
+	if err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		switch {
+		case result.Err != nil:
+			rowError(valid[i], result.Err)
+		case result.Outcome == service.ImportCreated:
+			progress.CreatedRows++
+		case result.Outcome == service.ImportUpdated:
+			progress.UpdatedRows++
+		case result.Outcome == service.ImportSkipped:
+			progress.SkippedRows++
+		}
+	}
+	progress.FailedRows += int32(len(rowErrors))
+	progress.ProcessedRows += int32(len(chunk))
+
+	if len(rowErrors) > 0 {
+		if _, err := cfg.DB.CreateUserImportErrors(ctx, rowErrors); err != nil {
+			return err
+		}
+	}
+	return cfg.DB.UpdateUserImportProgress(ctx, *progress)
+}
@@ -0,0 +1,170 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+)
+
+// row is one record of an import file, with its user fields looked up
+// through the column map.
+type row struct {
+	Number int
+	Name   string
+	Email  string
+	Age    string
+	// Err is set for records that could not be read at all.
+	Err error
+}
+
+// parse reads every record of data. An error is returned only when the file
+// as a whole is unusable; problems with single records are left on the row.
+func parse(format string, data []byte, columnMap map[string]string) ([]row, error) {
+	if format == FormatCSV {
+		return parseCSV(data, columnMap)
+	}
+	return parseNDJSON(data, columnMap), nil
+}
+
+func parseCSV(data []byte, columnMap map[string]string) ([]row, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CSV header: %w", err)
+	}
+
+	index := make(map[string]int, len(Fields))
+	for _, field := range Fields {
+		column := sourceColumn(columnMap, field)
+		index[field] = -1
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				index[field] = i
+				break
+			}
+		}
+		if index[field] < 0 {
+			return nil, fmt.Errorf("Missing column %q for %s", column, field)
+		}
+	}
+
+	value := func(record []string, field string) string {
+		if i := index[field]; i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []row
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, row{Number: parseErr.StartLine, Err: errors.New("Invalid CSV record")})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := r.FieldPos(0)
+		rows = append(rows, row{
+			Number: line,
+			Name:   value(record, "name"),
+			Email:  value(record, "email"),
+			Age:    value(record, "age"),
+		})
+	}
+}
+
+func parseNDJSON(data []byte, columnMap map[string]string) []row {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+
+	var rows []row
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(text))
+		dec.UseNumber()
+		var object map[string]interface{}
+		if err := dec.Decode(&object); err != nil {
+			rows = append(rows, row{Number: line, Err: errors.New("Invalid JSON")})
+			continue
+		}
+
+		value := func(field string) string {
+			column := sourceColumn(columnMap, field)
+			for key, v := range object {
+				if strings.EqualFold(key, column) {
+					if v == nil {
+						return ""
+					}
+					return strings.TrimSpace(fmt.Sprint(v))
+				}
+			}
+			return ""
+		}
+
+		rows = append(rows, row{
+			Number: line,
+			Name:   value("name"),
+			Email:  value("email"),
+			Age:    value("age"),
+		})
+	}
+
+	return rows
+}
+
+func sourceColumn(columnMap map[string]string, field string) string {
+	if column, ok := columnMap[field]; ok {
+		return column
+	}
+	return field
+}
+
+// validate applies the rules of the create endpoints to a row.
+func validate(r row) (database.CreateUserParams, error) {
+	if r.Err != nil {
+		return database.CreateUserParams{}, r.Err
+	}
+
+	switch {
+	case r.Name == "":
+		return database.CreateUserParams{}, errors.New("Missing name")
+	case r.Email == "":
+		return database.CreateUserParams{}, errors.New("Missing email")
+	case r.Age == "":
+		return database.CreateUserParams{}, errors.New("Missing age")
+	}
+
+	age, err := strconv.ParseUint(r.Age, 10, 31)
+	if err != nil {
+		return database.CreateUserParams{}, errors.New("Invalid age")
+	}
+
+	return database.CreateUserParams{
+		Name:  r.Name,
+		Email: r.Email,
+		Age:   int32(age),
+	}, nil
+}
@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+
+	// Jobs are retried until they have failed this many times, unless
+	// enqueued with another limit.
+	DefaultMaxAttempts = 5
+)
+
+// Statuses lists every job status, in the order a job goes through them.
+var Statuses = []string{StatusQueued, StatusRunning, StatusSucceeded, StatusFailed, StatusCancelled}
+
+// ErrDuplicate is returned by Enqueue when a job with the same unique key
+// already exists.
+var ErrDuplicate = errors.New("job already enqueued")
+
+type Options struct {
+	// RunAt delays the job until then. The zero time runs it straight away.
+	RunAt       time.Time
+	MaxAttempts int
+	// UniqueKey, when set, keeps the job from being enqueued more than once.
+	UniqueKey string
+}
+
+// Enqueue stores a job of kind with payload encoded as JSON. Pass queries
+// bound to a transaction to enqueue the job only if that transaction commits.
+func Enqueue(ctx context.Context, q *database.Queries, kind string, payload any, opts Options) (database.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return database.Job{}, err
+	}
+
+	runAt := opts.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	job, err := q.CreateJob(ctx, database.CreateJobParams{
+		Kind:        kind,
+		Payload:     data,
+		MaxAttempts: int32(maxAttempts),
+		RunAt:       pgtype.Timestamptz{Time: runAt, Valid: true},
+		UniqueKey:   pgtype.Text{String: opts.UniqueKey, Valid: opts.UniqueKey != ""},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return database.Job{}, ErrDuplicate
+	}
+	return job, err
+}
+
+// permanentError marks an error that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job fails straight away instead of being
+// retried.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
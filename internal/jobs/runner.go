@@ -0,0 +1,284 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type handlerFunc func(ctx context.Context, payload []byte) error
+
+type scheduled struct {
+	kind    string
+	spec    Schedule
+	payload any
+	next    time.Time
+}
+
+// Runner claims jobs of the kinds it has handlers for and runs them, up to
+// the configured concurrency at a time.
+type Runner struct {
+	cfg       *config.APIConfig
+	handlers  map[string]handlerFunc
+	schedules []*scheduled
+
+	mu      sync.Mutex
+	running map[int64]context.CancelFunc
+	wg      sync.WaitGroup
+	// Signalled when a job finishes, so its slot is filled without waiting
+	// for the next poll.
+	finished chan struct{}
+}
+
+func NewRunner(cfg *config.APIConfig) *Runner {
+	return &Runner{
+		cfg:      cfg,
+		handlers: map[string]handlerFunc{},
+		running:  map[int64]context.CancelFunc{},
+		finished: make(chan struct{}, 1),
+	}
+}
+
+// Register makes r run jobs of kind with fn, decoding their payload into T.
+// Jobs whose payload does not decode fail without being retried. Register
+// must be called before Run.
+func Register[T any](r *Runner, kind string, fn func(ctx context.Context, payload T) error) {
+	r.handlers[kind] = func(ctx context.Context, data []byte) error {
+		var payload T
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return Permanent(fmt.Errorf("invalid payload: %w", err))
+		}
+		return fn(ctx, payload)
+	}
+}
+
+// Schedule enqueues a job of kind with payload every time spec comes due.
+// Each run is enqueued once however many servers share the schedule. It must
+// be called before Run.
+func (r *Runner) Schedule(kind string, spec Schedule, payload any) {
+	r.schedules = append(r.schedules, &scheduled{kind: kind, spec: spec, payload: payload})
+}
+
+// Run enqueues scheduled jobs and runs due ones until ctx is done. It then
+// stops claiming jobs and waits for running ones, interrupting and requeueing
+// those that outlast the drain timeout, before it returns.
+func (r *Runner) Run(ctx context.Context) {
+	// Jobs get a context of their own so shutdown lets them finish rather
+	// than cancelling them straight away.
+	jobCtx, interrupt := context.WithCancel(context.WithoutCancel(ctx))
+	defer interrupt()
+
+	now := time.Now()
+	for _, s := range r.schedules {
+		s.next = s.spec.Next(now)
+	}
+
+	poll := time.NewTicker(r.cfg.Jobs.PollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(r.cfg.Jobs.StaleAfter / 4)
+	defer heartbeat.Stop()
+
+	for {
+		r.enqueueDue(ctx)
+		if err := r.claim(ctx, jobCtx); err != nil && ctx.Err() == nil {
+			log.Printf("Error claiming jobs: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			r.drain(interrupt)
+			return
+		case <-poll.C:
+		case <-r.finished:
+		case <-heartbeat.C:
+			if err := r.heartbeat(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Error sending job heartbeats: %v", err)
+			}
+		}
+	}
+}
+
+// enqueueDue enqueues the runs of schedules that have come due.
+func (r *Runner) enqueueDue(ctx context.Context) {
+	now := time.Now()
+
+	for _, s := range r.schedules {
+		if s.next.IsZero() || now.Before(s.next) {
+			continue
+		}
+
+		_, err := Enqueue(ctx, r.cfg.DB, s.kind, s.payload, Options{
+			RunAt:     s.next,
+			UniqueKey: s.kind + "@" + s.next.Format(time.RFC3339),
+		})
+		if err != nil && !errors.Is(err, ErrDuplicate) {
+			// Try again on the next poll
+			log.Printf("Error enqueueing scheduled %s job: %v", s.kind, err)
+			continue
+		}
+		s.next = s.spec.Next(now)
+	}
+}
+
+// claim fills the free worker slots with due jobs.
+func (r *Runner) claim(ctx context.Context, jobCtx context.Context) error {
+	r.mu.Lock()
+	free := r.cfg.Jobs.Concurrency - len(r.running)
+	r.mu.Unlock()
+	if free <= 0 || len(r.handlers) == 0 {
+		return nil
+	}
+
+	jobs, err := r.cfg.DB.ClaimJobs(ctx, database.ClaimJobsParams{
+		Kinds:       slices.Sorted(maps.Keys(r.handlers)),
+		StaleBefore: pgtype.Timestamptz{Time: time.Now().Add(-r.cfg.Jobs.StaleAfter), Valid: true},
+		MaxJobs:     int32(free),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		runCtx, cancel := context.WithCancel(jobCtx)
+		r.mu.Lock()
+		r.running[job.ID] = cancel
+		r.mu.Unlock()
+
+		r.wg.Add(1)
+		go r.execute(runCtx, jobCtx, job)
+	}
+	return nil
+}
+
+// execute runs job and records the outcome. runCtx is cancelled when the job
+// is cancelled through the admin API, jobCtx when shutdown interrupts it.
+func (r *Runner) execute(runCtx, jobCtx context.Context, job database.Job) {
+	defer func() {
+		r.mu.Lock()
+		r.running[job.ID]()
+		delete(r.running, job.ID)
+		r.mu.Unlock()
+
+		r.wg.Done()
+		select {
+		case r.finished <- struct{}{}:
+		default:
+		}
+	}()
+
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// Only jobs claimed back from a worker that died get here
+		err = Permanent(errors.New("worker stopped responding"))
+	} else {
+		err = call(runCtx, r.handlers[job.Kind], job.Payload)
+	}
+
+	// Record the outcome even though the job was interrupted.
+	ctx := context.WithoutCancel(runCtx)
+	switch {
+	case err == nil:
+		err = r.cfg.DB.CompleteJob(ctx, job.ID)
+	case jobCtx.Err() != nil:
+		log.Printf("Job %d (%s) interrupted by shutdown, requeueing it", job.ID, job.Kind)
+		err = r.cfg.DB.ReleaseJob(ctx, job.ID)
+	default:
+		err = r.fail(ctx, job, err)
+	}
+	if err != nil {
+		log.Printf("Error recording outcome of job %d (%s): %v", job.ID, job.Kind, err)
+	}
+}
+
+// fail records a failed attempt, retrying the job with backoff while it has
+// attempts left. Jobs cancelled meanwhile are left cancelled.
+func (r *Runner) fail(ctx context.Context, job database.Job, jobErr error) error {
+	status := StatusQueued
+	runAt := time.Now().Add(outbox.Backoff(int(job.Attempts)))
+
+	var permanent permanentError
+	if job.Attempts >= job.MaxAttempts || errors.As(jobErr, &permanent) {
+		status = StatusFailed
+		runAt = time.Now()
+		log.Printf("Job %d (%s) failed after %d attempts: %v", job.ID, job.Kind, job.Attempts, jobErr)
+	}
+
+	return r.cfg.DB.FailJob(ctx, database.FailJobParams{
+		ID:        job.ID,
+		Status:    status,
+		LastError: pgtype.Text{String: jobErr.Error(), Valid: true},
+		RunAt:     pgtype.Timestamptz{Time: runAt, Valid: true},
+	})
+}
+
+func call(ctx context.Context, fn handlerFunc, payload []byte) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return fn(ctx, payload)
+}
+
+// heartbeat keeps the running jobs from being claimed by another server,
+// and stops those that were cancelled through the admin API.
+func (r *Runner) heartbeat(ctx context.Context) error {
+	r.mu.Lock()
+	ids := slices.Collect(maps.Keys(r.running))
+	r.mu.Unlock()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	running, err := r.cfg.DB.TouchJobs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		if cancel, ok := r.running[id]; ok && !slices.Contains(running, id) {
+			cancel()
+		}
+	}
+	return nil
+}
+
+// drain waits for running jobs to finish, interrupting them once the drain
+// timeout has passed.
+func (r *Runner) drain(interrupt context.CancelFunc) {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(r.cfg.Jobs.DrainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	r.mu.Lock()
+	log.Printf("Interrupting %d jobs still running after %s", len(r.running), r.cfg.Jobs.DrainTimeout)
+	r.mu.Unlock()
+
+	interrupt()
+	<-done
+}
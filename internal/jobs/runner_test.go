@@ -0,0 +1,210 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database/dbtest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// table emulates the jobs table for the queries of the runner.
+type table struct {
+	mu   sync.Mutex
+	rows []*database.Job
+}
+
+func (t *table) job(id int64) database.Job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return *t.rows[id-1]
+}
+
+func (t *table) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.rows)
+}
+
+// due makes job id due now.
+func (t *table) due(id int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rows[id-1].RunAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+}
+
+func (t *table) db() *dbtest.DB {
+	db := dbtest.New()
+	db.Handle("CreateJob", func(args ...any) ([][]any, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		uniqueKey := args[4].(pgtype.Text)
+		for _, row := range t.rows {
+			if uniqueKey.Valid && row.UniqueKey == uniqueKey {
+				return nil, nil
+			}
+		}
+		job := &database.Job{
+			ID:          int64(len(t.rows) + 1),
+			Kind:        args[0].(string),
+			Payload:     args[1].([]byte),
+			Status:      StatusQueued,
+			MaxAttempts: args[2].(int32),
+			RunAt:       args[3].(pgtype.Timestamptz),
+			UniqueKey:   uniqueKey,
+		}
+		t.rows = append(t.rows, job)
+		return dbtest.Rows(*job), nil
+	})
+	db.Handle("ClaimJobs", func(args ...any) ([][]any, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		var claimed []database.Job
+		for _, row := range t.rows {
+			if row.Status == StatusQueued && !row.RunAt.Time.After(time.Now()) && len(claimed) < int(args[2].(int32)) {
+				row.Status = StatusRunning
+				row.Attempts++
+				claimed = append(claimed, *row)
+			}
+		}
+		return dbtest.Rows(claimed...), nil
+	})
+	db.Handle("CompleteJob", func(args ...any) ([][]any, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.rows[args[0].(int64)-1].Status = StatusSucceeded
+		return [][]any{nil}, nil
+	})
+	db.Handle("FailJob", func(args ...any) ([][]any, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		row := t.rows[args[0].(int64)-1]
+		row.Status = args[1].(string)
+		row.LastError = args[2].(pgtype.Text)
+		row.RunAt = args[3].(pgtype.Timestamptz)
+		return [][]any{nil}, nil
+	})
+	return db
+}
+
+func newRunner(t *table) *Runner {
+	return NewRunner(&config.APIConfig{
+		DB:   database.New(t.db()),
+		Jobs: config.JobsConfig{Concurrency: 2},
+	})
+}
+
+func TestDueSchedulesAreEnqueuedOnce(t *testing.T) {
+	ctx := context.Background()
+	spec, err := ParseSchedule("@hourly")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two servers share the schedule, whose last run came due within the hour
+	table := &table{}
+	due := spec.Next(time.Now().Add(-time.Hour))
+	servers := []*Runner{newRunner(table), newRunner(table)}
+	for _, r := range servers {
+		r.Schedule("report", spec, map[string]string{"period": "hourly"})
+		r.schedules[0].next = due
+	}
+
+	for range 2 {
+		for _, r := range servers {
+			r.enqueueDue(ctx)
+		}
+	}
+	if n := table.len(); n != 1 {
+		t.Fatalf("enqueued %d jobs, want 1", n)
+	}
+	job := table.job(1)
+	if job.Kind != "report" || !job.RunAt.Time.Equal(due) || job.UniqueKey.String != "report@"+due.Format(time.RFC3339) {
+		t.Errorf("enqueued %s at %s with key %q, want the due run", job.Kind, job.RunAt.Time, job.UniqueKey.String)
+	}
+
+	// Each server moves on to the next run
+	for i, r := range servers {
+		if next := r.schedules[0].next; !next.After(time.Now()) {
+			t.Errorf("server %d: next run at %s, want one to come", i+1, next)
+		}
+	}
+}
+
+// run claims the due jobs of r and waits for them to finish.
+func run(t *testing.T, r *Runner) {
+	t.Helper()
+	ctx := context.Background()
+	if err := r.claim(ctx, ctx); err != nil {
+		t.Fatal(err)
+	}
+	r.wg.Wait()
+}
+
+func TestFailedJobsAreRetried(t *testing.T) {
+	ctx := context.Background()
+	table := &table{}
+	r := newRunner(table)
+	calls := 0
+	Register(r, "email", func(ctx context.Context, payload struct{ To string }) error {
+		calls++
+		if calls == 1 {
+			return errors.New("mail server unavailable")
+		}
+		return nil
+	})
+	if _, err := Enqueue(ctx, r.cfg.DB, "email", struct{ To string }{"jane@example.com"}, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	run(t, r)
+	job := table.job(1)
+	if job.Status != StatusQueued || job.LastError.String != "mail server unavailable" || !job.RunAt.Time.After(time.Now()) {
+		t.Fatalf("after failing: status %s, error %q, run at %s, want queued for later", job.Status, job.LastError.String, job.RunAt.Time)
+	}
+
+	// Not before its backoff has passed
+	run(t, r)
+	if calls != 1 {
+		t.Fatalf("ran %d times before the backoff passed, want 1", calls)
+	}
+
+	table.due(1)
+	run(t, r)
+	if job := table.job(1); job.Status != StatusSucceeded || job.Attempts != 2 {
+		t.Fatalf("after the retry: status %s after %d attempts, want succeeded after 2", job.Status, job.Attempts)
+	}
+}
+
+func TestJobsFailAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	table := &table{}
+	r := newRunner(table)
+	Register(r, "email", func(context.Context, struct{}) error {
+		return errors.New("mail server unavailable")
+	})
+	Register(r, "sms", func(context.Context, struct{}) error {
+		return Permanent(errors.New("no such number"))
+	})
+	Enqueue(ctx, r.cfg.DB, "email", struct{}{}, Options{MaxAttempts: 2})
+	Enqueue(ctx, r.cfg.DB, "sms", struct{}{}, Options{})
+	// A payload that does not decode fails without being retried
+	Enqueue(ctx, r.cfg.DB, "sms", "not an object", Options{})
+
+	run(t, r)
+	table.due(1)
+	run(t, r)
+	for id := int64(1); id <= 3; id++ {
+		if job := table.job(id); job.Status != StatusFailed {
+			t.Errorf("job %d (%s): %s after %d attempts, want failed", id, job.Kind, job.Status, job.Attempts)
+		}
+	}
+	if job := table.job(1); job.Attempts != 2 {
+		t.Errorf("email job failed after %d attempts, want 2", job.Attempts)
+	}
+}
@@ -0,0 +1,123 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func at(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestScheduleNext(t *testing.T) {
+	// 2024-01-01 is a Monday
+	tests := []struct {
+		spec string
+		from string
+		want []string
+	}{
+		{"@hourly", "2024-01-01 10:30", []string{"2024-01-01 11:00", "2024-01-01 12:00"}},
+		{"@daily", "2024-01-01 10:30", []string{"2024-01-02 00:00", "2024-01-03 00:00"}},
+		{"@midnight", "2024-01-01 00:00", []string{"2024-01-02 00:00"}},
+		{"@weekly", "2024-01-01 10:30", []string{"2024-01-07 00:00", "2024-01-14 00:00"}},
+		{"@monthly", "2024-01-15 10:30", []string{"2024-02-01 00:00", "2024-03-01 00:00"}},
+		{"@yearly", "2024-01-01 00:00", []string{"2025-01-01 00:00"}},
+		{"@annually", "2024-06-01 00:00", []string{"2025-01-01 00:00"}},
+		{"@every 15m", "2024-01-01 10:07", []string{"2024-01-01 10:15", "2024-01-01 10:30"}},
+		{"@every 1h", "2024-01-01 10:00", []string{"2024-01-01 11:00", "2024-01-01 12:00"}},
+
+		// Lists, ranges and steps
+		{"0,30 9-10 * * *", "2024-01-01 08:00", []string{"2024-01-01 09:00", "2024-01-01 09:30", "2024-01-01 10:00", "2024-01-01 10:30", "2024-01-02 09:00"}},
+		{"*/20 * * * *", "2024-01-01 10:05", []string{"2024-01-01 10:20", "2024-01-01 10:40", "2024-01-01 11:00"}},
+		{"10-30/10 * * * *", "2024-01-01 10:00", []string{"2024-01-01 10:10", "2024-01-01 10:20", "2024-01-01 10:30", "2024-01-01 11:10"}},
+		// A value with a step runs from the value to the end of the field
+		{"45/5 * * * *", "2024-01-01 10:00", []string{"2024-01-01 10:45", "2024-01-01 10:50", "2024-01-01 10:55", "2024-01-01 11:45"}},
+		{"0 0 1 */3 *", "2024-01-15 00:00", []string{"2024-04-01 00:00", "2024-07-01 00:00"}},
+
+		// Sunday is both 0 and 7
+		{"0 12 * * 7", "2024-01-01 00:00", []string{"2024-01-07 12:00", "2024-01-14 12:00"}},
+		{"0 12 * * 0", "2024-01-01 00:00", []string{"2024-01-07 12:00", "2024-01-14 12:00"}},
+		{"0 0 * * 5-7", "2024-01-01 00:00", []string{"2024-01-05 00:00", "2024-01-06 00:00", "2024-01-07 00:00", "2024-01-12 00:00"}},
+
+		// With one day field restricted, only that one counts
+		{"0 0 * * 1", "2024-01-01 00:00", []string{"2024-01-08 00:00", "2024-01-15 00:00"}},
+		{"0 0 13 * *", "2024-01-01 00:00", []string{"2024-01-13 00:00", "2024-02-13 00:00"}},
+		// With both restricted, either one matches: Fridays and the 13th
+		{"0 0 13 * 5", "2024-01-01 00:00", []string{"2024-01-05 00:00", "2024-01-12 00:00", "2024-01-13 00:00", "2024-01-19 00:00"}},
+		// */2 restricts the day of month to odd days, so Mondays match as
+		// well rather than only the odd ones
+		{"0 0 */2 * 1", "2024-01-01 00:00", []string{"2024-01-03 00:00", "2024-01-05 00:00", "2024-01-07 00:00", "2024-01-08 00:00", "2024-01-09 00:00"}},
+
+		// Leap days, and days that never come
+		{"0 0 29 2 *", "2024-03-01 00:00", []string{"2028-02-29 00:00"}},
+		{"0 0 31 2 *", "2024-01-01 00:00", nil},
+		{"0 0 31 4,6,9,11 *", "2024-01-01 00:00", nil},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("%s: %v", tt.spec, err)
+			continue
+		}
+
+		next := s.Next(at(tt.from))
+		for _, want := range tt.want {
+			if !next.Equal(at(want)) {
+				t.Errorf("%s: got %s, want %s", tt.spec, next.Format(time.DateTime), want)
+				break
+			}
+			next = s.Next(next)
+		}
+		if tt.want == nil && !next.IsZero() {
+			t.Errorf("%s: runs at %s, want never", tt.spec, next.Format(time.DateTime))
+		}
+	}
+}
+
+// Times are worked out in UTC whatever the location of the time given: 10:00
+// in Nairobi is 07:00 UTC.
+func TestScheduleNextIsInUTC(t *testing.T) {
+	s, err := ParseSchedule("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nairobi := time.FixedZone("EAT", 3*60*60)
+	from := time.Date(2024, 1, 1, 10, 0, 0, 0, nairobi)
+	if got := s.Next(from); !got.Equal(at("2024-01-01 09:00")) || got.Location() != time.UTC {
+		t.Fatalf("got %s, want 09:00 UTC", got)
+	}
+}
+
+func TestParseScheduleRejects(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"@sometimes",
+		"@every",
+		"@every often",
+		"@every 500ms",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/-1 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"1-2-3 * * * *",
+		"1,,2 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("%q parsed, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package loadshed
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+)
+
+var (
+	// ErrQueueFull is returned when the queue is full of requests of the
+	// same priority or higher.
+	ErrQueueFull = errors.New("queue full")
+	// ErrEvicted is returned to a queued request that made room for one of
+	// a higher priority.
+	ErrEvicted = errors.New("evicted")
+	// ErrWaitTimeout is returned when no slot freed up before the context
+	// of the request was done.
+	ErrWaitTimeout = errors.New("wait timeout")
+)
+
+// shares is the part of the limit the requests of each priority may fill.
+var shares = map[config.Priority]float64{
+	config.PriorityRead:  1,
+	config.PriorityWrite: 0.8,
+	config.PriorityBulk:  0.5,
+}
+
+// rank orders the queue: the higher the rank, the earlier a request gets a
+// slot, and the later it is evicted.
+var rank = map[config.Priority]int{
+	config.PriorityRead:  2,
+	config.PriorityWrite: 1,
+	config.PriorityBulk:  0,
+}
+
+// Limiter limits how many requests run at once. Requests over the limit
+// wait in a bounded queue, by priority, for a slot. Unless the mode is
+// static, the limit follows the latency of the requests, between the
+// minimum and the limit it started at.
+type Limiter struct {
+	mode      string
+	min, max  float64
+	target    time.Duration
+	queueSize int
+
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	queue    []*waiter
+	// lastDecrease keeps aimd from lowering the limit for every slow
+	// request of one burst.
+	lastDecrease time.Time
+	// usual is the moving average of the latency, for gradient.
+	usual float64
+}
+
+type waiter struct {
+	priority config.Priority
+	ready    chan struct{}
+	err      error
+}
+
+func NewLimiter(cfg config.LimitConfig, limit int) *Limiter {
+	return &Limiter{
+		mode:      cfg.Mode,
+		min:       float64(cfg.MinLimit),
+		max:       float64(limit),
+		target:    cfg.TargetLatency,
+		queueSize: cfg.QueueSize,
+		limit:     float64(limit),
+	}
+}
+
+// fits reports whether a request of priority p may start now.
+func (l *Limiter) fits(p config.Priority) bool {
+	return float64(l.inflight) < max(1, math.Floor(l.limit*shares[p]))
+}
+
+// Acquire takes a slot for a request of priority p, waiting in the queue
+// until ctx is done if there is none. Critical requests always get one.
+// Every Acquire that returns nil must be followed by a Release.
+func (l *Limiter) Acquire(ctx context.Context, p config.Priority) error {
+	l.mu.Lock()
+	if p == config.PriorityCritical || (l.fits(p) && !l.queuedAhead(p)) {
+		l.inflight++
+		l.mu.Unlock()
+		return nil
+	}
+
+	if len(l.queue) >= l.queueSize {
+		last := l.queue[len(l.queue)-1]
+		if rank[last.priority] >= rank[p] {
+			l.mu.Unlock()
+			return ErrQueueFull
+		}
+		l.queue = l.queue[:len(l.queue)-1]
+		last.err = ErrEvicted
+		close(last.ready)
+	}
+	w := &waiter{priority: p, ready: make(chan struct{})}
+	i := len(l.queue)
+	for i > 0 && rank[l.queue[i-1].priority] < rank[p] {
+		i--
+	}
+	l.queue = append(l.queue[:i], append([]*waiter{w}, l.queue[i:]...)...)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return w.err
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-w.ready:
+		// A slot came, or the request was evicted, just as it gave up
+		return w.err
+	default:
+	}
+	for i, queued := range l.queue {
+		if queued == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			break
+		}
+	}
+	return ErrWaitTimeout
+}
+
+// queuedAhead reports whether a queued request would get a slot before one
+// of priority p.
+func (l *Limiter) queuedAhead(p config.Priority) bool {
+	return len(l.queue) > 0 && rank[l.queue[0].priority] >= rank[p]
+}
+
+// Release gives back a slot, to the first queued request that fits.
+func (l *Limiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	l.admit()
+}
+
+func (l *Limiter) admit() {
+	// The queue is in order of priority, and a request of a lower priority
+	// fits no sooner than one of a higher, so the first is the only one to
+	// look at
+	for len(l.queue) > 0 && l.fits(l.queue[0].priority) {
+		w := l.queue[0]
+		l.queue = l.queue[1:]
+		l.inflight++
+		close(w.ready)
+	}
+}
+
+// Observe adapts the limit to the latency of a finished request. A dropped
+// request, one that timed out, counts as slow whatever its latency.
+func (l *Limiter) Observe(latency time.Duration, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch l.mode {
+	case "aimd":
+		// Lower the limit by a tenth when requests get slow, and raise it
+		// by about one for every limit's worth of fast requests while it is
+		// in use
+		if dropped || latency > l.target {
+			if time.Since(l.lastDecrease) > l.target {
+				l.limit = max(l.min, l.limit*0.9)
+				l.lastDecrease = time.Now()
+			}
+		} else if float64(l.inflight) >= l.limit/2 {
+			l.limit = min(l.max, l.limit+1/l.limit)
+		}
+	case "gradient":
+		// Scale the limit by how much slower the latest request was than
+		// usual, leaving room for a queue of about its square root
+		sample := float64(latency)
+		if l.usual == 0 {
+			l.usual = sample
+		}
+		if dropped {
+			sample = max(sample, 2*l.usual)
+		}
+		l.usual += (sample - l.usual) / 100
+		gradient := max(0.5, min(1, l.usual/max(sample, 1)))
+		next := l.limit*gradient + math.Sqrt(l.limit)
+		l.limit = max(l.min, min(l.max, 0.8*l.limit+0.2*next))
+	}
+	l.admit()
+}
+
+// Stats returns the current limit, and how many requests are running and
+// waiting.
+func (l *Limiter) Stats() (limit float64, inflight, queued int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit, l.inflight, len(l.queue)
+}
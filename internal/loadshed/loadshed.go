@@ -0,0 +1,199 @@
+// Package loadshed limits how many requests each server, and each route of
+// it, runs at once, so a traffic spike is turned away with 503s rather than
+// exhausting the database pool. Requests over the limit wait a short while
+// in a queue, and those of a lower priority are shed first.
+package loadshed
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/metrics"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/route"
+)
+
+var shed = metrics.NewCounter("http_requests_shed_total",
+	"Requests answered with 503 without running, for lack of capacity.",
+	"server", "method", "route", "priority", "reason")
+
+// shedders are every Shedder, for the gauges.
+var shedders struct {
+	sync.Mutex
+	all []*Shedder
+}
+
+func init() {
+	gauge := func(name, help string, value func(l *Limiter) float64) {
+		metrics.NewGaugeFunc(name, help, []string{"server", "scope"}, func(report func(float64, ...string)) {
+			shedders.Lock()
+			defer shedders.Unlock()
+			for _, s := range shedders.all {
+				report(value(s.server), s.name, "server")
+				s.mu.Lock()
+				for key, l := range s.routes {
+					report(value(l), s.name, key)
+				}
+				s.mu.Unlock()
+			}
+		})
+	}
+	gauge("concurrency_limit", "The concurrency limit of each server, and each route by method and pattern.",
+		func(l *Limiter) float64 { limit, _, _ := l.Stats(); return limit })
+	gauge("concurrency_in_flight", "Requests running on each server, and each route by method and pattern.",
+		func(l *Limiter) float64 { _, inflight, _ := l.Stats(); return float64(inflight) })
+	gauge("concurrency_queued", "Requests waiting for a slot on each server, and each route by method and pattern.",
+		func(l *Limiter) float64 { _, _, queued := l.Stats(); return float64(queued) })
+}
+
+// Shedder limits the requests of one server.
+type Shedder struct {
+	cfg    config.LimitConfig
+	name   string
+	server *Limiter
+
+	mu     sync.Mutex
+	routes map[string]*Limiter
+}
+
+func New(cfg config.LimitConfig, server string) *Shedder {
+	s := &Shedder{
+		cfg:    cfg,
+		name:   server,
+		server: NewLimiter(cfg, cfg.ServerLimit),
+		routes: map[string]*Limiter{},
+	}
+	shedders.Lock()
+	shedders.all = append(shedders.all, s)
+	shedders.Unlock()
+	return s
+}
+
+// route returns the limiter of a route, by method and pattern.
+func (s *Shedder) route(key string) *Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.routes[key]
+	if !ok {
+		l = NewLimiter(s.cfg, s.cfg.RouteLimit)
+		s.routes[key] = l
+	}
+	return l
+}
+
+// Middleware runs each request once it has a slot on both its route, which
+// must have matched, and the server, waiting up to the max wait for them.
+// Requests that get none are answered with a 503 and Retry-After.
+func (s *Shedder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pattern := route.Pattern(r)
+		priority := s.cfg.Priority(r.Method, pattern)
+		if priority == config.PriorityUnlimited {
+			next.ServeHTTP(w, r)
+			return
+		}
+		routeLimiter := s.route(r.Method + " " + pattern)
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.cfg.MaxWait)
+		err := routeLimiter.Acquire(ctx, priority)
+		if err == nil {
+			if err = s.server.Acquire(ctx, priority); err != nil {
+				routeLimiter.Release()
+			}
+		}
+		cancel()
+		if err != nil {
+			shed.Inc(s.name, r.Method, pattern, string(priority), reason(err))
+			writeShed(w, err)
+			return
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			s.server.Release()
+			routeLimiter.Release()
+			if priority == config.PriorityRead || priority == config.PriorityWrite {
+				// Health checks are quick and bulk requests slow by nature,
+				// so only reads and writes show how loaded the server is.
+				// The requests that timed out are the ones the limit was
+				// too high for
+				dropped := sw.status == http.StatusServiceUnavailable || sw.status == http.StatusGatewayTimeout
+				latency := time.Since(start)
+				s.server.Observe(latency, dropped)
+				routeLimiter.Observe(latency, dropped)
+			}
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+func reason(err error) string {
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		return "queue_full"
+	case errors.Is(err, ErrEvicted):
+		return "evicted"
+	default:
+		return "wait_timeout"
+	}
+}
+
+func writeShed(w http.ResponseWriter, err error) {
+	detail := "No capacity freed up in time to run the request."
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		detail = "Too many requests are waiting to run."
+	case errors.Is(err, ErrEvicted):
+		detail = "The request made way for one of a higher priority."
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("Retry-After", "1")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(models.Problem{
+		Type:   "about:blank",
+		Title:  "Server overloaded",
+		Status: http.StatusServiceUnavailable,
+		Detail: detail,
+	})
+}
+
+// statusWriter notes the status of a response on its way through.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 && status >= http.StatusOK {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+func (sw *statusWriter) Flush() {
+	http.NewResponseController(sw.ResponseWriter).Flush()
+}
+
+func (sw *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(sw.ResponseWriter).Hijack()
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,174 @@
+// Package metrics keeps the counters and gauges of the servers and serves
+// them in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// registry holds every metric, by name.
+var registry = struct {
+	sync.Mutex
+	families map[string]*family
+}{families: map[string]*family{}}
+
+// family is a metric and the values of each combination of its labels.
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+	// collect, if set, reports the values when the metrics are served
+	// instead, for values kept elsewhere, such as pool statistics.
+	collect func(report func(value float64, labelValues ...string))
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+func register(name, help, kind string, labels []string) *family {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.families[name]; ok {
+		panic("metrics: " + name + " registered twice")
+	}
+	f := &family{name: name, help: help, kind: kind, labels: labels, series: map[string]*series{}}
+	registry.families[name] = f
+	return f
+}
+
+func (f *family) add(delta float64, set bool, labelValues []string) {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: slices.Clone(labelValues)}
+		f.series[key] = s
+	}
+	if set {
+		s.value = delta
+	} else {
+		s.value += delta
+	}
+}
+
+// Counter is a value that only goes up, such as a number of requests.
+type Counter struct{ f *family }
+
+// NewCounter registers a counter with the names of its labels, whose values
+// are given, in the same order, whenever it is counted.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{register(name, help, "counter", labels)}
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.f.add(1, false, labelValues)
+}
+
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	c.f.add(delta, false, labelValues)
+}
+
+// Gauge is a value that goes up and down, such as requests in flight.
+type Gauge struct{ f *family }
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{register(name, help, "gauge", labels)}
+}
+
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.f.add(value, true, labelValues)
+}
+
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.f.add(delta, false, labelValues)
+}
+
+// NewGaugeFunc registers a gauge whose values are reported by collect each
+// time the metrics are served.
+func NewGaugeFunc(name, help string, labels []string, collect func(report func(value float64, labelValues ...string))) {
+	register(name, help, "gauge", labels).collect = collect
+}
+
+// NewCounterFunc registers a counter whose values are reported by collect
+// each time the metrics are served.
+func NewCounterFunc(name, help string, labels []string, collect func(report func(value float64, labelValues ...string))) {
+	register(name, help, "counter", labels).collect = collect
+}
+
+// Handler serves every metric in the Prometheus text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w)
+	})
+}
+
+// Write writes every metric in the Prometheus text format, sorted by name
+// and label values.
+func Write(w io.Writer) {
+	registry.Lock()
+	families := make([]*family, 0, len(registry.families))
+	for _, f := range registry.families {
+		families = append(families, f)
+	}
+	registry.Unlock()
+	slices.SortFunc(families, func(a, b *family) int { return strings.Compare(a.name, b.name) })
+
+	for _, f := range families {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		for _, s := range f.snapshot() {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, f.format(s.labelValues), strconv.FormatFloat(s.value, 'g', -1, 64))
+		}
+	}
+}
+
+func (f *family) snapshot() []series {
+	var all []series
+	if f.collect != nil {
+		f.collect(func(value float64, labelValues ...string) {
+			all = append(all, series{labelValues: labelValues, value: value})
+		})
+	} else {
+		f.mu.Lock()
+		for _, s := range f.series {
+			all = append(all, *s)
+		}
+		f.mu.Unlock()
+	}
+	slices.SortFunc(all, func(a, b series) int { return slices.Compare(a.labelValues, b.labelValues) })
+	return all
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (f *family) format(labelValues []string) string {
+	if len(f.labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(f.labels))
+	for i, label := range f.labels {
+		value := ""
+		if i < len(labelValues) {
+			value = labelValues[i]
+		}
+		pairs[i] = label + `="` + labelEscaper.Replace(value) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
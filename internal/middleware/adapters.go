@@ -0,0 +1,339 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/route"
+	"github.com/gin-gonic/gin"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/mux"
+	"github.com/julienschmidt/httprouter"
+	"github.com/labstack/echo/v4"
+)
+
+// Endpoint is a route registered through ServeMux or HttpRouter, with its
+// path in the OpenAPI style of the route package.
+type Endpoint struct {
+	Method string
+	Path   string
+}
+
+// ServeMux registers handlers on an http.ServeMux with the chain around
+// each, so it runs once the route has matched and r.Pattern and the path
+// values are set. It records the routes it registers, since ServeMux cannot
+// list them.
+type ServeMux struct {
+	*http.ServeMux
+	chain  Chain
+	routes []Endpoint
+}
+
+func NewServeMux(m *http.ServeMux, chain Chain) *ServeMux {
+	return &ServeMux{ServeMux: m, chain: chain}
+}
+
+// Routes returns the routes registered so far, in order. Patterns without a
+// method have an empty one.
+func (m *ServeMux) Routes() []Endpoint {
+	return slices.Clone(m.routes)
+}
+
+func (m *ServeMux) Handle(pattern string, h http.Handler) {
+	path := route.FromServeMux(pattern)
+	method, _, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method = ""
+	}
+	m.routes = append(m.routes, Endpoint{Method: method, Path: path})
+	names := route.Names(path)
+	h = m.chain.Then(h)
+	m.ServeMux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := make([]route.Param, len(names))
+		for i, name := range names {
+			params[i] = route.Param{Name: name, Value: r.PathValue(name)}
+		}
+		h.ServeHTTP(w, route.Set(r, route.Route{Pattern: path, Params: params}))
+	}))
+}
+
+func (m *ServeMux) HandleFunc(pattern string, h func(http.ResponseWriter, *http.Request)) {
+	m.Handle(pattern, http.HandlerFunc(h))
+}
+
+// HttpRouter registers handles on an httprouter.Router with the chain
+// around each. The path parameters are in the request context for the
+// chain, where httprouter.ParamsFromContext finds them. It records the
+// routes it registers, since httprouter cannot list them.
+type HttpRouter struct {
+	*httprouter.Router
+	chain  Chain
+	routes []Endpoint
+}
+
+func NewHttpRouter(r *httprouter.Router, chain Chain) *HttpRouter {
+	return &HttpRouter{Router: r, chain: chain}
+}
+
+// Routes returns the routes registered so far, in order, along with those
+// of the handles returned by Route.
+func (r *HttpRouter) Routes() []Endpoint {
+	return slices.Clone(r.routes)
+}
+
+func (r *HttpRouter) Handle(method, path string, handle httprouter.Handle) {
+	r.Router.Handle(method, path, r.Route(method, path, handle))
+}
+
+func (r *HttpRouter) GET(path string, handle httprouter.Handle) {
+	r.Handle(http.MethodGet, path, handle)
+}
+
+func (r *HttpRouter) POST(path string, handle httprouter.Handle) {
+	r.Handle(http.MethodPost, path, handle)
+}
+
+func (r *HttpRouter) PUT(path string, handle httprouter.Handle) {
+	r.Handle(http.MethodPut, path, handle)
+}
+
+func (r *HttpRouter) PATCH(path string, handle httprouter.Handle) {
+	r.Handle(http.MethodPatch, path, handle)
+}
+
+func (r *HttpRouter) DELETE(path string, handle httprouter.Handle) {
+	r.Handle(http.MethodDelete, path, handle)
+}
+
+// Route wraps handle in the chain as the route of method and path, and
+// records the route, without registering it, for a handle that is
+// dispatched to from another.
+func (r *HttpRouter) Route(method, path string, handle httprouter.Handle) httprouter.Handle {
+	r.routes = append(r.routes, Endpoint{Method: method, Path: route.FromColon(path)})
+	return HttpRouterHandle(r.chain, path, handle)
+}
+
+// HttpRouterHandle wraps a single httprouter handle of the route path in
+// chain. httprouter does not tell a handle which route it was registered
+// for, so it is given here.
+func HttpRouterHandle(chain Chain, path string, handle httprouter.Handle) httprouter.Handle {
+	path = route.FromColon(path)
+	h := chain.Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handle(w, r, httprouter.ParamsFromContext(r.Context()))
+	}))
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		params := make([]route.Param, len(ps))
+		for i, p := range ps {
+			params[i] = route.Param{Name: p.Key, Value: p.Value}
+		}
+		r = route.Set(r, route.Route{Pattern: path, Params: params})
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), httprouter.ParamsKey, ps)))
+	}
+}
+
+// Mux installs chain with mux.Router.Use, which runs it once a route has
+// matched and mux.Vars and mux.CurrentRoute work.
+func Mux(chain Chain) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		h := chain.Then(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					path := route.FromTemplate(template)
+					vars := mux.Vars(r)
+					var params []route.Param
+					for _, name := range route.Names(path) {
+						params = append(params, route.Param{Name: name, Value: vars[name]})
+					}
+					r = route.Set(r, route.Route{Pattern: path, Params: params})
+				}
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Chi returns chain as chi middleware. Installed with chi.Router.With, it
+// runs once a route has matched and chi.URLParam and the route pattern
+// work; with chi.Router.Use it would run before routing.
+func Chi(chain Chain) []func(http.Handler) http.Handler {
+	middlewares := []func(http.Handler) http.Handler{chiRoute}
+	for _, m := range chain {
+		middlewares = append(middlewares, m)
+	}
+	return middlewares
+}
+
+func chiRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			params := make([]route.Param, len(rctx.URLParams.Keys))
+			for i, name := range rctx.URLParams.Keys {
+				params[i] = route.Param{Name: name, Value: rctx.URLParams.Values[i]}
+			}
+			r = route.Set(r, route.Route{Pattern: route.FromTemplate(rctx.RoutePattern()), Params: params})
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Echo returns chain as echo middleware, for echo.Echo.Use, which runs it
+// once a route has matched and c.Param and c.Path work. The chain gets the
+// request and response writer of the context, and hands its own on to the
+// handler. An error the handler returns is turned into a response within
+// the chain, so that the response passes through it as well.
+func Echo(chain Chain) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !echoMatched(c) {
+				return next(c)
+			}
+			names, values := c.ParamNames(), c.ParamValues()
+			params := make([]route.Param, len(names))
+			for i, name := range names {
+				params[i] = route.Param{Name: name, Value: values[i]}
+			}
+			c.SetRequest(route.Set(c.Request(), route.Route{Pattern: route.FromColon(c.Path()), Params: params}))
+			chain.Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c.SetRequest(r)
+				c.SetResponse(echo.NewResponse(w, c.Echo()))
+				if err := next(c); err != nil {
+					c.Error(err)
+				}
+			})).ServeHTTP(c.Response(), c.Request())
+			return nil
+		}
+	}
+}
+
+// echoMatched reports whether a route matched the request of c, as echo
+// runs its middleware for unknown routes and methods too.
+func echoMatched(c echo.Context) bool {
+	if c.Get(echo.ContextKeyHeaderAllow) != nil {
+		// A route matched the path, but not the method
+		return false
+	}
+	return reflect.ValueOf(c.Handler()).Pointer() != reflect.ValueOf(echo.NotFoundHandler).Pointer()
+}
+
+// Gin returns chain as gin middleware, for gin.Engine.Use, which runs it
+// once a route has matched and c.Param and c.FullPath work. The chain gets
+// the request and response writer of the context, and hands its own on to
+// the rest of the handlers. If the chain answers the request itself, they
+// are skipped.
+func Gin(chain Chain) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.FullPath() == "" {
+			// Gin runs its middleware for unknown routes too
+			c.Next()
+			return
+		}
+		original := c.Writer
+		called := false
+		params := make([]route.Param, len(c.Params))
+		for i, p := range c.Params {
+			params[i] = route.Param{Name: p.Key, Value: p.Value}
+		}
+		c.Request = route.Set(c.Request, route.Route{Pattern: route.FromColon(c.FullPath()), Params: params})
+		chain.Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			c.Request = r
+			writer := newGinWriter(original, w)
+			c.Writer = writer
+			c.Next()
+			// As gin does once the handlers return, so that a status set
+			// without a body reaches the chain
+			writer.WriteHeaderNow()
+		})).ServeHTTP(original, c.Request)
+		c.Writer = original
+
+		if !called {
+			c.Abort()
+		}
+	}
+}
+
+// ginWriter is a gin.ResponseWriter writing to the response writer the
+// chain handed on, following the gin rules for when the header is sent.
+type ginWriter struct {
+	gin.ResponseWriter
+	w      http.ResponseWriter
+	status int
+	size   int
+}
+
+func newGinWriter(original gin.ResponseWriter, w http.ResponseWriter) *ginWriter {
+	return &ginWriter{ResponseWriter: original, w: w, status: http.StatusOK, size: -1}
+}
+
+func (g *ginWriter) Header() http.Header {
+	return g.w.Header()
+}
+
+func (g *ginWriter) WriteHeader(code int) {
+	if code > 0 && !g.Written() {
+		g.status = code
+	}
+}
+
+func (g *ginWriter) WriteHeaderNow() {
+	if !g.Written() {
+		g.size = 0
+		g.w.WriteHeader(g.status)
+	}
+}
+
+func (g *ginWriter) Write(b []byte) (int, error) {
+	g.WriteHeaderNow()
+	n, err := g.w.Write(b)
+	g.size += n
+	return n, err
+}
+
+func (g *ginWriter) WriteString(s string) (int, error) {
+	g.WriteHeaderNow()
+	n, err := io.WriteString(g.w, s)
+	g.size += n
+	return n, err
+}
+
+func (g *ginWriter) Status() int {
+	return g.status
+}
+
+func (g *ginWriter) Size() int {
+	return g.size
+}
+
+func (g *ginWriter) Written() bool {
+	return g.size != -1
+}
+
+func (g *ginWriter) Flush() {
+	g.WriteHeaderNow()
+	http.NewResponseController(g.w).Flush()
+}
+
+func (g *ginWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if g.size < 0 {
+		g.size = 0
+	}
+	return http.NewResponseController(g.w).Hijack()
+}
+
+func (g *ginWriter) Pusher() http.Pusher {
+	if pusher, ok := g.w.(http.Pusher); ok {
+		return pusher
+	}
+	return nil
+}
+
+func (g *ginWriter) Unwrap() http.ResponseWriter {
+	return g.w
+}
@@ -0,0 +1,291 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/route"
+	"github.com/gin-gonic/gin"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/mux"
+	"github.com/julienschmidt/httprouter"
+	"github.com/labstack/echo/v4"
+)
+
+// handle is a handler of GET /users/{id}, given the id as the router has it.
+type handle func(w http.ResponseWriter, r *http.Request, id string)
+
+// routers installs chain in each of the six routers, as their adapters do,
+// around the route GET /users/{id} served by h.
+var routers = map[string]func(chain Chain, h handle) http.Handler{
+	"standard": func(chain Chain, h handle) http.Handler {
+		m := NewServeMux(http.NewServeMux(), chain)
+		m.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			h(w, r, r.PathValue("id"))
+		})
+		return m
+	},
+	"httprouter": func(chain Chain, h handle) http.Handler {
+		router := NewHttpRouter(httprouter.New(), chain)
+		router.GET("/users/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+			h(w, r, ps.ByName("id"))
+		})
+		return router
+	},
+	"mux": func(chain Chain, h handle) http.Handler {
+		router := mux.NewRouter()
+		router.Use(Mux(chain))
+		router.HandleFunc("/users/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+			h(w, r, mux.Vars(r)["id"])
+		}).Methods(http.MethodGet)
+		return router
+	},
+	"chi": func(chain Chain, h handle) http.Handler {
+		router := chi.NewRouter()
+		router.With(Chi(chain)...).Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			h(w, r, chi.URLParam(r, "id"))
+		})
+		return router
+	},
+	"echo": func(chain Chain, h handle) http.Handler {
+		e := echo.New()
+		e.Use(Echo(chain))
+		e.GET("/users/:id", func(c echo.Context) error {
+			h(c.Response(), c.Request(), c.Param("id"))
+			return nil
+		})
+		return e
+	},
+	"gin": func(chain Chain, h handle) http.Handler {
+		gin.SetMode(gin.TestMode)
+		g := gin.New()
+		g.Use(Gin(chain))
+		g.GET("/users/:id", func(c *gin.Context) {
+			h(c.Writer, c.Request, c.Param("id"))
+		})
+		return g
+	},
+}
+
+// layer is middleware adding to trace when the request reaches it, with
+// the route it sees, and when the response leaves it, with the status.
+func layer(name string, trace *[]string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*trace = append(*trace, name+" "+route.Pattern(r)+" id="+r.PathValue("id"))
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+			if sw.status == 0 {
+				sw.status = http.StatusOK
+			}
+			*trace = append(*trace, name+" "+http.StatusText(sw.status))
+		})
+	}
+}
+
+func TestChainRunsInOrderOnEveryRouter(t *testing.T) {
+	for name, router := range routers {
+		var trace []string
+		chain := Chain{layer("outer", &trace), layer("inner", &trace)}
+		h := router(chain, func(w http.ResponseWriter, r *http.Request, id string) {
+			rt, _ := route.FromRequest(r)
+			trace = append(trace, "handler "+rt.Pattern+" id="+id+" param="+rt.Param("id"))
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+
+		want := []string{
+			"outer /users/{id} id=7",
+			"inner /users/{id} id=7",
+			"handler /users/{id} id=7 param=7",
+			"inner Accepted",
+			"outer Accepted",
+		}
+		if !slices.Equal(trace, want) {
+			t.Errorf("%s: ran\n\t%q\nwant\n\t%q", name, trace, want)
+		}
+		if w.Code != http.StatusAccepted {
+			t.Errorf("%s: answered %d, want 202", name, w.Code)
+		}
+	}
+}
+
+// Middleware that answers the request itself keeps the handler from
+// running.
+func TestChainCanStopTheRequest(t *testing.T) {
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "denied", http.StatusForbidden)
+		})
+	}
+	for name, router := range routers {
+		var trace []string
+		h := router(Chain{layer("outer", &trace), deny}, func(w http.ResponseWriter, r *http.Request, id string) {
+			trace = append(trace, "handler")
+			w.WriteHeader(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+
+		want := []string{"outer /users/{id} id=7", "outer Forbidden"}
+		if !slices.Equal(trace, want) {
+			t.Errorf("%s: ran %q, want %q", name, trace, want)
+		}
+		if w.Code != http.StatusForbidden || w.Body.String() != "denied\n" {
+			t.Errorf("%s: answered %d %q, want 403 denied", name, w.Code, w.Body.String())
+		}
+	}
+}
+
+// The gin handlers after the chain are skipped too when it stops the
+// request, and see the response the chain handed on when it does not.
+func TestGinHandlersAfterTheChain(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, stop := range []bool{false, true} {
+		chain := Chain{func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if stop {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.Header().Set("X-Chain", "yes")
+				next.ServeHTTP(w, r)
+			})
+		}}
+
+		var ran []string
+		g := gin.New()
+		g.Use(Gin(chain))
+		g.Use(func(c *gin.Context) {
+			ran = append(ran, "logger")
+			c.Next()
+		})
+		g.GET("/users/:id", func(c *gin.Context) {
+			ran = append(ran, "handler")
+			c.String(http.StatusCreated, "created")
+		})
+
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+		if stop {
+			if len(ran) != 0 || w.Code != http.StatusUnauthorized {
+				t.Errorf("stopped: ran %q and answered %d, want nothing run and 401", ran, w.Code)
+			}
+			continue
+		}
+		if !slices.Equal(ran, []string{"logger", "handler"}) || w.Code != http.StatusCreated || w.Header().Get("X-Chain") != "yes" {
+			t.Errorf("ran %q and answered %d, X-Chain %q, want both run and 201 through the chain", ran, w.Code, w.Header().Get("X-Chain"))
+		}
+	}
+}
+
+// An error the echo handler returns becomes a response inside the chain.
+func TestEchoErrorsPassThroughTheChain(t *testing.T) {
+	var trace []string
+	e := echo.New()
+	e.Use(Echo(Chain{layer("outer", &trace)}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+	if want := []string{"outer /users/{id} id=7", "outer Not Found"}; !slices.Equal(trace, want) {
+		t.Errorf("ran %q, want %q", trace, want)
+	}
+	if w.Code != http.StatusNotFound {
+		t.Errorf("answered %d, want 404", w.Code)
+	}
+}
+
+// Unknown routes are left to the router, without the chain.
+func TestChainSkipsUnknownRoutes(t *testing.T) {
+	for name, router := range routers {
+		var trace []string
+		h := router(Chain{layer("outer", &trace)}, func(http.ResponseWriter, *http.Request, string) {})
+
+		for _, r := range []*http.Request{
+			httptest.NewRequest(http.MethodGet, "/posts/7", nil),
+			httptest.NewRequest(http.MethodDelete, "/users/7", nil),
+		} {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != http.StatusNotFound && w.Code != http.StatusMethodNotAllowed {
+				t.Errorf("%s: %s %s answered %d, want 404 or 405", name, r.Method, r.URL.Path, w.Code)
+			}
+		}
+		if len(trace) != 0 {
+			t.Errorf("%s: ran the chain for unknown routes: %q", name, trace)
+		}
+	}
+}
+
+func TestGinWriterCounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	type counts struct {
+		status, size int
+		written      bool
+	}
+	tests := []struct {
+		name  string
+		write func(w gin.ResponseWriter)
+		want  []counts
+		code  int
+		body  string
+	}{
+		{
+			"nothing written",
+			func(w gin.ResponseWriter) {},
+			[]counts{{200, -1, false}},
+			200, "",
+		},
+		{
+			"status only",
+			func(w gin.ResponseWriter) { w.WriteHeader(204); w.WriteHeaderNow() },
+			[]counts{{204, 0, true}},
+			204, "",
+		},
+		{
+			"body",
+			func(w gin.ResponseWriter) {
+				w.WriteHeader(201)
+				w.WriteString("hello")
+				w.Write([]byte(", world"))
+			},
+			[]counts{{201, 12, true}},
+			201, "hello, world",
+		},
+		{
+			"status after the body",
+			func(w gin.ResponseWriter) {
+				w.Write([]byte("ok"))
+				w.WriteHeader(500)
+			},
+			[]counts{{200, 2, true}},
+			200, "ok",
+		},
+	}
+	for _, tt := range tests {
+		var got []counts
+		g := gin.New()
+		g.Use(Gin(Chain{}))
+		g.GET("/", func(c *gin.Context) {
+			tt.write(c.Writer)
+			got = append(got, counts{c.Writer.Status(), c.Writer.Size(), c.Writer.Written()})
+		})
+
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: counted %+v, want %+v", tt.name, got, tt.want)
+		}
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s: answered %d %q, want %d %q", tt.name, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
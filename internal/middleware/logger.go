@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/route"
+)
+
+// Logger logs every request once it has been answered, with the status,
+// size and duration of the response, the server it came through and the
+// route it matched. The route is only known to it inside route.Capture.
+func Logger(framework string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+
+			if sw.status == 0 {
+				// Nothing was written, so the server sends a 200
+				sw.status = http.StatusOK
+			}
+			pattern := route.Pattern(r)
+			if pattern == "" {
+				pattern = "-"
+			}
+			log.Printf("[%s] %d %s %s %s %dB %v", framework, sw.status, r.Method, r.URL.RequestURI(), pattern,
+				sw.bytes, time.Since(start).Round(time.Microsecond))
+		})
+	}
+}
+
+// statusWriter notes the status and size of a response on its way through.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 && status >= 200 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	n, err := sw.ResponseWriter.Write(b)
+	sw.bytes += n
+	return n, err
+}
+
+func (sw *statusWriter) Flush() {
+	http.NewResponseController(sw.ResponseWriter).Flush()
+}
+
+// Hijack hands the connection over, as for a WebSocket, which then answers
+// with 101 Switching Protocols itself.
+func (sw *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(sw.ResponseWriter).Hijack()
+	if err == nil && sw.status == 0 {
+		sw.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
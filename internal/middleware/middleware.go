@@ -0,0 +1,29 @@
+// Package middleware lets cross-cutting concerns be written once, as
+// net/http middleware, and installed into all six routers.
+//
+// A Chain either wraps a whole router, and so sees every request before
+// routing, or is installed inside a router with the adapters in this
+// package, and so runs once a route has matched, with the path parameters
+// of the route in the request as each framework keeps them. For every
+// framework the route is also recorded with the route package, and its
+// parameters are path values of the request.
+package middleware
+
+import (
+	"net/http"
+)
+
+// Middleware is the one form middleware is written in.
+type Middleware func(http.Handler) http.Handler
+
+// Chain is middleware in the order it runs: the first sees the request
+// first and the response last.
+type Chain []Middleware
+
+// Then wraps h in the middleware of the chain.
+func (c Chain) Then(h http.Handler) http.Handler {
+	for i := len(c) - 1; i >= 0; i-- {
+		h = c[i](h)
+	}
+	return h
+}
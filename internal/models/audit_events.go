@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type AuditEvent struct {
+	ID        int64              `json:"id"`
+	UserID    int32              `json:"user_id"`
+	Action    string             `json:"action"`
+	Actor     string             `json:"actor"`
+	RequestID string             `json:"request_id"`
+	Framework string             `json:"framework"`
+	Changes   json.RawMessage    `json:"changes"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type AuditEventPage struct {
+	Events []AuditEvent `json:"events"`
+	Limit  int32        `json:"limit"`
+	Offset int32        `json:"offset"`
+}
+
+func FromDatabaseAuditEvent(databaseEvent database.AuditEvent) AuditEvent {
+	return AuditEvent{
+		ID:        databaseEvent.ID,
+		UserID:    databaseEvent.UserID,
+		Action:    databaseEvent.Action,
+		Actor:     databaseEvent.Actor,
+		RequestID: databaseEvent.RequestID,
+		Framework: databaseEvent.Framework,
+		Changes:   databaseEvent.Changes,
+		CreatedAt: databaseEvent.CreatedAt,
+	}
+}
+
+func FromDatabaseAuditEvents(databaseEvents []database.AuditEvent, limit, offset int32) AuditEventPage {
+	events := []AuditEvent{}
+
+	for _, databaseEvent := range databaseEvents {
+		events = append(events, FromDatabaseAuditEvent(databaseEvent))
+	}
+	return AuditEventPage{Events: events, Limit: limit, Offset: offset}
+}
@@ -0,0 +1,26 @@
+package models
+
+// BulkItem is one user in a bulk request. Fields are pointers so a PATCH can
+// tell a missing field from a zero value.
+type BulkItem struct {
+	ID    *int32  `json:"id,omitempty"`
+	Name  *string `json:"name,omitempty"`
+	Email *string `json:"email,omitempty"`
+	Age   *int32  `json:"age,omitempty"`
+}
+
+// BulkItemResult is the outcome of one item of a bulk request. Status is the
+// HTTP status the item would have had as a single request.
+type BulkItemResult struct {
+	Index  int    `json:"index"`
+	Status int    `json:"status"`
+	User   *User  `json:"user,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BulkResponse struct {
+	Mode      string           `json:"mode"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
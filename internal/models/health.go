@@ -0,0 +1,29 @@
+package models
+
+// Health is the answer of the health checks.
+type Health struct {
+	Status string `json:"status"`
+}
+
+// Readiness is the answer of the readiness check: "ready", or "unavailable"
+// when the database cannot be used.
+type Readiness struct {
+	Status   string          `json:"status"`
+	Database DatabaseStatus  `json:"database"`
+	Replicas []ReplicaStatus `json:"replicas,omitempty"`
+}
+
+type DatabaseStatus struct {
+	// Breaker is the state of the circuit breaker of the database: closed,
+	// half-open or open.
+	Breaker string `json:"breaker"`
+}
+
+// ReplicaStatus is the state of a read replica. Reads go to the primary
+// while a replica is not usable, so it does not make the server unready.
+type ReplicaStatus struct {
+	Name       string  `json:"name"`
+	Usable     bool    `json:"usable"`
+	LagSeconds float64 `json:"lag_seconds"`
+	Breaker    string  `json:"breaker"`
+}
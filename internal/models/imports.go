@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type UserImport struct {
+	ID            int32              `json:"id"`
+	Status        string             `json:"status"`
+	Format        string             `json:"format"`
+	OnDuplicate   string             `json:"on_duplicate"`
+	DryRun        bool               `json:"dry_run"`
+	ColumnMap     json.RawMessage    `json:"column_map"`
+	TotalRows     int32              `json:"total_rows"`
+	ProcessedRows int32              `json:"processed_rows"`
+	CreatedRows   int32              `json:"created_rows"`
+	UpdatedRows   int32              `json:"updated_rows"`
+	SkippedRows   int32              `json:"skipped_rows"`
+	FailedRows    int32              `json:"failed_rows"`
+	Error         pgtype.Text        `json:"error"`
+	Actor         string             `json:"actor"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	StartedAt     pgtype.Timestamptz `json:"started_at"`
+	FinishedAt    pgtype.Timestamptz `json:"finished_at"`
+}
+
+func FromDatabaseUserImport(databaseImport database.UserImport) UserImport {
+	return UserImport{
+		ID:            databaseImport.ID,
+		Status:        databaseImport.Status,
+		Format:        databaseImport.Format,
+		OnDuplicate:   databaseImport.OnDuplicate,
+		DryRun:        databaseImport.DryRun,
+		ColumnMap:     databaseImport.ColumnMap,
+		TotalRows:     databaseImport.TotalRows,
+		ProcessedRows: databaseImport.ProcessedRows,
+		CreatedRows:   databaseImport.CreatedRows,
+		UpdatedRows:   databaseImport.UpdatedRows,
+		SkippedRows:   databaseImport.SkippedRows,
+		FailedRows:    databaseImport.FailedRows,
+		Error:         databaseImport.Error,
+		Actor:         databaseImport.Actor,
+		CreatedAt:     databaseImport.CreatedAt,
+		StartedAt:     databaseImport.StartedAt,
+		FinishedAt:    databaseImport.FinishedAt,
+	}
+}
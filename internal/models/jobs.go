@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type Job struct {
+	ID          int64              `json:"id"`
+	Kind        string             `json:"kind"`
+	Payload     json.RawMessage    `json:"payload"`
+	Status      string             `json:"status"`
+	Attempts    int32              `json:"attempts"`
+	MaxAttempts int32              `json:"max_attempts"`
+	RunAt       pgtype.Timestamptz `json:"run_at"`
+	LastError   pgtype.Text        `json:"last_error"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	StartedAt   pgtype.Timestamptz `json:"started_at"`
+	FinishedAt  pgtype.Timestamptz `json:"finished_at"`
+}
+
+type JobPage struct {
+	Jobs   []Job `json:"jobs"`
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func FromDatabaseJob(databaseJob database.Job) Job {
+	return Job{
+		ID:          databaseJob.ID,
+		Kind:        databaseJob.Kind,
+		Payload:     databaseJob.Payload,
+		Status:      databaseJob.Status,
+		Attempts:    databaseJob.Attempts,
+		MaxAttempts: databaseJob.MaxAttempts,
+		RunAt:       databaseJob.RunAt,
+		LastError:   databaseJob.LastError,
+		CreatedAt:   databaseJob.CreatedAt,
+		StartedAt:   databaseJob.StartedAt,
+		FinishedAt:  databaseJob.FinishedAt,
+	}
+}
+
+func FromDatabaseJobs(databaseJobs []database.Job, limit, offset int32) JobPage {
+	jobs := []Job{}
+
+	for _, databaseJob := range databaseJobs {
+		jobs = append(jobs, FromDatabaseJob(databaseJob))
+	}
+	return JobPage{Jobs: jobs, Limit: limit, Offset: offset}
+}
@@ -0,0 +1,20 @@
+package models
+
+// Problem is an RFC 9457 problem details body, sent with the
+// application/problem+json content type when a request does not match the
+// OpenAPI document.
+type Problem struct {
+	Type   string         `json:"type"`
+	Title  string         `json:"title"`
+	Status int            `json:"status"`
+	Detail string         `json:"detail,omitempty"`
+	Errors []ProblemError `json:"errors,omitempty"`
+}
+
+// ProblemError is one thing wrong with a request. Pointer is a JSON pointer
+// into the body, and Parameter names a path, query or header parameter.
+type ProblemError struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	Detail    string `json:"detail"`
+}
@@ -11,6 +11,27 @@ type User struct {
 	Email     string             `json:"email"`
 	Age       int32              `json:"age"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+}
+
+// CreateUserRequest holds the fields of a new user.
+type CreateUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Age   int32  `json:"age"`
+}
+
+// UpdateUserRequest holds the fields of a user to change. Fields left out
+// keep their current value.
+type UpdateUserRequest struct {
+	Name  *string `json:"name,omitempty"`
+	Email *string `json:"email,omitempty"`
+	Age   *int32  `json:"age,omitempty"`
+}
+
+// Error is the body of every error response.
+type Error struct {
+	Error string `json:"error"`
 }
 
 func FromDatabaseUser(databaseUser database.User) User {
@@ -20,6 +41,7 @@ func FromDatabaseUser(databaseUser database.User) User {
 		Email:     databaseUser.Email,
 		Age:       databaseUser.Age,
 		CreatedAt: databaseUser.CreatedAt,
+		DeletedAt: databaseUser.DeletedAt,
 	}
 }
 
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Webhook leaves the secret out unless it is set explicitly, which only
+// happens in the response to creating the webhook or rotating its secret.
+type Webhook struct {
+	ID           int32              `json:"id"`
+	URL          string             `json:"url"`
+	Secret       string             `json:"secret,omitempty"`
+	Events       []string           `json:"events"`
+	Active       bool               `json:"active"`
+	FailureCount int32              `json:"failure_count"`
+	DisabledAt   pgtype.Timestamptz `json:"disabled_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+// WebhookRequest holds the fields of a webhook to create or update. Fields
+// left out of an update keep their current value, and a secret left out of a
+// create is generated.
+type WebhookRequest struct {
+	URL    *string   `json:"url,omitempty"`
+	Events *[]string `json:"events,omitempty"`
+	Secret *string   `json:"secret,omitempty"`
+	Active *bool     `json:"active,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID               int64              `json:"id"`
+	WebhookID        int32              `json:"webhook_id"`
+	EventID          int64              `json:"event_id"`
+	EventType        string             `json:"event_type"`
+	Payload          json.RawMessage    `json:"payload"`
+	Status           string             `json:"status"`
+	Attempts         int32              `json:"attempts"`
+	LastResponseCode pgtype.Int4        `json:"last_response_code"`
+	LastError        pgtype.Text        `json:"last_error"`
+	NextAttemptAt    pgtype.Timestamptz `json:"next_attempt_at"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+}
+
+type WebhookDeliveryPage struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Limit      int32             `json:"limit"`
+	Offset     int32             `json:"offset"`
+}
+
+func FromDatabaseWebhook(databaseWebhook database.Webhook) Webhook {
+	events := databaseWebhook.Events
+	if events == nil {
+		events = []string{}
+	}
+
+	return Webhook{
+		ID:           databaseWebhook.ID,
+		URL:          databaseWebhook.Url,
+		Events:       events,
+		Active:       databaseWebhook.Active,
+		FailureCount: databaseWebhook.FailureCount,
+		DisabledAt:   databaseWebhook.DisabledAt,
+		CreatedAt:    databaseWebhook.CreatedAt,
+	}
+}
+
+func FromDatabaseWebhooks(databaseWebhooks []database.Webhook) []Webhook {
+	webhooks := []Webhook{}
+
+	for _, databaseWebhook := range databaseWebhooks {
+		webhooks = append(webhooks, FromDatabaseWebhook(databaseWebhook))
+	}
+	return webhooks
+}
+
+func FromDatabaseWebhookDelivery(databaseDelivery database.WebhookDelivery) WebhookDelivery {
+	return WebhookDelivery{
+		ID:               databaseDelivery.ID,
+		WebhookID:        databaseDelivery.WebhookID,
+		EventID:          databaseDelivery.EventID,
+		EventType:        databaseDelivery.EventType,
+		Payload:          databaseDelivery.Payload,
+		Status:           databaseDelivery.Status,
+		Attempts:         databaseDelivery.Attempts,
+		LastResponseCode: databaseDelivery.LastResponseCode,
+		LastError:        databaseDelivery.LastError,
+		NextAttemptAt:    databaseDelivery.NextAttemptAt,
+		CreatedAt:        databaseDelivery.CreatedAt,
+	}
+}
+
+func FromDatabaseWebhookDeliveries(databaseDeliveries []database.WebhookDelivery, limit, offset int32) WebhookDeliveryPage {
+	deliveries := []WebhookDelivery{}
+
+	for _, databaseDelivery := range databaseDeliveries {
+		deliveries = append(deliveries, FromDatabaseWebhookDelivery(databaseDelivery))
+	}
+	return WebhookDeliveryPage{Deliveries: deliveries, Limit: limit, Offset: offset}
+}
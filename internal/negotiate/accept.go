@@ -0,0 +1,98 @@
+// Package negotiate chooses how responses are sent from the Accept and
+// Accept-Encoding headers of the request: as JSON, XML or MessagePack, and
+// compressed with gzip or deflate.
+package negotiate
+
+import (
+	"strconv"
+	"strings"
+)
+
+// preference is one entry of an Accept style header, such as text/html;q=0.8.
+type preference struct {
+	value string
+	q     float64
+}
+
+// parsePreferences splits an Accept style header into its entries, dropping
+// any media type parameters other than q.
+func parsePreferences(header string) []preference {
+	var prefs []preference
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		pref := preference{value: value, q: 1}
+		for _, param := range strings.Split(params, ";") {
+			name, v, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(name) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && q >= 0 && q <= 1 {
+				pref.q = q
+			}
+		}
+		prefs = append(prefs, pref)
+	}
+	return prefs
+}
+
+// mediaTypeQuality returns the quality the preferences give mediaType, from
+// the most specific range that matches it, or -1 if none does.
+func mediaTypeQuality(prefs []preference, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := -1.0, -1
+	for _, pref := range prefs {
+		s := -1
+		switch pref.value {
+		case mediaType:
+			s = 2
+		case typ + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = pref.q, s
+		}
+	}
+	return q
+}
+
+// codingQuality returns the quality the preferences give a content coding.
+// Identity is acceptable unless it is refused, directly or with *.
+func codingQuality(prefs []preference, coding string) float64 {
+	q, wildcard := -1.0, -1.0
+	for _, pref := range prefs {
+		switch pref.value {
+		case coding:
+			q = pref.q
+		case "*":
+			wildcard = pref.q
+		}
+	}
+	switch {
+	case q >= 0:
+		return q
+	case wildcard >= 0:
+		return wildcard
+	case coding == "identity":
+		return 1
+	}
+	return 0
+}
+
+// best returns the offer with the highest quality above zero, preferring the
+// earliest offer on a tie, or "" if the preferences allow none.
+func best(offers []string, quality func(string) float64) string {
+	chosen, chosenQ := "", 0.0
+	for _, offer := range offers {
+		if q := quality(offer); q > chosenQ {
+			chosen, chosenQ = offer, q
+		}
+	}
+	return chosen
+}
@@ -0,0 +1,195 @@
+package negotiate
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+)
+
+// Codings the Compressor offers, most preferred first.
+var codings = []string{"gzip", "deflate", "identity"}
+
+var (
+	gzipWriters  = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+	flateWriters = sync.Pool{New: func() any {
+		w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return w
+	}}
+)
+
+// Compressor compresses responses for clients that accept it.
+type Compressor struct {
+	cfg config.CompressionConfig
+}
+
+func NewCompressor(cfg config.CompressionConfig) *Compressor {
+	return &Compressor{cfg: cfg}
+}
+
+// Middleware compresses the responses of next with gzip or deflate, as the
+// Accept-Encoding of the request prefers. Responses smaller than the
+// configured minimum, event streams, media that is compressed already and
+// upgraded connections are sent as they are.
+func (c *Compressor) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		prefs := parsePreferences(r.Header.Get("Accept-Encoding"))
+		coding := best(codings, func(coding string) float64 { return codingQuality(prefs, coding) })
+		if coding == "" || coding == "identity" {
+			// Sending the response unencoded beats refusing it with a 406
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, coding: coding, minBytes: c.cfg.MinBytes}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter holds back the start of a response until it is known to be
+// large enough to compress, then passes it on compressed or as it is.
+type compressWriter struct {
+	http.ResponseWriter
+	coding   string
+	minBytes int
+
+	status  int
+	buf     []byte
+	started bool
+	encoder io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if status < 200 {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.started {
+		if cw.encoder != nil {
+			return cw.encoder.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.minBytes {
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends what is held back, compressed since more is on its way.
+func (cw *compressWriter) Flush() {
+	if !cw.started {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.start(true); err != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// start writes the header and what is held back, compressing from here on
+// if compress is set and the response is worth compressing.
+func (cw *compressWriter) start(compress bool) error {
+	cw.started = true
+	header := cw.Header()
+	if compress && compressible(cw.status, header) {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", cw.coding)
+		switch cw.coding {
+		case "gzip":
+			gz := gzipWriters.Get().(*gzip.Writer)
+			gz.Reset(cw.ResponseWriter)
+			cw.encoder = gz
+		case "deflate":
+			fl := flateWriters.Get().(*flate.Writer)
+			fl.Reset(cw.ResponseWriter)
+			cw.encoder = fl
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.encoder != nil {
+		_, err := cw.encoder.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// close sends a response that stayed below the minimum size as it is, or
+// finishes the compressed stream.
+func (cw *compressWriter) close() {
+	if !cw.started {
+		if cw.status == 0 {
+			// The handler wrote nothing, so the server sends its own 200
+			return
+		}
+		cw.start(false)
+		return
+	}
+
+	switch encoder := cw.encoder.(type) {
+	case *gzip.Writer:
+		encoder.Close()
+		gzipWriters.Put(encoder)
+	case *flate.Writer:
+		encoder.Close()
+		flateWriters.Put(encoder)
+	}
+}
+
+func compressible(status int, header http.Header) bool {
+	if status == http.StatusNoContent || status == http.StatusNotModified || header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	mediaType := strings.ToLower(header.Get("Content-Type"))
+	switch {
+	case strings.HasPrefix(mediaType, "text/event-stream"),
+		strings.HasPrefix(mediaType, "image/") && !strings.HasPrefix(mediaType, "image/svg"),
+		strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "application/zip"),
+		strings.HasPrefix(mediaType, "application/gzip"):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,244 @@
+package negotiate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The handlers write JSON, which is decoded into the values below, keeping
+// the order of object members, and encoded again as XML or MessagePack.
+
+type member struct {
+	name  string
+	value any
+}
+
+// object is a JSON object with its members in order.
+type object []member
+
+// decodeJSON decodes a single JSON value into nil, bool, json.Number,
+// string, []any or object values.
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := decodeValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+func decodeValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		obj := object{}
+		for decoder.More() {
+			name, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, member{name: name.(string), value: value})
+		}
+		_, err := decoder.Token()
+		return obj, err
+	case json.Delim('['):
+		items := []any{}
+		for decoder.More() {
+			item, err := decodeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err := decoder.Token()
+		return items, err
+	}
+	return token, nil
+}
+
+// encodeXML writes value as the content of a <response> element. Object
+// members become child elements named after them, array items become <item>
+// elements and null becomes an empty element with nil="true".
+func encodeXML(w io.Writer, value any) error {
+	io.WriteString(w, xml.Header)
+	encoder := xml.NewEncoder(w)
+	if err := writeXMLElement(encoder, "response", value); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+func writeXMLElement(encoder *xml.Encoder, name string, value any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !xmlName(name) {
+		// Member names that are not XML names are kept in an attribute
+		start = xml.StartElement{
+			Name: xml.Name{Local: "member"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: name}},
+		}
+	}
+	if value == nil {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "nil"}, Value: "true"})
+	}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case object:
+		for _, m := range v {
+			if err := writeXMLElement(encoder, m.name, m.value); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := writeXMLElement(encoder, "item", item); err != nil {
+				return err
+			}
+		}
+	case json.Number:
+		if err := encoder.EncodeToken(xml.CharData(v.String())); err != nil {
+			return err
+		}
+	case string:
+		if err := encoder.EncodeToken(xml.CharData(v)); err != nil {
+			return err
+		}
+	case bool:
+		text := "false"
+		if v {
+			text = "true"
+		}
+		if err := encoder.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// xmlName reports whether name can be used as an element name as it is.
+func xmlName(name string) bool {
+	if name == "" || len(name) >= 3 && (name[0]|0x20) == 'x' && (name[1]|0x20) == 'm' && (name[2]|0x20) == 'l' {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r), r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// encodeMessagePack writes value in the MessagePack format, with integers
+// in the smallest form that holds them and other numbers as float 64.
+func encodeMessagePack(buf *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			writeMessagePackInt(buf, n)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(0xcb)
+		buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+	case string:
+		if !utf8.ValidString(v) {
+			return errors.New("string is not valid UTF-8")
+		}
+		writeMessagePackLength(buf, len(v), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []any:
+		writeMessagePackLength(buf, len(v), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := encodeMessagePack(buf, item); err != nil {
+				return err
+			}
+		}
+	case object:
+		writeMessagePackLength(buf, len(v), 0x80, 15, 0, 0xde, 0xdf)
+		for _, m := range v {
+			if err := encodeMessagePack(buf, m.name); err != nil {
+				return err
+			}
+			if err := encodeMessagePack(buf, m.value); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New("unsupported value")
+	}
+	return nil
+}
+
+func writeMessagePackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0 && n <= math.MaxInt8:
+		buf.WriteByte(byte(n))
+	case n < 0 && n >= -32:
+		buf.WriteByte(byte(int8(n)))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		buf.Write([]byte{0xd0, byte(int8(n))})
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(int16(n))))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(int32(n))))
+	default:
+		buf.WriteByte(0xd3)
+		buf.Write(binary.BigEndian.AppendUint64(nil, uint64(n)))
+	}
+}
+
+// writeMessagePackLength writes the header of a string, array or map of n
+// elements: the fix form when n is at most fixMax, otherwise the 8 (if the
+// format has one), 16 or 32 bit form.
+func writeMessagePackLength(buf *bytes.Buffer, n int, fix byte, fixMax int, f8, f16, f32 byte) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case f8 != 0 && n <= math.MaxUint8:
+		buf.Write([]byte{f8, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(f16)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(f32)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
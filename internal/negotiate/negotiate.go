@@ -0,0 +1,175 @@
+package negotiate
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/openapi"
+)
+
+// Other names clients use for the media types JSON is transcoded to.
+var aliases = map[string][]string{
+	"application/xml":     {"text/xml"},
+	"application/msgpack": {"application/x-msgpack", "application/vnd.msgpack"},
+}
+
+// transcoders encode a JSON response as another media type.
+var transcoders = map[string]func(any) ([]byte, error){
+	"application/xml": func(value any) ([]byte, error) {
+		var buf bytes.Buffer
+		err := encodeXML(&buf, value)
+		return buf.Bytes(), err
+	},
+	"application/msgpack": func(value any) ([]byte, error) {
+		var buf bytes.Buffer
+		err := encodeMessagePack(&buf, value)
+		return buf.Bytes(), err
+	},
+}
+
+func init() {
+	for mediaType, names := range aliases {
+		for _, name := range names {
+			transcoders[name] = transcoders[mediaType]
+		}
+	}
+}
+
+// Middleware picks the representation of the response from the Accept
+// header and the media types the OpenAPI document lists for the route. JSON
+// responses, errors included, are transcoded to XML or MessagePack when the
+// client prefers those; a request that accepts none of the media types of
+// the route gets a 406 before it reaches the handler.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := r.Header.Get("Accept")
+		offers, ok := openapi.ResponseMediaTypes(r)
+		if !ok || len(offers) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept")
+		if accept == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for _, offer := range slices.Clone(offers) {
+			offers = append(offers, aliases[offer]...)
+		}
+		prefs := parsePreferences(accept)
+		mediaType := best(offers, func(offer string) float64 { return mediaTypeQuality(prefs, offer) })
+		if mediaType == "" {
+			writeNotAcceptable(w, offers)
+			return
+		}
+
+		transcode := transcoders[mediaType]
+		if transcode == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tw := &transcodeWriter{ResponseWriter: w}
+		next.ServeHTTP(tw, r)
+		tw.finish(mediaType, transcode)
+	})
+}
+
+func writeNotAcceptable(w http.ResponseWriter, offers []string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(http.StatusNotAcceptable)
+	json.NewEncoder(w).Encode(models.Problem{
+		Type:   "about:blank",
+		Title:  "Not Acceptable",
+		Status: http.StatusNotAcceptable,
+		Detail: "The response is available as " + strings.Join(offers, ", "),
+	})
+}
+
+// transcodeWriter holds back JSON responses to transcode them once they are
+// complete. Anything else is passed through.
+type transcodeWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	passthrough bool
+}
+
+func (tw *transcodeWriter) WriteHeader(status int) {
+	if status < 200 {
+		tw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if tw.status != 0 {
+		return
+	}
+	tw.status = status
+
+	mediaType, _, _ := mime.ParseMediaType(tw.Header().Get("Content-Type"))
+	if mediaType != "application/json" {
+		tw.passthrough = true
+		tw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (tw *transcodeWriter) Write(b []byte) (int, error) {
+	if tw.status == 0 {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if tw.passthrough {
+		return tw.ResponseWriter.Write(b)
+	}
+	return tw.buf.Write(b)
+}
+
+// Flush only reaches the client for responses passed through; a JSON
+// response is sent once it is complete.
+func (tw *transcodeWriter) Flush() {
+	if tw.passthrough {
+		http.NewResponseController(tw.ResponseWriter).Flush()
+	}
+}
+
+func (tw *transcodeWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+func (tw *transcodeWriter) finish(mediaType string, transcode func(any) ([]byte, error)) {
+	if tw.status == 0 || tw.passthrough {
+		return
+	}
+
+	body := tw.buf.Bytes()
+	if len(body) > 0 {
+		value, err := decodeJSON(body)
+		if err == nil {
+			body, err = transcode(value)
+		}
+		if err != nil {
+			// Better the JSON the handler wrote than no response at all
+			log.Printf("Error transcoding response to %s: %v", mediaType, err)
+			body = tw.buf.Bytes()
+			mediaType = "application/json"
+		}
+	}
+
+	tw.Header().Del("Content-Length")
+	tw.Header().Set("Content-Type", contentType(mediaType))
+	tw.ResponseWriter.WriteHeader(tw.status)
+	tw.ResponseWriter.Write(body)
+}
+
+func contentType(mediaType string) string {
+	if strings.HasSuffix(mediaType, "xml") {
+		return mediaType + "; charset=utf-8"
+	}
+	return mediaType
+}
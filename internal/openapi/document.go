@@ -0,0 +1,327 @@
+package openapi
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+)
+
+// DocsHTML is a page that renders /openapi.json without loading anything
+// from outside the server.
+//
+//go:embed docs.html
+var DocsHTML []byte
+
+var inlineBlock = regexp.MustCompile(`(?s)<(script|style)>(.*?)</(?:script|style)>`)
+
+// DocsContentSecurityPolicy is the Content-Security-Policy for the docs page.
+// It allows the inline script and style of the page by their hashes, and
+// requests to this server and the others listed in the document, and
+// nothing else.
+var DocsContentSecurityPolicy = sync.OnceValue(func() string {
+	sources := map[string][]string{"script": {}, "style": {}}
+	for _, match := range inlineBlock.FindAllSubmatch(DocsHTML, -1) {
+		sum := sha256.Sum256(match[2])
+		kind := string(match[1])
+		sources[kind] = append(sources[kind], "'sha256-"+base64.StdEncoding.EncodeToString(sum[:])+"'")
+	}
+
+	// The servers may be serving HTTPS instead, so both are allowed
+	connect := []string{"'self'"}
+	for _, server := range servers {
+		connect = append(connect, server["url"], strings.Replace(server["url"], "http://", "https://", 1))
+	}
+
+	return strings.Join([]string{
+		"default-src 'none'",
+		"script-src " + strings.Join(sources["script"], " "),
+		"style-src " + strings.Join(sources["style"], " "),
+		"connect-src " + strings.Join(connect, " "),
+		"base-uri 'none'",
+		"form-action 'none'",
+	}, "; ")
+})
+
+// Every router serves the same API, so each is listed as a server.
+var servers = []map[string]string{
+	{"url": "http://localhost:8000", "description": "Standard library"},
+	{"url": "http://localhost:8001", "description": "httprouter"},
+	{"url": "http://localhost:8002", "description": "gorilla/mux"},
+	{"url": "http://localhost:8003", "description": "chi"},
+	{"url": "http://localhost:8004", "description": "Echo"},
+	{"url": "http://localhost:8005", "description": "Gin"},
+}
+
+var tags = []map[string]string{
+	{"name": tagUsers, "description": "Users and their live events"},
+	{"name": tagImports, "description": "Bulk imports from CSV or NDJSON files"},
+	{"name": tagAudit, "description": "The audit log of user changes"},
+	{"name": tagWebhooks, "description": "Webhook subscriptions and their deliveries"},
+	{"name": tagJobs, "description": "Background jobs"},
+	{"name": tagDocs, "description": "This documentation"},
+	{"name": tagHealth, "description": "Health checks of the servers"},
+}
+
+// built is the document along with the schemas of its components, which
+// the validator checks values against.
+type built struct {
+	document map[string]any
+	schemas  *schemas
+}
+
+var build = sync.OnceValue(func() built {
+	s := &schemas{components: map[string]Schema{}}
+	s.components["UserEvent"] = s.object(reflect.TypeFor[outbox.Event]())
+
+	paths := map[string]map[string]any{}
+	for _, route := range Routes {
+		if paths[route.Path] == nil {
+			paths[route.Path] = map[string]any{}
+		}
+		paths[route.Path][strings.ToLower(route.Method)] = operation(s, route)
+	}
+
+	document := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "Go Frameworks CRUD",
+			"version":     "1.0.0",
+			"description": "The same users API served by six Go routers. Admin operations need the ADMIN_TOKEN as a bearer token.",
+		},
+		"servers": servers,
+		"tags":    tags,
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": s.components,
+			"securitySchemes": map[string]any{
+				"adminToken": map[string]string{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+	return built{document: document, schemas: s}
+})
+
+// Document returns the OpenAPI 3.1 document of Routes.
+func Document() map[string]any {
+	return build().document
+}
+
+// JSON returns the document encoded as JSON.
+var JSON = sync.OnceValue(func() []byte {
+	data, err := json.MarshalIndent(Document(), "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return data
+})
+
+func operation(s *schemas, route Route) map[string]any {
+	op := map[string]any{
+		"operationId": route.OperationID,
+		"summary":     route.Summary,
+		"tags":        []string{route.Tag},
+	}
+	if route.Description != "" {
+		op["description"] = route.Description
+	}
+
+	if routeParameters := route.parameters(); len(routeParameters) > 0 {
+		parameters := []map[string]any{}
+		for _, p := range routeParameters {
+			parameter := map[string]any{"name": p.Name, "in": p.In, "schema": p.Schema}
+			if p.Description != "" {
+				parameter["description"] = p.Description
+			}
+			if p.Required {
+				parameter["required"] = true
+			}
+			parameters = append(parameters, parameter)
+		}
+		op["parameters"] = parameters
+	}
+
+	if route.Body != nil {
+		op["requestBody"] = map[string]any{
+			"required": route.Body.Required,
+			"content":  content(s, route.Body.Content),
+		}
+	}
+
+	if route.Admin {
+		op["security"] = []map[string][]string{{"adminToken": {}}}
+	}
+
+	byStatus := map[string]any{}
+	for _, r := range route.responses() {
+		response := map[string]any{"description": r.Description}
+		if len(r.Content) > 0 {
+			response["content"] = content(s, r.Content)
+		}
+		if len(r.Headers) > 0 {
+			headers := map[string]any{}
+			for name, description := range r.Headers {
+				headers[name] = map[string]any{"description": description, "schema": Schema{"type": "string"}}
+			}
+			response["headers"] = headers
+		}
+		byStatus[strconv.Itoa(r.Status)] = response
+	}
+	op["responses"] = byStatus
+
+	return op
+}
+
+func content(s *schemas, contents []Content) map[string]any {
+	byType := map[string]any{}
+	for _, c := range contents {
+		byType[c.MediaType] = map[string]any{"schema": s.of(c.Schema)}
+	}
+	return byType
+}
+
+// idempotent reports whether requests to the route may carry an
+// Idempotency-Key.
+func (route Route) idempotent() bool {
+	return route.Method == http.MethodPost || route.Method == http.MethodPatch
+}
+
+var idempotencyKey = Parameter{
+	Name:        "Idempotency-Key",
+	In:          "header",
+	Description: "Runs the request once per key, replaying the first response to repeats",
+	Schema:      Schema{"type": "string"},
+}
+
+// parameters adds the parameters every route of its kind has to those listed.
+func (route Route) parameters() []Parameter {
+	if route.idempotent() {
+		return append(slices.Clone(route.Parameters), idempotencyKey)
+	}
+	return route.Parameters
+}
+
+// Successful JSON responses are also served as XML and MessagePack, for
+// clients that ask for them with the Accept header.
+var alternativeMediaTypes = []string{"application/xml", "application/msgpack"}
+
+// responses adds the responses every route of its kind has to those listed.
+func (route Route) responses() []Response {
+	responses := slices.Clone(route.Responses)
+	negotiable := false
+	for i, r := range responses {
+		if r.Status < 200 || r.Status > 299 || len(r.Content) == 0 {
+			continue
+		}
+		negotiable = true
+		for _, c := range r.Content {
+			if c.MediaType == "application/json" {
+				for _, mediaType := range alternativeMediaTypes {
+					responses[i].Content = append(slices.Clip(responses[i].Content), Content{MediaType: mediaType, Schema: c.Schema})
+				}
+			}
+		}
+	}
+	if negotiable {
+		responses = append(responses, Response{
+			Status:      http.StatusNotAcceptable,
+			Description: "None of the media types of the response is acceptable",
+			Content:     problemContent,
+		})
+	}
+	if route.Admin {
+		responses = append(responses, errorResponse(http.StatusForbidden, "Admin access required"))
+	}
+	if route.Body != nil {
+		responses = append(responses, Response{
+			Status:      http.StatusUnsupportedMediaType,
+			Description: "The body is not one of the documented content types",
+			Content:     problemContent,
+		})
+	}
+	if route.idempotent() {
+		responses = withProblem(responses, http.StatusBadRequest, "Invalid Idempotency-Key")
+		responses = withProblem(responses, http.StatusConflict, "A request with the same Idempotency-Key is in progress")
+		responses = withProblem(responses, http.StatusRequestEntityTooLarge, "Body too large for an Idempotency-Key")
+		responses = withProblem(responses, http.StatusUnprocessableEntity, "The Idempotency-Key was used for a different request")
+	}
+	return responses
+}
+
+// withProblem adds a problem details body to the response with status,
+// adding the response if there is none.
+func withProblem(responses []Response, status int, description string) []Response {
+	for i, r := range responses {
+		if r.Status == status {
+			if !slices.ContainsFunc(r.Content, func(c Content) bool { return c.MediaType == problemContent[0].MediaType }) {
+				responses[i].Content = slices.Concat(r.Content, problemContent)
+			}
+			return responses
+		}
+	}
+	return append(responses, Response{Status: status, Description: description, Content: problemContent})
+}
+
+// Endpoint is a method and a path in the OpenAPI style, such as
+// GET /users/{id}.
+type Endpoint struct {
+	Method string
+	Path   string
+}
+
+func (e Endpoint) String() string {
+	return e.Method + " " + e.Path
+}
+
+// Endpoints lists the endpoints of Routes.
+func Endpoints() []Endpoint {
+	endpoints := []Endpoint{}
+	for _, route := range Routes {
+		endpoints = append(endpoints, Endpoint{Method: route.Method, Path: route.Path})
+	}
+	return endpoints
+}
+
+// Diff compares the endpoints a router serves with Routes, returning those
+// the router is missing and those the document does not describe.
+func Diff(served []Endpoint) (missing, undocumented []Endpoint) {
+	documented := Endpoints()
+	for _, e := range documented {
+		if !slices.Contains(served, e) {
+			missing = append(missing, e)
+		}
+	}
+	for _, e := range served {
+		if !slices.Contains(documented, e) && !slices.Contains(undocumented, e) {
+			undocumented = append(undocumented, e)
+		}
+	}
+	return missing, undocumented
+}
+
+// Check returns an error listing the differences between served and Routes.
+func Check(served []Endpoint) error {
+	missing, undocumented := Diff(served)
+	if len(missing) == 0 && len(undocumented) == 0 {
+		return nil
+	}
+
+	var lines []string
+	for _, e := range missing {
+		lines = append(lines, "  not served: "+e.String())
+	}
+	for _, e := range undocumented {
+		lines = append(lines, "  not documented: "+e.String())
+	}
+	return fmt.Errorf("routes differ from the OpenAPI document:\n%s", strings.Join(lines, "\n"))
+}
@@ -0,0 +1,495 @@
+package openapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+)
+
+// route is a Route prepared for matching requests against.
+type route struct {
+	Route
+	segments  []string
+	body      map[string]Schema
+	responses map[int]map[string]Schema
+}
+
+// compiled prepares Routes for matching requests against, once.
+var compiled = sync.OnceValue(func() []*route {
+	s := build().schemas
+	var routes []*route
+
+	for _, r := range Routes {
+		prepared := &route{
+			Route:     r,
+			segments:  strings.Split(strings.Trim(r.Path, "/"), "/"),
+			body:      map[string]Schema{},
+			responses: map[int]map[string]Schema{},
+		}
+		if r.Body != nil {
+			for _, c := range r.Body.Content {
+				prepared.body[c.MediaType] = s.of(c.Schema)
+			}
+		}
+		for _, response := range r.responses() {
+			prepared.responses[response.Status] = map[string]Schema{}
+			for _, c := range response.Content {
+				prepared.responses[response.Status][c.MediaType] = s.of(c.Schema)
+			}
+		}
+		routes = append(routes, prepared)
+	}
+
+	return routes
+})
+
+// Validator checks requests, and optionally responses, against the document.
+type Validator struct {
+	cfg     config.ValidationConfig
+	schemas *schemas
+	routes  []*route
+}
+
+func NewValidator(cfg config.ValidationConfig) *Validator {
+	return &Validator{cfg: cfg, schemas: build().schemas, routes: compiled()}
+}
+
+// ResponseMediaTypes returns the media types the document lists for the
+// successful responses of the route r is for, in the order listed. It
+// reports false for requests the document has no route for.
+func ResponseMediaTypes(r *http.Request) ([]string, bool) {
+	route, _ := match(compiled(), r)
+	if route == nil {
+		return nil, false
+	}
+
+	var mediaTypes []string
+	for _, response := range route.Route.responses() {
+		if response.Status < 200 || response.Status > 299 {
+			continue
+		}
+		for _, c := range response.Content {
+			if !slices.Contains(mediaTypes, c.MediaType) {
+				mediaTypes = append(mediaTypes, c.MediaType)
+			}
+		}
+	}
+	return mediaTypes, true
+}
+
+// Middleware validates the requests next serves. Requests for routes the
+// document does not have are passed on untouched, for the router to answer.
+// It returns next itself when validation is off.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	if !v.cfg.Requests && !v.cfg.Responses {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, params := match(v.routes, r)
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if v.cfg.Requests {
+			if problem := v.validateRequest(route, params, r); problem != nil {
+				writeProblem(w, *problem)
+				return
+			}
+		}
+
+		if !v.cfg.Responses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &responseRecorder{w: w}
+		next.ServeHTTP(rec, r)
+		v.finishResponse(route, rec, r)
+	})
+}
+
+// match finds the route of a request, preferring static segments to
+// parameters as the routers do, and returns its path parameters.
+func match(routes []*route, r *http.Request) (*route, map[string]string) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	var best *route
+	var bestParams map[string]string
+	bestStatic := -1
+	for _, candidate := range routes {
+		if candidate.Method != r.Method || len(candidate.segments) != len(segments) {
+			continue
+		}
+
+		params := map[string]string{}
+		static := 0
+		matched := true
+		for i, segment := range candidate.segments {
+			if name, ok := strings.CutPrefix(segment, "{"); ok {
+				params[strings.TrimSuffix(name, "}")] = segments[i]
+				continue
+			}
+			if segment != segments[i] {
+				matched = false
+				break
+			}
+			static++
+		}
+
+		if matched && static > bestStatic {
+			best, bestParams, bestStatic = candidate, params, static
+		}
+	}
+
+	return best, bestParams
+}
+
+func (v *Validator) validateRequest(route *route, pathParams map[string]string, r *http.Request) *models.Problem {
+	var errs []models.ProblemError
+
+	query := r.URL.Query()
+	for _, p := range route.parameters() {
+		var value string
+		var present bool
+		switch p.In {
+		case "path":
+			value, present = pathParams[p.Name]
+		case "query":
+			present = query.Has(p.Name)
+			value = query.Get(p.Name)
+		case "header":
+			value = r.Header.Get(p.Name)
+			present = value != ""
+		}
+
+		if !present {
+			if p.Required {
+				errs = append(errs, models.ProblemError{Parameter: p.Name, Detail: "is required"})
+			}
+			continue
+		}
+		if detail := v.schemas.validateString(p.Schema, value); detail != "" {
+			errs = append(errs, models.ProblemError{Parameter: p.Name, Detail: detail})
+		}
+	}
+
+	if route.Body != nil {
+		problem, bodyErrs := v.validateBody(route, r)
+		if problem != nil {
+			return problem
+		}
+		errs = append(errs, bodyErrs...)
+	}
+
+	if len(errs) > 0 {
+		return &models.Problem{
+			Type:   "about:blank",
+			Title:  "Request does not match the API description",
+			Status: http.StatusBadRequest,
+			Errors: errs,
+		}
+	}
+	return nil
+}
+
+// validateBody checks the content type and, for JSON, NDJSON and form bodies
+// no larger than the configured limit, the content of a request body. The
+// body is read into memory and put back for the handler.
+func (v *Validator) validateBody(route *route, r *http.Request) (*models.Problem, []models.ProblemError) {
+	data, complete, err := peekBody(r, v.cfg.MaxBodyBytes)
+	if err != nil {
+		return &models.Problem{Type: "about:blank", Title: "Could not read the request body", Status: http.StatusBadRequest}, nil
+	}
+	if len(data) == 0 && r.Header.Get("Content-Type") == "" {
+		if route.Body.Required {
+			return nil, []models.ProblemError{{Pointer: "", Detail: "A request body is required"}}
+		}
+		return nil, nil
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	schema, ok := route.body[mediaType]
+	if !ok {
+		return &models.Problem{
+			Type:   "about:blank",
+			Title:  "Unsupported content type",
+			Status: http.StatusUnsupportedMediaType,
+			Detail: fmt.Sprintf("Content-Type must be one of %s", strings.Join(slices.Sorted(maps.Keys(route.body)), ", ")),
+		}, nil
+	}
+	if !complete {
+		// Too large to check here; the handlers enforce their own limits
+		return nil, nil
+	}
+
+	var errs []models.ProblemError
+	switch mediaType {
+	case "application/json":
+		value, err := decodeJSON(data)
+		if err != nil {
+			return nil, []models.ProblemError{{Pointer: "", Detail: "Invalid JSON: " + err.Error()}}
+		}
+		v.schemas.validate(schema, value, "", &errs)
+	case "application/x-ndjson":
+		// Each line is pointed at by its index, as if the lines were an array
+		index := 0
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			pointer := "/" + strconv.Itoa(index)
+			value, err := decodeJSON(line)
+			if err != nil {
+				errs = append(errs, models.ProblemError{Pointer: pointer, Detail: "Invalid JSON: " + err.Error()})
+			} else {
+				v.schemas.validate(schema, value, pointer, &errs)
+			}
+			index++
+		}
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(data))
+		if err != nil {
+			return nil, []models.ProblemError{{Pointer: "", Detail: "Invalid form data"}}
+		}
+		errs = v.validateForm(schema, form, nil)
+	case "multipart/form-data":
+		form, err := multipart.NewReader(bytes.NewReader(data), params["boundary"]).ReadForm(int64(v.cfg.MaxBodyBytes))
+		if err != nil {
+			return nil, []models.ProblemError{{Pointer: "", Detail: "Invalid multipart form data"}}
+		}
+		defer form.RemoveAll()
+		errs = v.validateForm(schema, form.Value, form.File)
+	}
+
+	return nil, errs
+}
+
+// validateForm checks form fields against the properties of an object
+// schema. Array properties may be repeated.
+func (v *Validator) validateForm(schema Schema, values url.Values, files map[string][]*multipart.FileHeader) []models.ProblemError {
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = v.schemas.components[strings.TrimPrefix(ref, "#/components/schemas/")]
+	}
+	properties, _ := schema["properties"].(map[string]any)
+
+	var errs []models.ProblemError
+	if required, ok := schema["required"].([]string); ok {
+		for _, name := range required {
+			if _, ok := values[name]; !ok && len(files[name]) == 0 {
+				errs = append(errs, models.ProblemError{Pointer: "/" + escapePointer(name), Detail: "is required"})
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		fieldValues := values[name]
+		property, ok := properties[name].(Schema)
+		if !ok {
+			continue
+		}
+		if items, isArray := property["items"].(Schema); isArray {
+			property = items
+		} else {
+			fieldValues = fieldValues[:1]
+		}
+		for _, value := range fieldValues {
+			if detail := v.schemas.validateString(property, value); detail != "" {
+				errs = append(errs, models.ProblemError{Pointer: "/" + escapePointer(name), Detail: detail})
+			}
+		}
+	}
+
+	return errs
+}
+
+// finishResponse checks a response against the document. Buffered responses
+// that do not match are replaced with a 500 problem; streamed ones have
+// already been sent, so their mismatch is only logged.
+func (v *Validator) finishResponse(route *route, rec *responseRecorder, r *http.Request) {
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	body := rec.buf.Bytes()
+	contentType := rec.Header().Get("Content-Type")
+	if contentType == "" && len(body) > 0 {
+		contentType = http.DetectContentType(body)
+	}
+
+	var errs []models.ProblemError
+	contents, documented := route.responses[status]
+	switch {
+	case !documented:
+		errs = append(errs, models.ProblemError{Detail: fmt.Sprintf("Status %d is not documented", status)})
+	case len(body) > 0 || rec.passthrough && status != http.StatusNoContent && status != http.StatusSwitchingProtocols:
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		schema, ok := contents[mediaType]
+		switch {
+		case !ok:
+			errs = append(errs, models.ProblemError{Detail: fmt.Sprintf("Content-Type %q is not documented for status %d", contentType, status)})
+		case !rec.passthrough && isJSON(mediaType):
+			value, err := decodeJSON(body)
+			if err != nil {
+				errs = append(errs, models.ProblemError{Pointer: "", Detail: "Invalid JSON: " + err.Error()})
+			} else {
+				v.schemas.validate(schema, value, "", &errs)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		log.Printf("Response to %s %s does not match the API description: %+v", r.Method, r.URL.Path, errs)
+	}
+	if rec.passthrough {
+		return
+	}
+
+	if len(errs) > 0 {
+		rec.Header().Del("Content-Length")
+		writeProblem(rec.w, models.Problem{
+			Type:   "about:blank",
+			Title:  "Response does not match the API description",
+			Status: http.StatusInternalServerError,
+			Detail: fmt.Sprintf("The handler answered %d", status),
+			Errors: errs,
+		})
+		return
+	}
+	rec.w.WriteHeader(status)
+	rec.w.Write(body)
+}
+
+func writeProblem(w http.ResponseWriter, problem models.Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// peekBody reads up to limit bytes of the body and puts them back in front of
+// the rest, reporting whether that was the whole body.
+func peekBody(r *http.Request, limit int) ([]byte, bool, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+	if err != nil {
+		return nil, false, err
+	}
+
+	complete := len(data) <= limit
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(data), r.Body), Closer: r.Body}
+	return data, complete, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// responseRecorder buffers a JSON response so it can be checked before it is
+// sent. Responses of any other type, and those that are flushed or hijacked
+// as event streams and WebSockets are, pass straight through instead.
+type responseRecorder struct {
+	w           http.ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	passthrough bool
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.w.Header()
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status != 0 {
+		return
+	}
+	rec.status = status
+
+	if contentType := rec.Header().Get("Content-Type"); contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if !isJSON(mediaType) {
+			rec.startPassthrough()
+		}
+	}
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.passthrough {
+		return rec.w.Write(p)
+	}
+	return rec.buf.Write(p)
+}
+
+func (rec *responseRecorder) startPassthrough() {
+	if rec.passthrough {
+		return
+	}
+	rec.passthrough = true
+
+	if rec.status != 0 {
+		rec.w.WriteHeader(rec.status)
+	}
+	rec.w.Write(rec.buf.Bytes())
+	rec.buf.Reset()
+}
+
+func (rec *responseRecorder) Flush() {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.startPassthrough()
+	http.NewResponseController(rec.w).Flush()
+}
+
+func (rec *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rec.passthrough = true
+	rec.status = http.StatusSwitchingProtocols
+	return http.NewResponseController(rec.w).Hijack()
+}
+
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.w
+}
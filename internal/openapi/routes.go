@@ -0,0 +1,522 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/jobs"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/service"
+)
+
+// Route describes one operation of the API. Every router registers the same
+// routes and the OpenAPI document is built from Routes, so this list is the
+// one place the API is defined for clients.
+type Route struct {
+	Method string
+	// Path uses the OpenAPI {name} style for parameters.
+	Path        string
+	OperationID string
+	Summary     string
+	Description string
+	Tag         string
+	// Admin routes need the admin token and answer 403 without it.
+	Admin      bool
+	Parameters []Parameter
+	Body       *Body
+	Responses  []Response
+}
+
+type Parameter struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Schema      Schema
+}
+
+type Body struct {
+	Required bool
+	Content  []Content
+}
+
+type Response struct {
+	Status      int
+	Description string
+	Headers     map[string]string
+	Content     []Content
+}
+
+// Content is a media type and the schema of its body, given either as a
+// Schema or as a Go value whose type the schema is built from. The schema of
+// NDJSON content is that of each line.
+type Content struct {
+	MediaType string
+	Schema    any
+}
+
+const (
+	tagUsers    = "users"
+	tagImports  = "imports"
+	tagAudit    = "audit"
+	tagWebhooks = "webhooks"
+	tagJobs     = "jobs"
+	tagDocs     = "docs"
+	tagHealth   = "health"
+)
+
+func jsonContent(v any) []Content {
+	return []Content{{MediaType: "application/json", Schema: v}}
+}
+
+func formBody(v any) *Body {
+	return &Body{Required: true, Content: []Content{
+		{MediaType: "application/json", Schema: v},
+		{MediaType: "application/x-www-form-urlencoded", Schema: v},
+		{MediaType: "multipart/form-data", Schema: v},
+	}}
+}
+
+// Requests that do not match the document are rejected with a problem
+// details body when request validation is on.
+var problemContent = []Content{{MediaType: "application/problem+json", Schema: models.Problem{}}}
+
+func errorResponse(status int, description string) Response {
+	content := jsonContent(models.Error{})
+	if status == http.StatusBadRequest {
+		content = append(content, problemContent...)
+	}
+	return Response{Status: status, Description: description, Content: content}
+}
+
+func pathID(name, description, format string) Parameter {
+	return Parameter{
+		Name:        name,
+		In:          "path",
+		Description: description,
+		Required:    true,
+		Schema:      Schema{"type": "integer", "format": format, "minimum": 0},
+	}
+}
+
+func query(name, description string, schema Schema) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func enum(values ...string) Schema {
+	return Schema{"type": "string", "enum": values}
+}
+
+var (
+	userID = pathID("id", "User ID", "int32")
+
+	includeDeleted = query("include_deleted", "Include soft-deleted users, admins only", Schema{"type": "boolean"})
+
+	pagination = []Parameter{
+		query("limit", "Page size", Schema{"type": "integer", "minimum": 1, "maximum": 200, "default": 50}),
+		query("offset", "Number of items to skip", Schema{"type": "integer", "minimum": 0, "default": 0}),
+	}
+
+	auditFilters = append([]Parameter{
+		query("actor", "Only events by this actor", Schema{"type": "string"}),
+		query("action", "Only events with this action", Schema{"type": "string"}),
+		query("framework", "Only events made through this framework", Schema{"type": "string"}),
+		query("since", "Only events at or after this time", Schema{"type": "string", "format": "date-time"}),
+		query("until", "Only events before this time", Schema{"type": "string", "format": "date-time"}),
+	}, pagination...)
+
+	bulkMode = query("mode", "atomic applies every item or none, best_effort applies those that succeed",
+		Schema{"type": "string", "enum": []string{"atomic", "best_effort"}, "default": "atomic"})
+
+	bulkBody = &Body{Required: true, Content: []Content{
+		{MediaType: "application/json", Schema: []models.BulkItem{}},
+		{MediaType: "application/x-ndjson", Schema: models.BulkItem{}},
+	}}
+
+	bulkResponses = []Response{
+		{Status: http.StatusMultiStatus, Description: "Some items failed", Content: jsonContent(models.BulkResponse{})},
+		errorResponse(http.StatusBadRequest, "Invalid request or items"),
+		errorResponse(http.StatusConflict, "An atomic request failed on an item"),
+		errorResponse(http.StatusRequestEntityTooLarge, "Too many items or body too large"),
+		errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+	}
+
+	fileContent = []Content{
+		{MediaType: "multipart/form-data", Schema: Schema{
+			"type":       "object",
+			"properties": map[string]any{"file": Schema{"type": "string", "contentMediaType": "application/octet-stream"}},
+			"required":   []string{"file"},
+		}},
+		{MediaType: "text/csv", Schema: Schema{"type": "string"}},
+		{MediaType: "application/x-ndjson", Schema: Schema{"type": "object"}},
+	}
+)
+
+// Routes lists every route of the API.
+var Routes = []Route{
+	{
+		Method: http.MethodGet, Path: "/users", OperationID: "listUsers", Tag: tagUsers,
+		Summary:     "List users",
+		Description: "Returns every user, newest first, unless limit or offset is given, in which case one page of users ordered by ID is returned.",
+		Parameters:  append([]Parameter{includeDeleted}, pagination...),
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The users", Content: jsonContent([]models.User{})},
+			errorResponse(http.StatusBadRequest, "Invalid include_deleted value or pagination"),
+			errorResponse(http.StatusForbidden, "include_deleted without the admin token"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/users", OperationID: "createUser", Tag: tagUsers,
+		Summary: "Create a user",
+		Body:    formBody(models.CreateUserRequest{}),
+		Responses: []Response{
+			{Status: http.StatusCreated, Description: "The new user", Content: jsonContent(models.User{})},
+			errorResponse(http.StatusBadRequest, "Missing or invalid fields"),
+			errorResponse(http.StatusInternalServerError, "Error creating user"),
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/users/events", OperationID: "streamUserEvents", Tag: tagUsers,
+		Summary:     "Stream user events",
+		Description: "Server-sent events, one per user change, each carrying a UserEvent as JSON data. Reconnecting with Last-Event-ID replays the events missed.",
+		Parameters: []Parameter{
+			{Name: "Last-Event-ID", In: "header", Description: "Resume after this event", Schema: Schema{"type": "integer", "format": "int64"}},
+			query("last_event_id", "Resume after this event, for clients that cannot set headers", Schema{"type": "integer", "format": "int64"}),
+		},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "An event stream", Content: []Content{{MediaType: "text/event-stream", Schema: Schema{"type": "string"}}}},
+			errorResponse(http.StatusBadRequest, "Invalid event ID"),
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/users/export", OperationID: "exportUsers", Tag: tagUsers,
+		Summary:     "Export users",
+		Description: "Streams every user as CSV or NDJSON. The format comes from the format parameter, then the Accept header, and defaults to CSV. Exports are not paginated.",
+		Parameters: []Parameter{
+			query("format", "Export format", enum("csv", "ndjson")),
+			query("columns", "Comma separated columns to export, all by default", Schema{"type": "string"}),
+			query("excel", "Prefix CSV text a spreadsheet would run as a formula with '", Schema{"type": "boolean", "default": false}),
+			includeDeleted,
+		},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The users", Content: []Content{
+				{MediaType: "text/csv", Schema: Schema{"type": "string"}},
+				{MediaType: "application/x-ndjson", Schema: Schema{"type": "object"}},
+			}},
+			errorResponse(http.StatusBadRequest, "Invalid format, columns or excel, or a limit or offset"),
+			errorResponse(http.StatusForbidden, "include_deleted without the admin token"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/users/bulk", OperationID: "bulkCreateUsers", Tag: tagUsers,
+		Summary:    "Create users in bulk",
+		Parameters: []Parameter{bulkMode},
+		Body:       bulkBody,
+		Responses: append([]Response{
+			{Status: http.StatusCreated, Description: "Every user was created", Content: jsonContent(models.BulkResponse{})},
+		}, bulkResponses...),
+	},
+	{
+		Method: http.MethodPatch, Path: "/users/bulk", OperationID: "bulkUpdateUsers", Tag: tagUsers,
+		Summary:    "Update users in bulk",
+		Parameters: []Parameter{bulkMode},
+		Body:       bulkBody,
+		Responses: append([]Response{
+			{Status: http.StatusOK, Description: "Every user was updated", Content: jsonContent(models.BulkResponse{})},
+			errorResponse(http.StatusNotFound, "An atomic request named a missing user"),
+		}, bulkResponses...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/users/bulk", OperationID: "bulkDeleteUsers", Tag: tagUsers,
+		Summary:    "Delete users in bulk",
+		Parameters: []Parameter{bulkMode},
+		Body:       bulkBody,
+		Responses: append([]Response{
+			{Status: http.StatusOK, Description: "Every user was deleted", Content: jsonContent(models.BulkResponse{})},
+			errorResponse(http.StatusNotFound, "An atomic request named a missing user"),
+		}, bulkResponses...),
+	},
+	{
+		Method: http.MethodPost, Path: "/users/import", OperationID: "importUsers", Tag: tagImports, Admin: true,
+		Summary:     "Import users from a file",
+		Description: "Stores a CSV or NDJSON file and queues a job to import it. Poll the import at the Location header for progress.",
+		Parameters: []Parameter{
+			query("format", "File format, guessed from the content type or file name by default", enum("csv", "ndjson")),
+			query("on_duplicate", "What to do with rows whose email is in use",
+				Schema{"type": "string", "enum": []string{service.DuplicateSkip, service.DuplicateFail, service.DuplicateUpsert}, "default": service.DuplicateFail}),
+			query("dry_run", "Validate every row without saving anything", Schema{"type": "boolean"}),
+			query("mapping", "Source columns of the user fields, such as name:Full Name,email:Mail", Schema{"type": "string"}),
+		},
+		Body: &Body{Required: true, Content: fileContent},
+		Responses: []Response{
+			{
+				Status: http.StatusAccepted, Description: "The import was queued",
+				Headers: map[string]string{"Location": "URL of the import"},
+				Content: jsonContent(models.UserImport{}),
+			},
+			errorResponse(http.StatusBadRequest, "Invalid options or empty file"),
+			errorResponse(http.StatusRequestEntityTooLarge, "File too large"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/users/{id}", OperationID: "getUser", Tag: tagUsers,
+		Summary:    "Get a user",
+		Parameters: []Parameter{userID, includeDeleted},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The user", Content: jsonContent(models.User{})},
+			errorResponse(http.StatusBadRequest, "Invalid user ID"),
+			errorResponse(http.StatusForbidden, "include_deleted without the admin token"),
+			errorResponse(http.StatusNotFound, "User not found"),
+		},
+	},
+	{
+		Method: http.MethodPut, Path: "/users/{id}", OperationID: "updateUser", Tag: tagUsers,
+		Summary:    "Update a user",
+		Parameters: []Parameter{userID},
+		Body:       formBody(models.UpdateUserRequest{}),
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The updated user", Content: jsonContent(models.User{})},
+			errorResponse(http.StatusBadRequest, "Invalid user ID or fields"),
+			errorResponse(http.StatusNotFound, "User not found"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodDelete, Path: "/users/{id}", OperationID: "deleteUser", Tag: tagUsers,
+		Summary:     "Delete a user",
+		Description: "Soft-deletes the user, who can be restored until purged.",
+		Parameters:  []Parameter{userID},
+		Responses: []Response{
+			{Status: http.StatusNoContent, Description: "The user was deleted"},
+			errorResponse(http.StatusBadRequest, "Invalid user ID"),
+			errorResponse(http.StatusNotFound, "User not found"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/users/{id}/restore", OperationID: "restoreUser", Tag: tagUsers, Admin: true,
+		Summary:    "Restore a deleted user",
+		Parameters: []Parameter{userID},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The restored user", Content: jsonContent(models.User{})},
+			errorResponse(http.StatusBadRequest, "Invalid user ID"),
+			errorResponse(http.StatusNotFound, "Deleted user not found"),
+			errorResponse(http.StatusConflict, "Email is in use by another user"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/users/{id}/history", OperationID: "getUserHistory", Tag: tagAudit, Admin: true,
+		Summary:    "List the audit events of a user",
+		Parameters: append([]Parameter{userID}, auditFilters...),
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "A page of audit events", Content: jsonContent(models.AuditEventPage{})},
+			errorResponse(http.StatusBadRequest, "Invalid user ID or filters"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/audit", OperationID: "listAuditEvents", Tag: tagAudit, Admin: true,
+		Summary: "List audit events",
+		Parameters: append([]Parameter{
+			query("user_id", "Only events of this user", Schema{"type": "integer", "format": "int32"}),
+		}, auditFilters...),
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "A page of audit events", Content: jsonContent(models.AuditEventPage{})},
+			errorResponse(http.StatusBadRequest, "Invalid filters"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/ws", OperationID: "userSocket", Tag: tagUsers,
+		Summary: "Subscribe to user events over a WebSocket",
+		Description: `Clients send {"action": "subscribe", "user_ids": [1]} or {"action": "subscribe", "all": true}, ` +
+			`and "unsubscribe" likewise, and receive {"type": "event", "event": UserEvent} messages.`,
+		Responses: []Response{
+			{Status: http.StatusSwitchingProtocols, Description: "The connection was upgraded"},
+			errorResponse(http.StatusBadRequest, "Not a WebSocket handshake"),
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/imports/{id}", OperationID: "getImport", Tag: tagImports, Admin: true,
+		Summary:    "Get an import",
+		Parameters: []Parameter{pathID("id", "Import ID", "int32")},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The import and its progress", Content: jsonContent(models.UserImport{})},
+			errorResponse(http.StatusBadRequest, "Invalid import ID"),
+			errorResponse(http.StatusNotFound, "Import not found"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/imports/{id}/errors", OperationID: "getImportErrors", Tag: tagImports, Admin: true,
+		Summary:    "Download the rows an import rejected",
+		Parameters: []Parameter{pathID("id", "Import ID", "int32")},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "A CSV report with row, email and error columns", Content: []Content{{MediaType: "text/csv", Schema: Schema{"type": "string"}}}},
+			errorResponse(http.StatusBadRequest, "Invalid import ID"),
+			errorResponse(http.StatusNotFound, "Import not found"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+
+	{
+		Method: http.MethodGet, Path: "/webhooks", OperationID: "listWebhooks", Tag: tagWebhooks, Admin: true,
+		Summary: "List webhooks",
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The webhooks", Content: jsonContent([]models.Webhook{})},
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/webhooks", OperationID: "createWebhook", Tag: tagWebhooks, Admin: true,
+		Summary:     "Create a webhook",
+		Description: "events may be repeated or comma separated, and subscribes to every event when empty.",
+		Body:        formBody(models.WebhookRequest{}),
+		Responses: []Response{
+			{Status: http.StatusCreated, Description: "The new webhook, including its secret", Content: jsonContent(models.Webhook{})},
+			errorResponse(http.StatusBadRequest, "Missing or invalid fields"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/webhooks/{id}", OperationID: "getWebhook", Tag: tagWebhooks, Admin: true,
+		Summary:    "Get a webhook",
+		Parameters: []Parameter{pathID("id", "Webhook ID", "int32")},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The webhook", Content: jsonContent(models.Webhook{})},
+			errorResponse(http.StatusBadRequest, "Invalid webhook ID"),
+			errorResponse(http.StatusNotFound, "Webhook not found"),
+		},
+	},
+	{
+		Method: http.MethodPut, Path: "/webhooks/{id}", OperationID: "updateWebhook", Tag: tagWebhooks, Admin: true,
+		Summary:     "Update a webhook",
+		Description: "Setting active to true re-enables a webhook disabled after repeated failures.",
+		Parameters:  []Parameter{pathID("id", "Webhook ID", "int32")},
+		Body:        formBody(models.WebhookRequest{}),
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The updated webhook", Content: jsonContent(models.Webhook{})},
+			errorResponse(http.StatusBadRequest, "Invalid webhook ID or fields"),
+			errorResponse(http.StatusNotFound, "Webhook not found"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodDelete, Path: "/webhooks/{id}", OperationID: "deleteWebhook", Tag: tagWebhooks, Admin: true,
+		Summary:    "Delete a webhook",
+		Parameters: []Parameter{pathID("id", "Webhook ID", "int32")},
+		Responses: []Response{
+			{Status: http.StatusNoContent, Description: "The webhook was deleted"},
+			errorResponse(http.StatusBadRequest, "Invalid webhook ID"),
+			errorResponse(http.StatusNotFound, "Webhook not found"),
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/webhooks/{id}/deliveries", OperationID: "listWebhookDeliveries", Tag: tagWebhooks, Admin: true,
+		Summary:    "List the deliveries of a webhook",
+		Parameters: append([]Parameter{pathID("id", "Webhook ID", "int32")}, pagination...),
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "A page of deliveries", Content: jsonContent(models.WebhookDeliveryPage{})},
+			errorResponse(http.StatusBadRequest, "Invalid webhook ID or pagination"),
+			errorResponse(http.StatusNotFound, "Webhook not found"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/webhooks/{id}/deliveries/{delivery_id}/redeliver", OperationID: "redeliverWebhook", Tag: tagWebhooks, Admin: true,
+		Summary: "Send a delivery again",
+		Parameters: []Parameter{
+			pathID("id", "Webhook ID", "int32"),
+			pathID("delivery_id", "Delivery ID", "int64"),
+		},
+		Responses: []Response{
+			{Status: http.StatusAccepted, Description: "The delivery was queued again", Content: jsonContent(models.WebhookDelivery{})},
+			errorResponse(http.StatusBadRequest, "Invalid webhook or delivery ID"),
+			errorResponse(http.StatusNotFound, "Delivery not found"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+
+	{
+		Method: http.MethodGet, Path: "/jobs", OperationID: "listJobs", Tag: tagJobs, Admin: true,
+		Summary: "List background jobs",
+		Parameters: append([]Parameter{
+			query("status", "Only jobs with this status", enum(jobs.Statuses...)),
+			query("kind", "Only jobs of this kind", Schema{"type": "string"}),
+		}, pagination...),
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "A page of jobs, newest first", Content: jsonContent(models.JobPage{})},
+			errorResponse(http.StatusBadRequest, "Invalid filters"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/jobs/{id}", OperationID: "getJob", Tag: tagJobs, Admin: true,
+		Summary:    "Get a job",
+		Parameters: []Parameter{pathID("id", "Job ID", "int64")},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The job", Content: jsonContent(models.Job{})},
+			errorResponse(http.StatusBadRequest, "Invalid job ID"),
+			errorResponse(http.StatusNotFound, "Job not found"),
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/jobs/{id}/retry", OperationID: "retryJob", Tag: tagJobs, Admin: true,
+		Summary:    "Retry a failed or cancelled job",
+		Parameters: []Parameter{pathID("id", "Job ID", "int64")},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The queued job", Content: jsonContent(models.Job{})},
+			errorResponse(http.StatusBadRequest, "Invalid job ID"),
+			errorResponse(http.StatusNotFound, "Job not found"),
+			errorResponse(http.StatusConflict, "The job is not failed or cancelled"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/jobs/{id}/cancel", OperationID: "cancelJob", Tag: tagJobs, Admin: true,
+		Summary:    "Cancel a queued or running job",
+		Parameters: []Parameter{pathID("id", "Job ID", "int64")},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The cancelled job", Content: jsonContent(models.Job{})},
+			errorResponse(http.StatusBadRequest, "Invalid job ID"),
+			errorResponse(http.StatusNotFound, "Job not found"),
+			errorResponse(http.StatusConflict, "The job is not queued or running"),
+			errorResponse(http.StatusInternalServerError, "Internal Server Error"),
+		},
+	},
+
+	{
+		Method: http.MethodGet, Path: "/openapi.json", OperationID: "getOpenAPI", Tag: tagDocs,
+		Summary: "This OpenAPI document",
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The OpenAPI document", Content: jsonContent(Schema{"type": "object"})},
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/docs", OperationID: "getDocs", Tag: tagDocs,
+		Summary: "API documentation",
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "A page rendering this OpenAPI document", Content: []Content{{MediaType: "text/html", Schema: Schema{"type": "string"}}}},
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/healthz", OperationID: "checkHealth", Tag: tagHealth,
+		Summary:     "Liveness check",
+		Description: "Answers as long as the server is up. It is never shed under load.",
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The server is up", Content: jsonContent(models.Health{})},
+		},
+	},
+	{
+		Method: http.MethodGet, Path: "/readyz", OperationID: "checkReadiness", Tag: tagHealth,
+		Summary:     "Readiness check",
+		Description: "Answers whether the server can serve requests that use the database, with the state of its circuit breaker. It is never shed under load.",
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The server is ready", Content: jsonContent(models.Readiness{})},
+			{Status: http.StatusServiceUnavailable, Description: "The database cannot be used", Content: jsonContent(models.Readiness{})},
+		},
+	},
+}
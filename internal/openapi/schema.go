@@ -0,0 +1,102 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Schema is a JSON Schema object, the dialect OpenAPI 3.1 uses.
+type Schema map[string]any
+
+// Types that marshal to something other than their fields.
+var knownSchemas = map[reflect.Type]Schema{
+	reflect.TypeFor[time.Time]():          {"type": "string", "format": "date-time"},
+	reflect.TypeFor[pgtype.Timestamptz](): {"type": []string{"string", "null"}, "format": "date-time"},
+	reflect.TypeFor[pgtype.Text]():        {"type": []string{"string", "null"}},
+	reflect.TypeFor[pgtype.Int4]():        {"type": []string{"integer", "null"}, "format": "int32"},
+	reflect.TypeFor[json.RawMessage]():    {},
+}
+
+// schemas turns Go types into schemas, keeping named structs under
+// components/schemas and referring to them by name.
+type schemas struct {
+	components map[string]Schema
+}
+
+// of returns the schema of the type of v, or v itself if it is a Schema.
+func (s *schemas) of(v any) Schema {
+	if schema, ok := v.(Schema); ok {
+		return schema
+	}
+	return s.ofType(reflect.TypeOf(v))
+}
+
+func (s *schemas) ofType(t reflect.Type) Schema {
+	if schema, ok := knownSchemas[t]; ok {
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.ofType(t.Elem())
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return Schema{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": "array", "items": s.ofType(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": s.ofType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		if _, ok := s.components[t.Name()]; !ok {
+			// Placeholder first, so types that refer to themselves end
+			s.components[t.Name()] = Schema{}
+			s.components[t.Name()] = s.object(t)
+		}
+		return Schema{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return Schema{}
+}
+
+// object follows encoding/json in naming the fields, and makes every field
+// required unless it is a pointer or omitted when empty.
+func (s *schemas) object(t reflect.Type) Schema {
+	properties := map[string]any{}
+	required := []string{}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = s.ofType(field.Type)
+		if field.Type.Kind() != reflect.Pointer && !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	return Schema{"type": "object", "properties": properties, "required": required}
+}
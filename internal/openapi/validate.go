@@ -0,0 +1,202 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+)
+
+// The validation below covers the parts of JSON Schema the document uses:
+// $ref, type, enum, format, minimum, maximum, properties, required, items and
+// additionalProperties.
+
+// validate checks a value decoded with json.Decoder.UseNumber against schema,
+// appending an error for each mismatch found under pointer.
+func (s *schemas) validate(schema Schema, value any, pointer string, errs *[]models.ProblemError) {
+	if ref, ok := schema["$ref"].(string); ok {
+		s.validate(s.components[strings.TrimPrefix(ref, "#/components/schemas/")], value, pointer, errs)
+		return
+	}
+
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, models.ProblemError{Pointer: pointer, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 && !slices.Contains(types, jsonType(value)) {
+		// Integers are numbers too
+		if !(jsonType(value) == "integer" && slices.Contains(types, "number")) {
+			fail("must be %s", typeNames(types))
+			return
+		}
+	}
+
+	if values, ok := schema["enum"].([]string); ok {
+		if str, isString := value.(string); !isString || !slices.Contains(values, str) {
+			fail("must be one of %s", strings.Join(values, ", "))
+			return
+		}
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		n, err := v.Float64()
+		if err != nil {
+			fail("must be a number")
+			return
+		}
+		if detail := checkNumber(schema, n); detail != "" {
+			fail("%s", detail)
+		}
+	case string:
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				fail("must be an RFC 3339 date-time")
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(Schema); ok {
+			for i, item := range v {
+				s.validate(items, item, pointer+"/"+strconv.Itoa(i), errs)
+			}
+		}
+	case map[string]any:
+		s.validateObject(schema, v, pointer, errs)
+	}
+}
+
+func (s *schemas) validateObject(schema Schema, object map[string]any, pointer string, errs *[]models.ProblemError) {
+	if required, ok := schema["required"].([]string); ok {
+		for _, name := range required {
+			if _, ok := object[name]; !ok {
+				*errs = append(*errs, models.ProblemError{Pointer: pointer + "/" + escapePointer(name), Detail: "is required"})
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	additional, _ := schema["additionalProperties"].(Schema)
+	for _, name := range slices.Sorted(maps.Keys(object)) {
+		value := object[name]
+		if property, ok := properties[name].(Schema); ok {
+			s.validate(property, value, pointer+"/"+escapePointer(name), errs)
+		} else if additional != nil {
+			s.validate(additional, value, pointer+"/"+escapePointer(name), errs)
+		}
+	}
+}
+
+// validateString checks a value that arrives as text, such as a query
+// parameter or a form field, returning what is wrong with it or "".
+func (s *schemas) validateString(schema Schema, value string) string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return s.validateString(s.components[strings.TrimPrefix(ref, "#/components/schemas/")], value)
+	}
+
+	types := schemaTypes(schema)
+	switch {
+	case slices.Contains(types, "integer"):
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "must be an integer"
+		}
+		return checkNumber(schema, float64(n))
+	case slices.Contains(types, "number"):
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "must be a number"
+		}
+		return checkNumber(schema, n)
+	case slices.Contains(types, "boolean"):
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "must be true or false"
+		}
+	case slices.Contains(types, "string"):
+		if values, ok := schema["enum"].([]string); ok && !slices.Contains(values, value) {
+			return "must be one of " + strings.Join(values, ", ")
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				return "must be an RFC 3339 date-time"
+			}
+		}
+	}
+	return ""
+}
+
+func checkNumber(schema Schema, n float64) string {
+	switch schema["format"] {
+	case "int32":
+		if n != math.Trunc(n) || n < math.MinInt32 || n > math.MaxInt32 {
+			return "must be a 32-bit integer"
+		}
+	case "int64":
+		if n != math.Trunc(n) || n < math.MinInt64 || n > math.MaxInt64 {
+			return "must be a 64-bit integer"
+		}
+	}
+
+	if minimum, ok := schema["minimum"].(int); ok && n < float64(minimum) {
+		return fmt.Sprintf("must be at least %d", minimum)
+	}
+	if maximum, ok := schema["maximum"].(int); ok && n > float64(maximum) {
+		return fmt.Sprintf("must be at most %d", maximum)
+	}
+	return ""
+}
+
+func schemaTypes(schema Schema) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	}
+	return nil
+}
+
+func typeNames(types []string) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		switch t {
+		case "integer", "array", "object":
+			names[i] = "an " + t
+		case "null":
+			names[i] = t
+		default:
+			names[i] = "a " + t
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return ""
+}
+
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
@@ -0,0 +1,179 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	statusPending = "pending"
+	statusDead    = "dead"
+
+	baseBackoff    = time.Second
+	maxBackoff     = 10 * time.Minute
+	publishTimeout = 10 * time.Second
+	// leaseMargin is the time a lease allows for recording the outcome of
+	// an event once its sinks are done.
+	leaseMargin = 30 * time.Second
+)
+
+// Dispatcher moves events from the outbox table to the sinks.
+type Dispatcher struct {
+	cfg   *config.APIConfig
+	sinks []Sink
+}
+
+func NewDispatcher(cfg *config.APIConfig, sinks []Sink) *Dispatcher {
+	return &Dispatcher{cfg: cfg, sinks: sinks}
+}
+
+// Run polls the outbox until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Outbox.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while batches make progress; only the head event of
+		// each user is claimable per batch, so a busy user needs several.
+		for {
+			dispatched, err := d.dispatchBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Error dispatching outbox events: %v", err)
+				}
+				break
+			}
+			if dispatched == 0 {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchBatch claims a batch of events, publishes them and records the
+// outcome, returning how many were published to every sink. The events are
+// published concurrently, outside any transaction, so a slow sink holds no
+// locks or connections, and a batch takes no longer than its slowest event.
+func (d *Dispatcher) dispatchBatch(ctx context.Context) (int, error) {
+	rows, err := d.cfg.DB.ClaimOutboxEvents(ctx, database.ClaimOutboxEventsParams{
+		LeaseUntil: pgtype.Timestamptz{Time: time.Now().Add(d.lease()), Valid: true},
+		MaxEvents:  int32(d.cfg.Outbox.BatchSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		dispatched int
+		errs       []error
+	)
+	for _, row := range rows {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			published, err := d.dispatch(ctx, row)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if published {
+				dispatched++
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("event %d: %w", row.ID, err))
+			}
+		}()
+	}
+	wg.Wait()
+
+	return dispatched, errors.Join(errs...)
+}
+
+// lease is how long claimed events are kept from other dispatchers: long
+// enough for every sink to time out on one, and the outcome to be recorded.
+// Events whose dispatcher stops are published again once it runs out.
+func (d *Dispatcher) lease() time.Duration {
+	return time.Duration(len(d.sinks))*publishTimeout + leaseMargin
+}
+
+// dispatch publishes an event to the sinks it has not been published to yet,
+// noting each that succeeds so a retry skips it, and records the outcome. It
+// reports whether the event has now been published to every sink.
+func (d *Dispatcher) dispatch(ctx context.Context, row database.OutboxEvent) (bool, error) {
+	event := fromDatabaseEvent(row)
+	var errs []error
+
+	for _, sink := range d.sinks {
+		if slices.Contains(row.PublishedTo, sink.Name()) {
+			continue
+		}
+
+		sinkCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+		err := sink.Publish(sinkCtx, event)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			continue
+		}
+
+		if err := d.cfg.DB.MarkOutboxEventPublished(ctx, database.MarkOutboxEventPublishedParams{
+			Sink: sink.Name(),
+			ID:   row.ID,
+		}); err != nil {
+			return false, err
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return false, d.markFailed(ctx, row, err)
+	}
+	return true, d.cfg.DB.MarkOutboxEventDispatched(ctx, row.ID)
+}
+
+func (d *Dispatcher) markFailed(ctx context.Context, row database.OutboxEvent, publishErr error) error {
+	attempts := int(row.Attempts) + 1
+
+	status := statusPending
+	if attempts >= d.cfg.Outbox.MaxAttempts {
+		status = statusDead
+		log.Printf("Outbox event %d (%s) dead-lettered after %d attempts: %v", row.ID, row.EventType, attempts, publishErr)
+	}
+
+	return d.cfg.DB.MarkOutboxEventFailed(ctx, database.MarkOutboxEventFailedParams{
+		ID:            row.ID,
+		Status:        status,
+		LastError:     pgtype.Text{String: publishErr.Error(), Valid: true},
+		NextAttemptAt: pgtype.Timestamptz{Time: time.Now().Add(Backoff(attempts)), Valid: true},
+	})
+}
+
+// Backoff returns how long to wait before retry number attempt: exponential
+// from one second, capped at ten minutes, with up to 20% jitter so retries
+// from many events spread out.
+func Backoff(attempt int) time.Duration {
+	attempt = max(attempt, 1)
+
+	backoff := maxBackoff
+	if attempt < 20 {
+		backoff = min(baseBackoff<<(attempt-1), maxBackoff)
+	}
+
+	return backoff + rand.N(backoff/5+1)
+}
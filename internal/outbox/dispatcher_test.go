@@ -0,0 +1,259 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database/dbtest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// table emulates the outbox_events table for the queries of the
+// dispatcher, with a clock of its own for CURRENT_TIMESTAMP.
+type table struct {
+	mu   sync.Mutex
+	now  time.Time
+	rows []*database.OutboxEvent
+}
+
+func (t *table) add(userID int32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rows = append(t.rows, &database.OutboxEvent{
+		ID:            int64(len(t.rows) + 1),
+		UserID:        userID,
+		EventType:     UserUpdated,
+		Status:        statusPending,
+		NextAttemptAt: pgtype.Timestamptz{Time: t.now, Valid: true},
+		CreatedAt:     pgtype.Timestamptz{Time: t.now, Valid: true},
+	})
+}
+
+// advance moves the clock of the database on by d.
+func (t *table) advance(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.now = t.now.Add(d)
+}
+
+func (t *table) event(id int64) database.OutboxEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return *t.rows[id-1]
+}
+
+func (t *table) find(id int64) *database.OutboxEvent {
+	return t.rows[id-1]
+}
+
+func (t *table) db() *dbtest.DB {
+	db := dbtest.New()
+	db.Handle("ClaimOutboxEvents", func(args ...any) ([][]any, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		leaseUntil, maxEvents := args[0].(pgtype.Timestamptz), int(args[1].(int32))
+
+		var claimed []database.OutboxEvent
+		heads := map[int32]bool{}
+		for _, row := range t.rows {
+			if row.Status != statusPending || len(claimed) == maxEvents {
+				continue
+			}
+			// Only the oldest pending event of each user may be claimed
+			head := !heads[row.UserID]
+			heads[row.UserID] = true
+			if head && !row.NextAttemptAt.Time.After(t.now) {
+				row.NextAttemptAt = leaseUntil
+				claimed = append(claimed, *row)
+			}
+		}
+		return dbtest.Rows(claimed...), nil
+	})
+	db.Handle("MarkOutboxEventPublished", func(args ...any) ([][]any, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		row := t.find(args[1].(int64))
+		if sink := args[0].(string); !slices.Contains(row.PublishedTo, sink) {
+			row.PublishedTo = append(row.PublishedTo, sink)
+		}
+		return [][]any{nil}, nil
+	})
+	db.Handle("MarkOutboxEventDispatched", func(args ...any) ([][]any, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		row := t.find(args[0].(int64))
+		row.Status = "dispatched"
+		row.Attempts++
+		row.LastError = pgtype.Text{}
+		row.DispatchedAt = pgtype.Timestamptz{Time: t.now, Valid: true}
+		return [][]any{nil}, nil
+	})
+	db.Handle("MarkOutboxEventFailed", func(args ...any) ([][]any, error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		row := t.find(args[0].(int64))
+		row.Status = args[1].(string)
+		row.Attempts++
+		row.LastError = args[2].(pgtype.Text)
+		row.NextAttemptAt = args[3].(pgtype.Timestamptz)
+		return [][]any{nil}, nil
+	})
+	return db
+}
+
+// sink records the events it publishes, failing those fail says to, given
+// the event and how many times it has been published to the sink, this time
+// included.
+type sink struct {
+	name string
+	fail func(event Event, call int) bool
+
+	mu        sync.Mutex
+	calls     map[int64]int
+	published []Event
+}
+
+func newSink(name string, fail func(Event, int) bool) *sink {
+	return &sink{name: name, fail: fail, calls: map[int64]int{}}
+}
+
+func (s *sink) Name() string { return s.name }
+
+func (s *sink) Publish(_ context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[event.ID]++
+	if s.fail != nil && s.fail(event, s.calls[event.ID]) {
+		return errors.New("unavailable")
+	}
+	s.published = append(s.published, event)
+	return nil
+}
+
+// order returns the ids of the events of userID the sink published, in
+// order.
+func (s *sink) order(userID int32) []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []int64
+	for _, event := range s.published {
+		if event.UserID == userID {
+			ids = append(ids, event.ID)
+		}
+	}
+	return ids
+}
+
+func newDispatcher(maxAttempts int, sinks ...Sink) (*Dispatcher, *table) {
+	t := &table{now: time.Now()}
+	return NewDispatcher(&config.APIConfig{
+		DB:     database.New(t.db()),
+		Outbox: config.OutboxConfig{BatchSize: 10, MaxAttempts: maxAttempts},
+	}, sinks), t
+}
+
+func dispatch(t *testing.T, d *Dispatcher) int {
+	t.Helper()
+	n, _ := d.dispatchBatch(context.Background())
+	return n
+}
+
+func TestEventsOfAUserArePublishedInOrder(t *testing.T) {
+	// The first event of user 1 fails once
+	s := newSink("log", func(e Event, call int) bool { return e.ID == 1 && call == 1 })
+	d, table := newDispatcher(5, s)
+	for _, userID := range []int32{1, 1, 1, 2} {
+		table.add(userID)
+	}
+
+	// Only the head event of each user is claimed, and user 2 is not held
+	// up by user 1
+	if n := dispatch(t, d); n != 1 || !slices.Equal(s.order(2), []int64{4}) {
+		t.Fatalf("first batch published %d events, user 2 got %v, want event 4 alone", n, s.order(2))
+	}
+
+	// Until its failed event is due again, the later events of user 1 wait
+	if n := dispatch(t, d); n != 0 {
+		t.Fatalf("published %d events behind a failed one, want none", n)
+	}
+
+	table.advance(time.Minute)
+	for dispatch(t, d) > 0 {
+	}
+	if got := s.order(1); !slices.Equal(got, []int64{1, 2, 3}) {
+		t.Fatalf("user 1 events published as %v, want 1, 2, 3", got)
+	}
+}
+
+func TestRetryPublishesOnlyToTheSinksThatFailed(t *testing.T) {
+	ok := newSink("log", nil)
+	failOnce := newSink("webhook", func(_ Event, call int) bool { return call == 1 })
+	d, table := newDispatcher(5, ok, failOnce)
+	table.add(1)
+
+	if n := dispatch(t, d); n != 0 {
+		t.Fatalf("published %d events with a sink failing, want none", n)
+	}
+	event := table.event(1)
+	if event.Status != statusPending || event.Attempts != 1 || !slices.Equal(event.PublishedTo, []string{"log"}) {
+		t.Fatalf("after the failure: status %s, %d attempts, published to %v, want pending, 1 and the log sink", event.Status, event.Attempts, event.PublishedTo)
+	}
+
+	table.advance(time.Minute)
+	if n := dispatch(t, d); n != 1 {
+		t.Fatalf("retry published %d events, want 1", n)
+	}
+	if ok.calls[1] != 1 || failOnce.calls[1] != 2 {
+		t.Errorf("published to log %d and webhook %d times, want 1 and 2", ok.calls[1], failOnce.calls[1])
+	}
+	event = table.event(1)
+	if event.Status != "dispatched" || !slices.Equal(event.PublishedTo, []string{"log", "webhook"}) {
+		t.Errorf("after the retry: status %s, published to %v, want dispatched to both", event.Status, event.PublishedTo)
+	}
+}
+
+func TestClaimedEventsAreLeasedUntilTheLeaseRunsOut(t *testing.T) {
+	s := newSink("log", nil)
+	d, table := newDispatcher(5, s)
+	table.add(1)
+
+	// A dispatcher claims the event and stops before publishing it
+	_, err := d.cfg.DB.ClaimOutboxEvents(context.Background(), database.ClaimOutboxEventsParams{
+		LeaseUntil: pgtype.Timestamptz{Time: table.now.Add(d.lease()), Valid: true},
+		MaxEvents:  10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table.advance(d.lease() - time.Second)
+	if n := dispatch(t, d); n != 0 {
+		t.Fatalf("published %d leased events, want none", n)
+	}
+
+	table.advance(2 * time.Second)
+	if n := dispatch(t, d); n != 1 || s.calls[1] != 1 {
+		t.Fatalf("published %d events once the lease ran out, want the event", n)
+	}
+}
+
+func TestEventsAreDeadLetteredAfterMaxAttempts(t *testing.T) {
+	s := newSink("log", func(Event, int) bool { return true })
+	d, table := newDispatcher(2, s)
+	table.add(1)
+
+	for range 3 {
+		dispatch(t, d)
+		table.advance(time.Hour)
+	}
+	event := table.event(1)
+	if event.Status != statusDead || event.Attempts != 2 || s.calls[1] != 2 {
+		t.Fatalf("status %s after %d attempts and %d publishes, want dead after 2", event.Status, event.Attempts, s.calls[1])
+	}
+}
@@ -0,0 +1,56 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+)
+
+const (
+	UserCreated  = "user.created"
+	UserUpdated  = "user.updated"
+	UserDeleted  = "user.deleted"
+	UserRestored = "user.restored"
+	UserPurged   = "user.purged"
+)
+
+// EventTypes lists every event type, in the order they are documented.
+var EventTypes = []string{UserCreated, UserUpdated, UserDeleted, UserRestored, UserPurged}
+
+// Event is what sinks receive. Data holds the user as it looked right after
+// the change.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	UserID    int32           `json:"user_id"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Enqueue stores an event for user. q must be bound to the transaction making
+// the change so the event is only published if that transaction commits.
+func Enqueue(ctx context.Context, q *database.Queries, eventType string, user database.User) error {
+	payload, err := json.Marshal(models.FromDatabaseUser(user))
+	if err != nil {
+		return err
+	}
+
+	return q.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{
+		UserID:    user.ID,
+		EventType: eventType,
+		Payload:   payload,
+	})
+}
+
+func fromDatabaseEvent(row database.OutboxEvent) Event {
+	return Event{
+		ID:        row.ID,
+		Type:      row.EventType,
+		UserID:    row.UserID,
+		Data:      row.Payload,
+		CreatedAt: row.CreatedAt.Time,
+	}
+}
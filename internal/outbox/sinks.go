@@ -0,0 +1,152 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+)
+
+// A Sink publishes events somewhere outside the database. Publish must be safe
+// to call again for an event it has already seen: delivery is at least once.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event Event) error
+}
+
+// NewSinks builds the sinks listed in cfg.Outbox.Sinks.
+func NewSinks(cfg *config.APIConfig) ([]Sink, error) {
+	var sinks []Sink
+
+	for _, name := range cfg.Outbox.Sinks {
+		switch name {
+		case "log":
+			w := io.Writer(os.Stdout)
+			if cfg.Outbox.LogFile != "" {
+				f, err := os.OpenFile(cfg.Outbox.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+				if err != nil {
+					return nil, fmt.Errorf("failed to open outbox log file: %w", err)
+				}
+				w = f
+			}
+			sinks = append(sinks, NewLogSink(w))
+		case "webhook":
+			if cfg.Outbox.WebhookURL == "" {
+				return nil, fmt.Errorf("webhook sink requires OUTBOX_WEBHOOK_URL")
+			}
+			sinks = append(sinks, NewWebhookSink(cfg.Outbox.WebhookURL, nil))
+		case "notify":
+			sinks = append(sinks, NewNotifySink(cfg.DB, cfg.Outbox.NotifyChannel))
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+
+	return sinks, nil
+}
+
+// LogSink writes each event as a line of JSON.
+type LogSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLogSink(w io.Writer) *LogSink {
+	return &LogSink{w: w}
+}
+
+func (s *LogSink) Name() string { return "log" }
+
+func (s *LogSink) Publish(_ context.Context, event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.NewEncoder(s.w).Encode(event)
+}
+
+// WebhookSink POSTs each event as JSON to a single URL and treats any non-2xx
+// response as a failure.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &WebhookSink{url: url, client: client}
+}
+
+func (s *WebhookSink) Name() string { return "webhook" }
+
+func (s *WebhookSink) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(event.ID, 10))
+	req.Header.Set("X-Event-Type", event.Type)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+// NOTIFY payloads are limited to 8000 bytes by PostgreSQL.
+const maxNotifyPayload = 7999
+
+// NotifySink publishes events with PostgreSQL NOTIFY so that every process
+// LISTENing on the channel hears about them.
+type NotifySink struct {
+	db      *database.Queries
+	channel string
+}
+
+func NewNotifySink(db *database.Queries, channel string) *NotifySink {
+	return &NotifySink{db: db, channel: channel}
+}
+
+func (s *NotifySink) Name() string { return "notify" }
+
+func (s *NotifySink) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// Too big to send whole: listeners get the event without its data and
+	// can fetch the user themselves.
+	if len(payload) > maxNotifyPayload {
+		event.Data = nil
+		if payload, err = json.Marshal(event); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Notify(ctx, database.NotifyParams{Channel: s.channel, Payload: string(payload)})
+}
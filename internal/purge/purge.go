@@ -0,0 +1,41 @@
+package purge
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/jobs"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/service"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// JobKind is the kind of the recurring purge job.
+const JobKind = "purge_deleted_users"
+
+// Register schedules the purge job on r, which permanently removes users
+// that were soft-deleted longer ago than the configured retention window.
+func Register(r *jobs.Runner, cfg *config.APIConfig, schedule jobs.Schedule) {
+	jobs.Register(r, JobKind, func(ctx context.Context, _ struct{}) error {
+		return DeletedUsers(ctx, cfg)
+	})
+	r.Schedule(JobKind, schedule, struct{}{})
+}
+
+// DeletedUsers runs a single purge pass.
+func DeletedUsers(ctx context.Context, cfg *config.APIConfig) error {
+	cutoff := time.Now().Add(-cfg.DeletedUserRetention)
+
+	purged, err := service.PurgeDeletedUsers(ctx, cfg, audit.System, pgtype.Timestamptz{Time: cutoff, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d users deleted before %s", purged, cutoff.Format(time.RFC3339))
+	}
+
+	return nil
+}
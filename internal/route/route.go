@@ -0,0 +1,143 @@
+// Package route records the route a request matched, whichever of the six
+// routers served it, in the OpenAPI path style of the document: /users/{id}
+// rather than /users/:id or /users/{id:[0-9]+}.
+package route
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Route is the route a request matched.
+type Route struct {
+	// Pattern is the path the route was registered with, such as
+	// /users/{id}. A wildcard matching the rest of the path is written
+	// {name...}, or {*...} when the router leaves it unnamed.
+	Pattern string
+	// Params are the values the request has for the parameters of Pattern,
+	// in the order they appear in it.
+	Params []Param
+}
+
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Param returns the value of the named parameter, or "" if the route has
+// none by that name.
+func (rt Route) Param(name string) string {
+	for _, p := range rt.Params {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+type contextKey struct{}
+
+// holder is where the route is recorded. It is shared by the request and
+// the requests derived from it, so the route a router matched is seen by
+// the middleware around the router as well.
+type holder struct {
+	route Route
+	ok    bool
+}
+
+// Capture lets the middleware it wraps see the route matched inside it,
+// once the handler has returned. It is meant to run before routing.
+func Capture(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(contextKey{}).(*holder); !ok {
+			r = r.WithContext(context.WithValue(r.Context(), contextKey{}, &holder{}))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Set records rt as the route r matched and returns the request to hand on.
+// The parameters also become path values of the request, for r.PathValue.
+func Set(r *http.Request, rt Route) *http.Request {
+	for _, p := range rt.Params {
+		r.SetPathValue(p.Name, p.Value)
+	}
+	if h, ok := r.Context().Value(contextKey{}).(*holder); ok {
+		h.route, h.ok = rt, true
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), contextKey{}, &holder{route: rt, ok: true}))
+}
+
+// FromRequest returns the route r matched. There is none before routing,
+// or if no route matched.
+func FromRequest(r *http.Request) (Route, bool) {
+	if h, ok := r.Context().Value(contextKey{}).(*holder); ok && h.ok {
+		return h.route, true
+	}
+	return Route{}, false
+}
+
+// Pattern returns the pattern of the route r matched, or "" if there is
+// none, for use as a label in logs and metrics.
+func Pattern(r *http.Request) string {
+	rt, _ := FromRequest(r)
+	return rt.Pattern
+}
+
+// The functions below turn the patterns each router is registered with
+// into the OpenAPI path style.
+
+var (
+	colonParam    = regexp.MustCompile(`([:*])([A-Za-z0-9_]*)`)
+	regexpParam   = regexp.MustCompile(`\{([^:}]+):[^}]+\}`)
+	wildcardParam = regexp.MustCompile(`\{([^}]+?)(\.\.\.)?\}`)
+)
+
+// FromColon converts the :name and *name style of httprouter, echo and gin.
+func FromColon(path string) string {
+	return colonParam.ReplaceAllStringFunc(path, func(s string) string {
+		name := s[1:]
+		if s[0] == ':' {
+			return "{" + name + "}"
+		}
+		if name == "" {
+			name = "*"
+		}
+		return "{" + name + "...}"
+	})
+}
+
+// FromTemplate converts the {name} and {name:regexp} style of gorilla/mux
+// and chi, where a trailing /* is chi's wildcard.
+func FromTemplate(path string) string {
+	path = regexpParam.ReplaceAllString(path, "{$1}")
+	if strings.HasSuffix(path, "/*") {
+		path = strings.TrimSuffix(path, "*") + "{*...}"
+	}
+	return path
+}
+
+// FromServeMux converts a ServeMux pattern, dropping its method and host
+// and the {$} that anchors a trailing slash.
+func FromServeMux(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		pattern = strings.TrimLeft(path, " \t")
+	}
+	if i := strings.Index(pattern, "/"); i > 0 {
+		pattern = pattern[i:]
+	}
+	return strings.TrimSuffix(pattern, "{$}")
+}
+
+// Names returns the names of the parameters of a pattern in the OpenAPI
+// path style, in order.
+func Names(pattern string) []string {
+	var names []string
+	for _, m := range wildcardParam.FindAllStringSubmatch(pattern, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
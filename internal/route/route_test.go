@@ -0,0 +1,100 @@
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestFromColon(t *testing.T) {
+	tests := []struct{ path, want string }{
+		{"/users", "/users"},
+		{"/users/:id", "/users/{id}"},
+		{"/users/:id/posts/:post_id", "/users/{id}/posts/{post_id}"},
+		{"/files/*path", "/files/{path...}"},
+		// echo leaves its wildcard unnamed
+		{"/files/*", "/files/{*...}"},
+		{"/users/:id/files/*path", "/users/{id}/files/{path...}"},
+	}
+	for _, tt := range tests {
+		if got := FromColon(tt.path); got != tt.want {
+			t.Errorf("FromColon(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFromTemplate(t *testing.T) {
+	tests := []struct{ path, want string }{
+		{"/users", "/users"},
+		{"/users/{id}", "/users/{id}"},
+		{"/users/{id:[0-9]+}", "/users/{id}"},
+		{"/users/{id:[0-9]+}/posts/{slug:[a-z-]+}", "/users/{id}/posts/{slug}"},
+		// chi's wildcard
+		{"/files/*", "/files/{*...}"},
+		{"/users/{id}/files/*", "/users/{id}/files/{*...}"},
+	}
+	for _, tt := range tests {
+		if got := FromTemplate(tt.path); got != tt.want {
+			t.Errorf("FromTemplate(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFromServeMux(t *testing.T) {
+	tests := []struct{ pattern, want string }{
+		{"/users", "/users"},
+		{"/users/{id}", "/users/{id}"},
+		{"GET /users/{id}", "/users/{id}"},
+		{"DELETE  /users/{id}", "/users/{id}"},
+		{"GET api.example.com/users/{id}", "/users/{id}"},
+		{"api.example.com/users", "/users"},
+		{"/files/{path...}", "/files/{path...}"},
+		{"GET /files/{path...}", "/files/{path...}"},
+		{"GET /{$}", "/"},
+		{"/users/{$}", "/users/"},
+	}
+	for _, tt := range tests {
+		if got := FromServeMux(tt.pattern); got != tt.want {
+			t.Errorf("FromServeMux(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"/users", nil},
+		{"/users/{id}", []string{"id"}},
+		{"/users/{id}/posts/{post_id}", []string{"id", "post_id"}},
+		{"/files/{path...}", []string{"path"}},
+		{"/files/{*...}", []string{"*"}},
+	}
+	for _, tt := range tests {
+		if got := Names(tt.pattern); !slices.Equal(got, tt.want) {
+			t.Errorf("Names(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+// The route set inside the router is seen by the middleware around it.
+func TestCaptureSeesTheRouteSetInside(t *testing.T) {
+	var pattern string
+	router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = Set(r, Route{Pattern: "/users/{id}", Params: []Param{{Name: "id", Value: "7"}}})
+		if id := r.PathValue("id"); id != "7" {
+			t.Errorf("PathValue(id) = %q, want 7", id)
+		}
+	})
+	handler := Capture(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.ServeHTTP(w, r)
+		pattern = Pattern(r)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/7", nil))
+	if pattern != "/users/{id}" {
+		t.Fatalf("middleware saw pattern %q, want /users/{id}", pattern)
+	}
+}
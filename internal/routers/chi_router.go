@@ -2,19 +2,52 @@ package routers
 
 import (
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/handlers"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/middleware"
 	"github.com/go-chi/chi/v5"
 )
 
-func ChiRouter() *chi.Mux {
-	cfg := config.ApiCfg()
-	r := chi.NewRouter()
+func ChiRouter(cfg *config.APIConfig, broker *events.Broker, mw ...middleware.Middleware) *chi.Mux {
+	mux := chi.NewRouter()
+	r := mux.With(middleware.Chi(mw)...)
 
 	r.Get("/users", handlers.ChiGetUsers(cfg))
+	r.Get("/users/events", handlers.ChiUserEvents(cfg, broker))
+	r.Get("/users/export", handlers.ChiExportUsers(cfg))
 	r.Post("/users", handlers.ChiCreateUser(cfg))
+	r.Post("/users/bulk", handlers.ChiBulkCreateUsers(cfg))
+	r.Patch("/users/bulk", handlers.ChiBulkUpdateUsers(cfg))
+	r.Delete("/users/bulk", handlers.ChiBulkDeleteUsers(cfg))
+	r.Post("/users/import", handlers.ChiImportUsers(cfg))
 	r.Get("/users/{id}", handlers.ChiGetUser(cfg))
 	r.Put("/users/{id}", handlers.ChiUpdateUser(cfg))
 	r.Delete("/users/{id}", handlers.ChiDeleteUser(cfg))
+	r.Post("/users/{id}/restore", handlers.ChiRestoreUser(cfg))
+	r.Get("/users/{id}/history", handlers.ChiGetUserHistory(cfg))
+	r.Get("/audit", handlers.ChiGetAuditEvents(cfg))
+	r.Get("/ws", handlers.ChiUserSocket(cfg, broker))
+	r.Get("/imports/{id}", handlers.ChiGetImport(cfg))
+	r.Get("/imports/{id}/errors", handlers.ChiGetImportErrors(cfg))
 
-	return r
+	r.Get("/webhooks", handlers.ChiGetWebhooks(cfg))
+	r.Post("/webhooks", handlers.ChiCreateWebhook(cfg))
+	r.Get("/webhooks/{id}", handlers.ChiGetWebhook(cfg))
+	r.Put("/webhooks/{id}", handlers.ChiUpdateWebhook(cfg))
+	r.Delete("/webhooks/{id}", handlers.ChiDeleteWebhook(cfg))
+	r.Get("/webhooks/{id}/deliveries", handlers.ChiGetWebhookDeliveries(cfg))
+	r.Post("/webhooks/{id}/deliveries/{delivery_id}/redeliver", handlers.ChiRedeliverWebhook(cfg))
+
+	r.Get("/jobs", handlers.ChiGetJobs(cfg))
+	r.Get("/jobs/{id}", handlers.ChiGetJob(cfg))
+	r.Post("/jobs/{id}/retry", handlers.ChiRetryJob(cfg))
+	r.Post("/jobs/{id}/cancel", handlers.ChiCancelJob(cfg))
+
+	r.Get("/openapi.json", handlers.ChiOpenAPI())
+	r.Get("/docs", handlers.ChiDocs())
+
+	r.Get("/healthz", handlers.ChiHealth())
+	r.Get("/readyz", handlers.ChiReadiness(cfg))
+
+	return mux
 }
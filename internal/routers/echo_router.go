@@ -2,19 +2,52 @@ package routers
 
 import (
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/handlers"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/middleware"
 	"github.com/labstack/echo/v4"
 )
 
-func EchoRouter() *echo.Echo {
+func EchoRouter(cfg *config.APIConfig, broker *events.Broker, mw ...middleware.Middleware) *echo.Echo {
 	r := echo.New()
-	cfg := config.ApiCfg()
+	r.Use(middleware.Echo(mw))
 
 	r.GET("/users", handlers.EchoGetUsers(cfg))
+	r.GET("/users/events", handlers.EchoUserEvents(cfg, broker))
+	r.GET("/users/export", handlers.EchoExportUsers(cfg))
 	r.POST("/users", handlers.EchoCreateUser(cfg))
+	r.POST("/users/bulk", handlers.EchoBulkCreateUsers(cfg))
+	r.PATCH("/users/bulk", handlers.EchoBulkUpdateUsers(cfg))
+	r.DELETE("/users/bulk", handlers.EchoBulkDeleteUsers(cfg))
+	r.POST("/users/import", handlers.EchoImportUsers(cfg))
 	r.GET("/users/:id", handlers.EchoGetUser(cfg))
 	r.PUT("/users/:id", handlers.EchoUpdateUser(cfg))
 	r.DELETE("/users/:id", handlers.EchoDeleteUser(cfg))
+	r.POST("/users/:id/restore", handlers.EchoRestoreUser(cfg))
+	r.GET("/users/:id/history", handlers.EchoGetUserHistory(cfg))
+	r.GET("/audit", handlers.EchoGetAuditEvents(cfg))
+	r.GET("/ws", handlers.EchoUserSocket(cfg, broker))
+	r.GET("/imports/:id", handlers.EchoGetImport(cfg))
+	r.GET("/imports/:id/errors", handlers.EchoGetImportErrors(cfg))
+
+	r.GET("/webhooks", handlers.EchoGetWebhooks(cfg))
+	r.POST("/webhooks", handlers.EchoCreateWebhook(cfg))
+	r.GET("/webhooks/:id", handlers.EchoGetWebhook(cfg))
+	r.PUT("/webhooks/:id", handlers.EchoUpdateWebhook(cfg))
+	r.DELETE("/webhooks/:id", handlers.EchoDeleteWebhook(cfg))
+	r.GET("/webhooks/:id/deliveries", handlers.EchoGetWebhookDeliveries(cfg))
+	r.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", handlers.EchoRedeliverWebhook(cfg))
+
+	r.GET("/jobs", handlers.EchoGetJobs(cfg))
+	r.GET("/jobs/:id", handlers.EchoGetJob(cfg))
+	r.POST("/jobs/:id/retry", handlers.EchoRetryJob(cfg))
+	r.POST("/jobs/:id/cancel", handlers.EchoCancelJob(cfg))
+
+	r.GET("/openapi.json", handlers.EchoOpenAPI())
+	r.GET("/docs", handlers.EchoDocs())
+
+	r.GET("/healthz", handlers.EchoHealth())
+	r.GET("/readyz", handlers.EchoReadiness(cfg))
 
 	return r
 }
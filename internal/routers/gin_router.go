@@ -2,20 +2,55 @@ package routers
 
 import (
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/handlers"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
-func GinRouter() *gin.Engine {
+func GinRouter(cfg *config.APIConfig, broker *events.Broker, mw ...middleware.Middleware) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
-	r := gin.Default()
-	cfg := config.ApiCfg()
+	// Requests are logged by the middleware of every server, so gin's own
+	// logger is left out
+	r := gin.New()
+	r.Use(gin.Recovery(), middleware.Gin(mw))
 
 	r.GET("/users", handlers.GinGetUsers(cfg))
+	r.GET("/users/events", handlers.GinUserEvents(cfg, broker))
+	r.GET("/users/export", handlers.GinExportUsers(cfg))
 	r.POST("/users", handlers.GinCreateUser(cfg))
+	r.POST("/users/bulk", handlers.GinBulkCreateUsers(cfg))
+	r.PATCH("/users/bulk", handlers.GinBulkUpdateUsers(cfg))
+	r.DELETE("/users/bulk", handlers.GinBulkDeleteUsers(cfg))
+	r.POST("/users/import", handlers.GinImportUsers(cfg))
 	r.GET("/users/:id", handlers.GinGetUser(cfg))
 	r.PUT("/users/:id", handlers.GinUpdateUser(cfg))
 	r.DELETE("/users/:id", handlers.GinDeleteUser(cfg))
+	r.POST("/users/:id/restore", handlers.GinRestoreUser(cfg))
+	r.GET("/users/:id/history", handlers.GinGetUserHistory(cfg))
+	r.GET("/audit", handlers.GinGetAuditEvents(cfg))
+	r.GET("/ws", handlers.GinUserSocket(cfg, broker))
+	r.GET("/imports/:id", handlers.GinGetImport(cfg))
+	r.GET("/imports/:id/errors", handlers.GinGetImportErrors(cfg))
+
+	r.GET("/webhooks", handlers.GinGetWebhooks(cfg))
+	r.POST("/webhooks", handlers.GinCreateWebhook(cfg))
+	r.GET("/webhooks/:id", handlers.GinGetWebhook(cfg))
+	r.PUT("/webhooks/:id", handlers.GinUpdateWebhook(cfg))
+	r.DELETE("/webhooks/:id", handlers.GinDeleteWebhook(cfg))
+	r.GET("/webhooks/:id/deliveries", handlers.GinGetWebhookDeliveries(cfg))
+	r.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", handlers.GinRedeliverWebhook(cfg))
+
+	r.GET("/jobs", handlers.GinGetJobs(cfg))
+	r.GET("/jobs/:id", handlers.GinGetJob(cfg))
+	r.POST("/jobs/:id/retry", handlers.GinRetryJob(cfg))
+	r.POST("/jobs/:id/cancel", handlers.GinCancelJob(cfg))
+
+	r.GET("/openapi.json", handlers.GinOpenAPI())
+	r.GET("/docs", handlers.GinDocs())
+
+	r.GET("/healthz", handlers.GinHealth())
+	r.GET("/readyz", handlers.GinReadiness(cfg))
 
 	return r
 }
@@ -1,20 +1,89 @@
 package routers
 
 import (
+	"maps"
+	"net/http"
+	"slices"
+
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/handlers"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/middleware"
 	"github.com/julienschmidt/httprouter"
 )
 
-func HttpRouter() *httprouter.Router {
-	r := httprouter.New()
-	cfg := config.ApiCfg()
+func HttpRouter(cfg *config.APIConfig, broker *events.Broker, mw ...middleware.Middleware) *middleware.HttpRouter {
+	r := middleware.NewHttpRouter(httprouter.New(), mw)
 
 	r.GET("/users", handlers.HttpGetUsers(cfg))
 	r.POST("/users", handlers.HttpCreateUser(cfg))
-	r.GET("/users/:id", handlers.HttpGetUser(cfg))
+	byIDOrStatic(r, http.MethodPost, "/users", nil, map[string]httprouter.Handle{
+		"bulk":   handlers.HttpBulkCreateUsers(cfg),
+		"import": handlers.HttpImportUsers(cfg),
+	})
+	r.PATCH("/users/bulk", handlers.HttpBulkUpdateUsers(cfg))
+	byIDOrStatic(r, http.MethodGet, "/users", handlers.HttpGetUser(cfg), map[string]httprouter.Handle{
+		"events": handlers.HttpUserEvents(cfg, broker),
+		"export": handlers.HttpExportUsers(cfg),
+	})
 	r.PUT("/users/:id", handlers.HttpUpdateUser(cfg))
-	r.DELETE("/users/:id", handlers.HttpDeleteUser(cfg))
+	byIDOrStatic(r, http.MethodDelete, "/users", handlers.HttpDeleteUser(cfg), map[string]httprouter.Handle{
+		"bulk": handlers.HttpBulkDeleteUsers(cfg),
+	})
+	r.POST("/users/:id/restore", handlers.HttpRestoreUser(cfg))
+	r.GET("/users/:id/history", handlers.HttpGetUserHistory(cfg))
+	r.GET("/audit", handlers.HttpGetAuditEvents(cfg))
+	r.GET("/ws", handlers.HttpUserSocket(cfg, broker))
+	r.GET("/imports/:id", handlers.HttpGetImport(cfg))
+	r.GET("/imports/:id/errors", handlers.HttpGetImportErrors(cfg))
+
+	r.GET("/webhooks", handlers.HttpGetWebhooks(cfg))
+	r.POST("/webhooks", handlers.HttpCreateWebhook(cfg))
+	r.GET("/webhooks/:id", handlers.HttpGetWebhook(cfg))
+	r.PUT("/webhooks/:id", handlers.HttpUpdateWebhook(cfg))
+	r.DELETE("/webhooks/:id", handlers.HttpDeleteWebhook(cfg))
+	r.GET("/webhooks/:id/deliveries", handlers.HttpGetWebhookDeliveries(cfg))
+	r.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", handlers.HttpRedeliverWebhook(cfg))
+
+	r.GET("/jobs", handlers.HttpGetJobs(cfg))
+	r.GET("/jobs/:id", handlers.HttpGetJob(cfg))
+	r.POST("/jobs/:id/retry", handlers.HttpRetryJob(cfg))
+	r.POST("/jobs/:id/cancel", handlers.HttpCancelJob(cfg))
+
+	r.GET("/openapi.json", handlers.HttpOpenAPI())
+	r.GET("/docs", handlers.HttpDocs())
+
+	r.GET("/healthz", handlers.HttpHealth())
+	r.GET("/readyz", handlers.HttpReadiness(cfg))
 
 	return r
 }
+
+// httprouter does not allow a static segment such as /users/events next to
+// the /users/:id wildcard, so those routes are registered on the wildcard and
+// dispatched by the value it matched. Each handle is wrapped in the
+// middleware, and recorded, as the route it stands for, so /users/events is
+// not taken for the user with ID "events". A nil byID leaves the wildcard
+// route itself out: other IDs are not found.
+func byIDOrStatic(r *middleware.HttpRouter, method, prefix string, byID httprouter.Handle, static map[string]httprouter.Handle) {
+	if byID != nil {
+		byID = r.Route(method, prefix+"/:id", byID)
+	} else {
+		byID = notFound
+	}
+	wrapped := make(map[string]httprouter.Handle, len(static))
+	for _, segment := range slices.Sorted(maps.Keys(static)) {
+		wrapped[segment] = r.Route(method, prefix+"/"+segment, static[segment])
+	}
+	r.Router.Handle(method, prefix+"/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if handle, ok := wrapped[ps.ByName("id")]; ok {
+			handle(w, r, nil)
+			return
+		}
+		byID(w, r, ps)
+	})
+}
+
+func notFound(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	http.NotFound(w, r)
+}
@@ -2,19 +2,52 @@ package routers
 
 import (
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/handlers"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/middleware"
 	"github.com/gorilla/mux"
 )
 
-func MuxRouter() *mux.Router {
+func MuxRouter(cfg *config.APIConfig, broker *events.Broker, mw ...middleware.Middleware) *mux.Router {
 	r := mux.NewRouter()
-	cfg := config.ApiCfg()
+	r.Use(middleware.Mux(mw))
 
 	r.HandleFunc("/users", handlers.MuxGetUsers(cfg)).Methods("GET")
+	r.HandleFunc("/users/events", handlers.MuxUserEvents(cfg, broker)).Methods("GET")
+	r.HandleFunc("/users/export", handlers.MuxExportUsers(cfg)).Methods("GET")
 	r.HandleFunc("/users", handlers.MuxCreateUser(cfg)).Methods("POST")
+	r.HandleFunc("/users/bulk", handlers.MuxBulkCreateUsers(cfg)).Methods("POST")
+	r.HandleFunc("/users/bulk", handlers.MuxBulkUpdateUsers(cfg)).Methods("PATCH")
+	r.HandleFunc("/users/bulk", handlers.MuxBulkDeleteUsers(cfg)).Methods("DELETE")
+	r.HandleFunc("/users/import", handlers.MuxImportUsers(cfg)).Methods("POST")
 	r.HandleFunc("/users/{id:[0-9]+}", handlers.MuxGetUser(cfg)).Methods("GET")
 	r.HandleFunc("/users/{id:[0-9]+}", handlers.MuxUpdateUser(cfg)).Methods("PUT")
 	r.HandleFunc("/users/{id:[0-9]+}", handlers.MuxDeleteUser(cfg)).Methods("DELETE")
+	r.HandleFunc("/users/{id:[0-9]+}/restore", handlers.MuxRestoreUser(cfg)).Methods("POST")
+	r.HandleFunc("/users/{id:[0-9]+}/history", handlers.MuxGetUserHistory(cfg)).Methods("GET")
+	r.HandleFunc("/audit", handlers.MuxGetAuditEvents(cfg)).Methods("GET")
+	r.HandleFunc("/ws", handlers.MuxUserSocket(cfg, broker)).Methods("GET")
+	r.HandleFunc("/imports/{id:[0-9]+}", handlers.MuxGetImport(cfg)).Methods("GET")
+	r.HandleFunc("/imports/{id:[0-9]+}/errors", handlers.MuxGetImportErrors(cfg)).Methods("GET")
+
+	r.HandleFunc("/webhooks", handlers.MuxGetWebhooks(cfg)).Methods("GET")
+	r.HandleFunc("/webhooks", handlers.MuxCreateWebhook(cfg)).Methods("POST")
+	r.HandleFunc("/webhooks/{id:[0-9]+}", handlers.MuxGetWebhook(cfg)).Methods("GET")
+	r.HandleFunc("/webhooks/{id:[0-9]+}", handlers.MuxUpdateWebhook(cfg)).Methods("PUT")
+	r.HandleFunc("/webhooks/{id:[0-9]+}", handlers.MuxDeleteWebhook(cfg)).Methods("DELETE")
+	r.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", handlers.MuxGetWebhookDeliveries(cfg)).Methods("GET")
+	r.HandleFunc("/webhooks/{id:[0-9]+}/deliveries/{delivery_id:[0-9]+}/redeliver", handlers.MuxRedeliverWebhook(cfg)).Methods("POST")
+
+	r.HandleFunc("/jobs", handlers.MuxGetJobs(cfg)).Methods("GET")
+	r.HandleFunc("/jobs/{id:[0-9]+}", handlers.MuxGetJob(cfg)).Methods("GET")
+	r.HandleFunc("/jobs/{id:[0-9]+}/retry", handlers.MuxRetryJob(cfg)).Methods("POST")
+	r.HandleFunc("/jobs/{id:[0-9]+}/cancel", handlers.MuxCancelJob(cfg)).Methods("POST")
+
+	r.HandleFunc("/openapi.json", handlers.MuxOpenAPI()).Methods("GET")
+	r.HandleFunc("/docs", handlers.MuxDocs()).Methods("GET")
+
+	r.HandleFunc("/healthz", handlers.MuxHealth()).Methods("GET")
+	r.HandleFunc("/readyz", handlers.MuxReadiness(cfg)).Methods("GET")
 
 	return r
 }
@@ -0,0 +1,82 @@
+package routers
+
+import (
+	"net/http"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/middleware"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/openapi"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/route"
+	"github.com/gin-gonic/gin"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/mux"
+	"github.com/labstack/echo/v4"
+)
+
+// The functions below list the routes each router serves, in the OpenAPI
+// path style of the route package, so they can be checked against the
+// OpenAPI document.
+
+func ChiRoutes(r *chi.Mux) ([]openapi.Endpoint, error) {
+	var endpoints []openapi.Endpoint
+	err := chi.Walk(r, func(method, path string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		endpoints = append(endpoints, openapi.Endpoint{Method: method, Path: route.FromTemplate(path)})
+		return nil
+	})
+	return endpoints, err
+}
+
+func MuxRoutes(r *mux.Router) ([]openapi.Endpoint, error) {
+	var endpoints []openapi.Endpoint
+	err := r.Walk(func(current *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := current.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := current.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			endpoints = append(endpoints, openapi.Endpoint{Method: method, Path: route.FromTemplate(path)})
+		}
+		return nil
+	})
+	return endpoints, err
+}
+
+func EchoRoutes(e *echo.Echo) []openapi.Endpoint {
+	var endpoints []openapi.Endpoint
+	for _, r := range e.Routes() {
+		endpoints = append(endpoints, openapi.Endpoint{Method: r.Method, Path: route.FromColon(r.Path)})
+	}
+	return endpoints
+}
+
+func GinRoutes(g *gin.Engine) []openapi.Endpoint {
+	var endpoints []openapi.Endpoint
+	for _, r := range g.Routes() {
+		endpoints = append(endpoints, openapi.Endpoint{Method: r.Method, Path: route.FromColon(r.Path)})
+	}
+	return endpoints
+}
+
+// StandardRoutes returns the routes registered on the router, which the
+// adapter records since ServeMux cannot list them.
+func StandardRoutes(r *middleware.ServeMux) []openapi.Endpoint {
+	return endpoints(r.Routes())
+}
+
+// HttpRoutes returns the routes registered on the router, which the adapter
+// records since httprouter cannot list them. The static segments that
+// byIDOrStatic dispatches to, such as /users/bulk, are routes of their own.
+func HttpRoutes(r *middleware.HttpRouter) []openapi.Endpoint {
+	return endpoints(r.Routes())
+}
+
+func endpoints(routes []middleware.Endpoint) []openapi.Endpoint {
+	endpoints := make([]openapi.Endpoint, len(routes))
+	for i, r := range routes {
+		endpoints[i] = openapi.Endpoint(r)
+	}
+	return endpoints
+}
@@ -0,0 +1,46 @@
+package routers
+
+import (
+	"testing"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/openapi"
+)
+
+// Every router serves exactly the endpoints of the OpenAPI document. The
+// routers are only built, so the config needs no database.
+func TestRoutersMatchOpenAPI(t *testing.T) {
+	cfg := &config.APIConfig{}
+	broker := events.NewBroker(cfg)
+
+	chiRoutes, err := ChiRoutes(ChiRouter(cfg, broker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	muxRoutes, err := MuxRoutes(MuxRouter(cfg, broker))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := map[string][]openapi.Endpoint{
+		"standard":   StandardRoutes(StandardRouter(cfg, broker)),
+		"httprouter": HttpRoutes(HttpRouter(cfg, broker)),
+		"mux":        muxRoutes,
+		"chi":        chiRoutes,
+		"echo":       EchoRoutes(EchoRouter(cfg, broker)),
+		"gin":        GinRoutes(GinRouter(cfg, broker)),
+	}
+	for framework, endpoints := range served {
+		missing, undocumented := openapi.Diff(endpoints)
+		for _, e := range missing {
+			t.Errorf("%s: not served: %s", framework, e)
+		}
+		for _, e := range undocumented {
+			t.Errorf("%s: not documented: %s", framework, e)
+		}
+		if len(endpoints) != len(openapi.Routes) {
+			t.Errorf("%s: %d routes, want %d", framework, len(endpoints), len(openapi.Routes))
+		}
+	}
+}
@@ -4,18 +4,50 @@ import (
 	"net/http"
 
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/events"
 	"github.com/KennyMwendwaX/go-frameworks-crud/internal/handlers"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/middleware"
 )
 
-func StandardRouter() *http.ServeMux {
-	r := http.NewServeMux()
-	cfg := config.ApiCfg()
+func StandardRouter(cfg *config.APIConfig, broker *events.Broker, mw ...middleware.Middleware) *middleware.ServeMux {
+	r := middleware.NewServeMux(http.NewServeMux(), mw)
 
 	r.HandleFunc("GET /users", handlers.StandardGetUsers(cfg))
+	r.HandleFunc("GET /users/events", handlers.StandardUserEvents(cfg, broker))
+	r.HandleFunc("GET /users/export", handlers.StandardExportUsers(cfg))
 	r.HandleFunc("POST /users", handlers.StandardCreateUser(cfg))
+	r.HandleFunc("POST /users/bulk", handlers.StandardBulkCreateUsers(cfg))
+	r.HandleFunc("PATCH /users/bulk", handlers.StandardBulkUpdateUsers(cfg))
+	r.HandleFunc("DELETE /users/bulk", handlers.StandardBulkDeleteUsers(cfg))
+	r.HandleFunc("POST /users/import", handlers.StandardImportUsers(cfg))
 	r.HandleFunc("GET /users/{id}", handlers.StandardGetUser(cfg))
 	r.HandleFunc("PUT /users/{id}", handlers.StandardUpdateUser(cfg))
 	r.HandleFunc("DELETE /users/{id}", handlers.StandardDeleteUser(cfg))
+	r.HandleFunc("POST /users/{id}/restore", handlers.StandardRestoreUser(cfg))
+	r.HandleFunc("GET /users/{id}/history", handlers.StandardGetUserHistory(cfg))
+	r.HandleFunc("GET /audit", handlers.StandardGetAuditEvents(cfg))
+	r.HandleFunc("GET /ws", handlers.StandardUserSocket(cfg, broker))
+	r.HandleFunc("GET /imports/{id}", handlers.StandardGetImport(cfg))
+	r.HandleFunc("GET /imports/{id}/errors", handlers.StandardGetImportErrors(cfg))
+
+	r.HandleFunc("GET /webhooks", handlers.StandardGetWebhooks(cfg))
+	r.HandleFunc("POST /webhooks", handlers.StandardCreateWebhook(cfg))
+	r.HandleFunc("GET /webhooks/{id}", handlers.StandardGetWebhook(cfg))
+	r.HandleFunc("PUT /webhooks/{id}", handlers.StandardUpdateWebhook(cfg))
+	r.HandleFunc("DELETE /webhooks/{id}", handlers.StandardDeleteWebhook(cfg))
+	r.HandleFunc("GET /webhooks/{id}/deliveries", handlers.StandardGetWebhookDeliveries(cfg))
+	r.HandleFunc("POST /webhooks/{id}/deliveries/{delivery_id}/redeliver", handlers.StandardRedeliverWebhook(cfg))
+
+	r.HandleFunc("GET /jobs", handlers.StandardGetJobs(cfg))
+	r.HandleFunc("GET /jobs/{id}", handlers.StandardGetJob(cfg))
+	r.HandleFunc("POST /jobs/{id}/retry", handlers.StandardRetryJob(cfg))
+	r.HandleFunc("POST /jobs/{id}/cancel", handlers.StandardCancelJob(cfg))
+
+	r.HandleFunc("GET /openapi.json", handlers.StandardOpenAPI())
+	r.HandleFunc("GET /docs", handlers.StandardDocs())
+
+	r.HandleFunc("GET /healthz", handlers.StandardHealth())
+	r.HandleFunc("GET /readyz", handlers.StandardReadiness(cfg))
 
 	return r
 }
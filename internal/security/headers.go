@@ -0,0 +1,63 @@
+// Package security sets the response headers that tell browsers how to
+// treat the API's responses.
+package security
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/openapi"
+)
+
+// The API itself serves no page, so its responses may load nothing.
+const apiContentSecurityPolicy = "default-src 'none'"
+
+// Headers sets the security headers of every response.
+type Headers struct {
+	hsts           string
+	frameOptions   string
+	frameAncestors string
+}
+
+func New(cfg config.SecurityConfig) *Headers {
+	h := &Headers{frameOptions: cfg.FrameOptions, frameAncestors: "frame-ancestors 'none'"}
+	if cfg.FrameOptions == "SAMEORIGIN" {
+		h.frameAncestors = "frame-ancestors 'self'"
+	}
+
+	if seconds := int64(cfg.HSTSMaxAge.Seconds()); seconds > 0 {
+		h.hsts = "max-age=" + strconv.FormatInt(seconds, 10)
+		if cfg.HSTSIncludeSubdomains {
+			h.hsts += "; includeSubDomains"
+		}
+	}
+	return h
+}
+
+// Middleware sets the headers before next runs, so they are on every
+// response, errors included. Routes serving HTML, such as the docs page, get
+// the Content-Security-Policy of that page in place of the one for the API.
+func (h *Headers) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", h.frameOptions)
+		header.Set("Referrer-Policy", "no-referrer")
+
+		policy := apiContentSecurityPolicy
+		if mediaTypes, ok := openapi.ResponseMediaTypes(r); ok && slices.Contains(mediaTypes, "text/html") {
+			policy = openapi.DocsContentSecurityPolicy()
+		}
+		header.Set("Content-Security-Policy", policy+"; "+h.frameAncestors)
+
+		// Browsers ignore the header on plain HTTP, where anyone on the way
+		// could have added it
+		if h.hsts != "" && r.TLS != nil {
+			header.Set("Strict-Transport-Security", h.hsts)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
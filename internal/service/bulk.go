@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrUserNotFound = errors.New("User not found")
+	ErrEmailTaken   = errors.New("Email already in use")
+	ErrNotApplied   = errors.New("Not applied because another item failed")
+	ErrDuplicateID  = errors.New("Duplicate id")
+)
+
+// errRollback aborts an all-or-nothing transaction once an item has failed.
+var errRollback = errors.New("bulk operation rolled back")
+
+// BulkResult is the outcome of one item of a bulk operation, in the order
+// the items were given. User is set when Err is nil.
+type BulkResult struct {
+	User database.User
+	Err  error
+}
+
+// UserPatch changes the fields of a user that are not nil.
+type UserPatch struct {
+	ID    int32
+	Name  *string
+	Email *string
+	Age   *int32
+}
+
+// BulkCreateUsers creates users in one transaction. When atomic is true
+// either every user is created or none is; otherwise users whose email is
+// taken are skipped and the rest are created.
+func BulkCreateUsers(ctx context.Context, cfg *config.APIConfig, meta audit.Meta, arg []database.CreateUserParams, atomic bool) ([]BulkResult, error) {
+	if atomic {
+		return copyUsers(ctx, cfg, meta, arg)
+	}
+
+	var results []BulkResult
+	err := cfg.WithRetryableTx(ctx, func(q *database.Queries) error {
+		results = make([]BulkResult, len(arg))
+
+		params := make([]database.CreateUserIfAbsentParams, len(arg))
+		for i, a := range arg {
+			params[i] = database.CreateUserIfAbsentParams(a)
+		}
+
+		var batchErr error
+		q.CreateUserIfAbsent(ctx, params).QueryRow(func(i int, user database.User, err error) {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				results[i].Err = ErrEmailTaken
+			case err != nil:
+				batchErr = errors.Join(batchErr, err)
+			default:
+				results[i].User = user
+			}
+		})
+		if batchErr != nil {
+			return batchErr
+		}
+
+		return recordBulk(ctx, q, meta, audit.ActionCreate, outbox.UserCreated, results, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// copyUsers loads every user with COPY, which is the fastest way in but
+// fails as a whole on the first duplicate email.
+func copyUsers(ctx context.Context, cfg *config.APIConfig, meta audit.Meta, arg []database.CreateUserParams) ([]BulkResult, error) {
+	emails := make([]string, len(arg))
+	for i, a := range arg {
+		emails[i] = a.Email
+	}
+
+	results := make([]BulkResult, len(arg))
+	err := cfg.WithRetryableTx(ctx, func(q *database.Queries) error {
+		rows := make([]database.CreateUsersParams, len(arg))
+		for i, a := range arg {
+			rows[i] = database.CreateUsersParams(a)
+		}
+		if _, err := q.CreateUsers(ctx, rows); err != nil {
+			return err
+		}
+
+		// Active emails are unique, so they identify the copied rows.
+		users, err := q.GetActiveUsersByEmail(ctx, emails)
+		if err != nil {
+			return err
+		}
+		byEmail := make(map[string]database.User, len(users))
+		for _, user := range users {
+			byEmail[user.Email] = user
+		}
+		for i, a := range arg {
+			results[i].User = byEmail[a.Email]
+		}
+
+		return recordBulk(ctx, q, meta, audit.ActionCreate, outbox.UserCreated, results, nil)
+	})
+	if !database.IsUniqueViolation(err) {
+		if err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	// Work out which items caused the violation: emails already in use and
+	// repeats within the request.
+	existing, err := cfg.DB.GetActiveUsersByEmail(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, user := range existing {
+		taken[user.Email] = true
+	}
+	for i, a := range arg {
+		results[i] = BulkResult{Err: ErrNotApplied}
+		if taken[a.Email] {
+			results[i].Err = ErrEmailTaken
+		}
+		taken[a.Email] = true
+	}
+	return results, nil
+}
+
+// BulkUpdateUsers applies patches in one transaction. When atomic is true
+// either every patch is applied or none is; otherwise patches for missing
+// users or taken emails are skipped and the rest are applied. Each user may
+// appear only once; later patches of the same user fail with ErrDuplicateID.
+func BulkUpdateUsers(ctx context.Context, cfg *config.APIConfig, meta audit.Meta, patches []UserPatch, atomic bool) ([]BulkResult, error) {
+	ids := make([]int32, len(patches))
+	for i, patch := range patches {
+		ids[i] = patch.ID
+	}
+	unique := uniqueIDs(ids)
+	if atomic && len(unique) < len(ids) {
+		return notApplied(duplicateResults(ids)), nil
+	}
+
+	var results []BulkResult
+	err := cfg.WithRetryableTx(ctx, func(q *database.Queries) error {
+		results = duplicateResults(ids)
+
+		locked, err := q.GetUsersForUpdateByIDs(ctx, unique)
+		if err != nil {
+			return err
+		}
+		before := make(map[int32]database.User, len(locked))
+		for _, user := range locked {
+			before[user.ID] = user
+		}
+
+		var params []database.UpdateUserIfEmailFreeParams
+		var indexes []int
+		for i, patch := range patches {
+			if results[i].Err != nil {
+				continue
+			}
+			user, ok := before[patch.ID]
+			if !ok {
+				results[i].Err = ErrUserNotFound
+				continue
+			}
+			if patch.Name != nil {
+				user.Name = *patch.Name
+			}
+			if patch.Email != nil {
+				user.Email = *patch.Email
+			}
+			if patch.Age != nil {
+				user.Age = *patch.Age
+			}
+			params = append(params, database.UpdateUserIfEmailFreeParams{
+				ID:    user.ID,
+				Name:  user.Name,
+				Email: user.Email,
+				Age:   user.Age,
+			})
+			indexes = append(indexes, i)
+		}
+		if atomic && len(params) < len(patches) {
+			return errRollback
+		}
+
+		var batchErr error
+		q.UpdateUserIfEmailFree(ctx, params).QueryRow(func(j int, user database.User, err error) {
+			i := indexes[j]
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				results[i].Err = ErrEmailTaken
+			case err != nil:
+				batchErr = errors.Join(batchErr, err)
+			default:
+				results[i].User = user
+			}
+		})
+		if batchErr != nil {
+			return batchErr
+		}
+		if atomic && failed(results) {
+			return errRollback
+		}
+
+		return recordBulk(ctx, q, meta, audit.ActionUpdate, outbox.UserUpdated, results, func(i int) *database.User {
+			b := before[results[i].User.ID]
+			return &b
+		})
+	})
+	if errors.Is(err, errRollback) {
+		return notApplied(results), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// BulkDeleteUsers soft deletes users in one transaction. When atomic is true
+// either every user is deleted or none is; otherwise missing users are
+// skipped and the rest are deleted. Each user may appear only once; later
+// occurrences of the same user fail with ErrDuplicateID.
+func BulkDeleteUsers(ctx context.Context, cfg *config.APIConfig, meta audit.Meta, ids []int32, atomic bool) ([]BulkResult, error) {
+	unique := uniqueIDs(ids)
+	if atomic && len(unique) < len(ids) {
+		return notApplied(duplicateResults(ids)), nil
+	}
+
+	var results []BulkResult
+	err := cfg.WithRetryableTx(ctx, func(q *database.Queries) error {
+		results = duplicateResults(ids)
+
+		deleted, err := q.DeleteUsers(ctx, unique)
+		if err != nil {
+			return err
+		}
+		byID := make(map[int32]database.User, len(deleted))
+		for _, user := range deleted {
+			byID[user.ID] = user
+		}
+
+		for i, id := range ids {
+			if results[i].Err != nil {
+				continue
+			}
+			user, ok := byID[id]
+			if !ok {
+				results[i].Err = ErrUserNotFound
+				continue
+			}
+			results[i].User = user
+		}
+		if atomic && failed(results) {
+			return errRollback
+		}
+
+		// Only active users are deleted, so before the change deleted_at
+		// was null.
+		return recordBulk(ctx, q, meta, audit.ActionDelete, outbox.UserDeleted, results, func(i int) *database.User {
+			user := results[i].User
+			user.DeletedAt = pgtype.Timestamptz{}
+			return &user
+		})
+	})
+	if errors.Is(err, errRollback) {
+		return notApplied(results), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// recordBulk writes the audit and outbox events of every successful item.
+// before returns the user of item i as it was before the change, or is nil
+// for creations.
+func recordBulk(ctx context.Context, q *database.Queries, meta audit.Meta, action, eventType string, results []BulkResult, before func(i int) *database.User) error {
+	for i, result := range results {
+		if result.Err != nil {
+			continue
+		}
+
+		var prev *database.User
+		if before != nil {
+			prev = before(i)
+		}
+		if err := audit.Record(ctx, q, meta, action, prev, &result.User); err != nil {
+			return err
+		}
+		if err := outbox.Enqueue(ctx, q, eventType, result.User); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uniqueIDs returns ids without repeats, in the order they first appear.
+func uniqueIDs(ids []int32) []int32 {
+	seen := make(map[int32]bool, len(ids))
+	unique := make([]int32, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// duplicateResults returns the results of items with ids, with every repeat
+// of an id failed with ErrDuplicateID, so each user is changed, audited and
+// published at most once.
+func duplicateResults(ids []int32) []BulkResult {
+	results := make([]BulkResult, len(ids))
+	seen := make(map[int32]bool, len(ids))
+	for i, id := range ids {
+		if seen[id] {
+			results[i].Err = ErrDuplicateID
+		}
+		seen[id] = true
+	}
+	return results
+}
+
+func failed(results []BulkResult) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// notApplied marks the items that succeeded before a rollback as not applied.
+func notApplied(results []BulkResult) []BulkResult {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = BulkResult{Err: ErrNotApplied}
+		}
+	}
+	return results
+}
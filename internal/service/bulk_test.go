@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+)
+
+func TestUniqueIDsKeepsFirstOccurrences(t *testing.T) {
+	got := uniqueIDs([]int32{3, 1, 3, 2, 1})
+	if want := []int32{3, 1, 2}; !slices.Equal(got, want) {
+		t.Fatalf("uniqueIDs = %v, want %v", got, want)
+	}
+}
+
+func TestDuplicateResultsFailsRepeats(t *testing.T) {
+	results := duplicateResults([]int32{3, 1, 3, 2, 1})
+	for i, want := range []error{nil, nil, ErrDuplicateID, nil, ErrDuplicateID} {
+		if results[i].Err != want {
+			t.Errorf("item %d: error %v, want %v", i, results[i].Err, want)
+		}
+	}
+}
+
+// An atomic request with a repeated id fails before it reaches the database,
+// which the config here does not have.
+func TestAtomicBulkWithDuplicatesIsNotApplied(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.APIConfig{}
+
+	deleted, err := BulkDeleteUsers(ctx, cfg, audit.Meta{}, []int32{1, 2, 1}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := BulkUpdateUsers(ctx, cfg, audit.Meta{}, []UserPatch{{ID: 1}, {ID: 2}, {ID: 1}}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, results := range [][]BulkResult{deleted, updated} {
+		for i, want := range []error{ErrNotApplied, ErrNotApplied, ErrDuplicateID} {
+			if !errors.Is(results[i].Err, want) {
+				t.Errorf("item %d: error %v, want %v", i, results[i].Err, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+	"github.com/jackc/pgx/v5"
+)
+
+// What an import does with a row whose email is already in use.
+const (
+	DuplicateSkip   = "skip"
+	DuplicateFail   = "fail"
+	DuplicateUpsert = "upsert"
+)
+
+// What happened to an imported row that did not fail.
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+)
+
+// ImportResult is the outcome of one imported row. Outcome is set when Err
+// is nil.
+type ImportResult struct {
+	Outcome string
+	Err     error
+}
+
+// ImportUsers writes a chunk of imported users through q. Unlike the other
+// functions here it does not open a transaction itself, so the caller can
+// commit chunk by chunk, or run a whole dry run in one transaction and roll
+// it back.
+func ImportUsers(ctx context.Context, q *database.Queries, meta audit.Meta, arg []database.CreateUserParams, onDuplicate string) ([]ImportResult, error) {
+	if onDuplicate == DuplicateUpsert {
+		return upsertUsers(ctx, q, meta, arg)
+	}
+
+	params := make([]database.CreateUserIfAbsentParams, len(arg))
+	for i, a := range arg {
+		params[i] = database.CreateUserIfAbsentParams(a)
+	}
+
+	results := make([]ImportResult, len(arg))
+	created := make([]BulkResult, len(arg))
+	var batchErr error
+	q.CreateUserIfAbsent(ctx, params).QueryRow(func(i int, user database.User, err error) {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			created[i].Err = ErrEmailTaken
+			results[i].Outcome = ImportSkipped
+			if onDuplicate == DuplicateFail {
+				results[i] = ImportResult{Err: ErrEmailTaken}
+			}
+		case err != nil:
+			batchErr = errors.Join(batchErr, err)
+		default:
+			created[i].User = user
+			results[i].Outcome = ImportCreated
+		}
+	})
+	if batchErr != nil {
+		return nil, batchErr
+	}
+
+	if err := recordBulk(ctx, q, meta, audit.ActionCreate, outbox.UserCreated, created, nil); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// upsertUsers creates new users and updates the name and age of existing ones.
+func upsertUsers(ctx context.Context, q *database.Queries, meta audit.Meta, arg []database.CreateUserParams) ([]ImportResult, error) {
+	emails := make([]string, len(arg))
+	params := make([]database.UpsertUserParams, len(arg))
+	for i, a := range arg {
+		emails[i] = a.Email
+		params[i] = database.UpsertUserParams(a)
+	}
+
+	existing, err := q.GetActiveUsersByEmail(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]database.User, len(existing))
+	for _, user := range existing {
+		current[user.Email] = user
+	}
+
+	results := make([]ImportResult, len(arg))
+	created := make([]BulkResult, len(arg))
+	updated := make([]BulkResult, len(arg))
+	before := make([]database.User, len(arg))
+	var batchErr error
+	q.UpsertUser(ctx, params).QueryRow(func(i int, row database.UpsertUserRow, err error) {
+		created[i].Err, updated[i].Err = ErrNotApplied, ErrNotApplied
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			// The existing user already has these values
+			results[i].Outcome = ImportSkipped
+			return
+		case err != nil:
+			batchErr = errors.Join(batchErr, err)
+			return
+		}
+
+		user := database.User{
+			ID:        row.ID,
+			Name:      row.Name,
+			Email:     row.Email,
+			Age:       row.Age,
+			CreatedAt: row.CreatedAt,
+			DeletedAt: row.DeletedAt,
+		}
+		if row.Inserted {
+			created[i] = BulkResult{User: user}
+			results[i].Outcome = ImportCreated
+		} else {
+			// Rows are applied in order, so the same email appearing twice
+			// is diffed against the first row's values.
+			before[i] = current[user.Email]
+			updated[i] = BulkResult{User: user}
+			results[i].Outcome = ImportUpdated
+		}
+		current[user.Email] = user
+	})
+	if batchErr != nil {
+		return nil, batchErr
+	}
+
+	if err := recordBulk(ctx, q, meta, audit.ActionCreate, outbox.UserCreated, created, nil); err != nil {
+		return nil, err
+	}
+	err = recordBulk(ctx, q, meta, audit.ActionUpdate, outbox.UserUpdated, updated, func(i int) *database.User {
+		return &before[i]
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/audit"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// The functions below are the only place users are written to. Each one
+// records its audit event and queues its outbox event in the same transaction
+// as the change, so a rolled-back write never leaves a trail or emits an
+// event, and a committed one always does both. The transactions have no
+// other effects, so they are run again when they fail for a transient
+// reason.
+
+func CreateUser(ctx context.Context, cfg *config.APIConfig, meta audit.Meta, arg database.CreateUserParams) (database.User, error) {
+	var user database.User
+	err := cfg.WithRetryableTx(ctx, func(q *database.Queries) error {
+		var err error
+		user, err = q.CreateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		if err := audit.Record(ctx, q, meta, audit.ActionCreate, nil, &user); err != nil {
+			return err
+		}
+
+		return outbox.Enqueue(ctx, q, outbox.UserCreated, user)
+	})
+	return user, err
+}
+
+func UpdateUser(ctx context.Context, cfg *config.APIConfig, meta audit.Meta, arg database.UpdateUserParams) (database.User, error) {
+	var user database.User
+	err := cfg.WithRetryableTx(ctx, func(q *database.Queries) error {
+		before, err := q.GetUserForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.UpdateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		if err := audit.Record(ctx, q, meta, audit.ActionUpdate, &before, &user); err != nil {
+			return err
+		}
+
+		return outbox.Enqueue(ctx, q, outbox.UserUpdated, user)
+	})
+	return user, err
+}
+
+func DeleteUser(ctx context.Context, cfg *config.APIConfig, meta audit.Meta, id int32) error {
+	return cfg.WithRetryableTx(ctx, func(q *database.Queries) error {
+		before, err := q.GetUserForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		user, err := q.DeleteUser(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := audit.Record(ctx, q, meta, audit.ActionDelete, &before, &user); err != nil {
+			return err
+		}
+
+		return outbox.Enqueue(ctx, q, outbox.UserDeleted, user)
+	})
+}
+
+func RestoreUser(ctx context.Context, cfg *config.APIConfig, meta audit.Meta, id int32) (database.User, error) {
+	var user database.User
+	err := cfg.WithRetryableTx(ctx, func(q *database.Queries) error {
+		before, err := q.GetUserIncludingDeleted(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.RestoreUser(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := audit.Record(ctx, q, meta, audit.ActionRestore, &before, &user); err != nil {
+			return err
+		}
+
+		return outbox.Enqueue(ctx, q, outbox.UserRestored, user)
+	})
+	return user, err
+}
+
+// PurgeDeletedUsers permanently removes users soft-deleted before cutoff and
+// returns how many were removed.
+func PurgeDeletedUsers(ctx context.Context, cfg *config.APIConfig, meta audit.Meta, cutoff pgtype.Timestamptz) (int, error) {
+	var purged []database.User
+	err := cfg.WithRetryableTx(ctx, func(q *database.Queries) error {
+		var err error
+		purged, err = q.PurgeDeletedUsers(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range purged {
+			if err := audit.Record(ctx, q, meta, audit.ActionPurge, &user, nil); err != nil {
+				return err
+			}
+			if err := outbox.Enqueue(ctx, q, outbox.UserPurged, user); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	return len(purged), err
+}
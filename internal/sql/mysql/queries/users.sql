@@ -0,0 +1,55 @@
+-- MySQL has no RETURNING, so writes report the rows they changed, and the
+-- user is read back in the same transaction.
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE id = ? AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetUserForUpdate :one
+SELECT * FROM users
+WHERE id = ? AND deleted_at IS NULL LIMIT 1
+FOR UPDATE;
+
+-- name: GetUserIncludingDeleted :one
+SELECT * FROM users
+WHERE id = ? LIMIT 1;
+
+-- name: ListUsers :many
+SELECT * FROM users
+WHERE sqlc.arg(include_deleted) OR deleted_at IS NULL
+ORDER BY id
+LIMIT ? OFFSET ?;
+
+-- name: CreateUser :execlastid
+INSERT INTO users (
+    name, email, age
+) VALUES (
+    ?, ?, ?
+);
+
+-- name: UpdateUser :exec
+UPDATE users
+SET name = ?,
+    email = ?,
+    age = ?
+WHERE id = ? AND deleted_at IS NULL;
+
+-- name: DeleteUser :execrows
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP(6)
+WHERE id = ? AND deleted_at IS NULL;
+
+-- name: RestoreUser :execrows
+UPDATE users
+SET deleted_at = NULL
+WHERE id = ? AND deleted_at IS NOT NULL;
+
+-- name: GetUsersToPurgeForUpdate :many
+SELECT * FROM users
+WHERE deleted_at IS NOT NULL AND deleted_at < ?
+ORDER BY id
+FOR UPDATE;
+
+-- name: PurgeDeletedUsers :execrows
+DELETE FROM users
+WHERE deleted_at IS NOT NULL AND deleted_at < ?;
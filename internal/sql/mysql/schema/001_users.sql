@@ -0,0 +1,21 @@
+-- +goose Up
+-- The users table as the PostgreSQL migrations leave it. The timestamps are
+-- UTC, as the store sets the time zone of each session to it. Needs MySQL
+-- 8.0.13 or later for the functional index.
+CREATE TABLE users (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    age INT NOT NULL,
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    deleted_at DATETIME(6),
+    INDEX users_deleted_at_idx (deleted_at)
+);
+
+-- Deleted users keep their email, so uniqueness only applies to active rows.
+-- MySQL has no partial indexes, but a unique index allows any number of
+-- NULLs, which is what the email of a deleted user indexes as.
+CREATE UNIQUE INDEX users_email_active_key ON users ((CASE WHEN deleted_at IS NULL THEN email END));
+
+-- +goose Down
+DROP TABLE users;
@@ -0,0 +1,40 @@
+-- name: ClaimIdempotencyKey :one
+-- Takes the key of the scope for a new request, or over from an expired one,
+-- or from a request with the same fingerprint that has been in progress
+-- since before stale_before and so is assumed to have died. The claim token
+-- identifies this claim to CompleteIdempotencyKey and ReleaseIdempotencyKey.
+-- No row is returned when the key is taken.
+INSERT INTO idempotency_keys (scope, key, fingerprint, claim_token, expires_at)
+VALUES (sqlc.arg('scope'), sqlc.arg('key'), sqlc.arg('fingerprint'), sqlc.arg('claim_token'), sqlc.arg('expires_at'))
+ON CONFLICT (scope, key) DO UPDATE
+SET fingerprint = EXCLUDED.fingerprint,
+    claim_token = EXCLUDED.claim_token,
+    status = NULL,
+    headers = NULL,
+    body = NULL,
+    created_at = CURRENT_TIMESTAMP,
+    expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= CURRENT_TIMESTAMP
+   OR (idempotency_keys.status IS NULL
+       AND idempotency_keys.fingerprint = EXCLUDED.fingerprint
+       AND idempotency_keys.created_at < sqlc.arg('stale_before'))
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE scope = $1 AND key = $2;
+
+-- name: CompleteIdempotencyKey :execrows
+-- Stores the response of the claim, unless a retry has taken the key over.
+UPDATE idempotency_keys
+SET status = $4, headers = $5, body = $6
+WHERE scope = $1 AND key = $2 AND claim_token = $3 AND status IS NULL;
+
+-- name: ReleaseIdempotencyKey :exec
+-- Frees the key of the claim, unless a retry has taken it over.
+DELETE FROM idempotency_keys
+WHERE scope = $1 AND key = $2 AND claim_token = $3 AND status IS NULL;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE expires_at <= CURRENT_TIMESTAMP;
@@ -0,0 +1,91 @@
+-- name: CreateJob :one
+INSERT INTO jobs (
+    kind, payload, max_attempts, run_at, unique_key
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+ON CONFLICT (unique_key) DO NOTHING
+RETURNING *;
+
+-- name: ClaimJobs :many
+-- Claims due jobs of the given kinds, along with running jobs whose worker
+-- stopped sending heartbeats.
+UPDATE jobs
+SET status = 'running',
+    attempts = attempts + 1,
+    started_at = CURRENT_TIMESTAMP,
+    heartbeat_at = CURRENT_TIMESTAMP
+WHERE id IN (
+    SELECT id FROM jobs
+    WHERE kind = ANY(sqlc.arg('kinds')::text[])
+      AND ((status = 'queued' AND run_at <= CURRENT_TIMESTAMP)
+        OR (status = 'running' AND heartbeat_at < sqlc.arg('stale_before')))
+    ORDER BY run_at, id
+    LIMIT sqlc.arg('max_jobs')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: TouchJobs :many
+-- Refreshes the heartbeat of running jobs, returning those still running.
+UPDATE jobs
+SET heartbeat_at = CURRENT_TIMESTAMP
+WHERE id = ANY(sqlc.arg('ids')::bigint[])
+  AND status = 'running'
+RETURNING id;
+
+-- name: CompleteJob :exec
+UPDATE jobs
+SET status = 'succeeded',
+    last_error = NULL,
+    heartbeat_at = NULL,
+    finished_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'running';
+
+-- name: FailJob :exec
+-- Records a failed attempt, queueing the job again at run_at unless status
+-- is 'failed'.
+UPDATE jobs
+SET status = $2,
+    last_error = $3,
+    run_at = $4,
+    heartbeat_at = NULL,
+    finished_at = CASE WHEN $2 = 'failed' THEN CURRENT_TIMESTAMP END
+WHERE id = $1 AND status = 'running';
+
+-- name: ReleaseJob :exec
+-- Hands back a job interrupted by shutdown without using up an attempt.
+UPDATE jobs
+SET status = 'queued',
+    attempts = attempts - 1,
+    run_at = CURRENT_TIMESTAMP,
+    heartbeat_at = NULL
+WHERE id = $1 AND status = 'running';
+
+-- name: GetJob :one
+SELECT * FROM jobs
+WHERE id = $1;
+
+-- name: ListJobs :many
+SELECT * FROM jobs
+WHERE (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))
+  AND (sqlc.narg('kind')::text IS NULL OR kind = sqlc.narg('kind'))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: RetryJob :one
+UPDATE jobs
+SET status = 'queued',
+    attempts = 0,
+    run_at = CURRENT_TIMESTAMP,
+    finished_at = NULL
+WHERE id = $1 AND status IN ('failed', 'cancelled')
+RETURNING *;
+
+-- name: CancelJob :one
+UPDATE jobs
+SET status = 'cancelled',
+    heartbeat_at = NULL,
+    finished_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status IN ('queued', 'running')
+RETURNING *;
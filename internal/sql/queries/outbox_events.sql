@@ -0,0 +1,54 @@
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox_events (
+    user_id, event_type, payload
+) VALUES (
+    $1, $2, $3
+);
+
+-- name: ClaimOutboxEvents :many
+-- Claims the oldest pending event of each user, so events for the same user
+-- are always published in the order they were written. Claimed events are
+-- leased until lease_until, and not claimed again before then unless the
+-- dispatcher records them as failed.
+UPDATE outbox_events
+SET next_attempt_at = sqlc.arg('lease_until')
+WHERE id IN (
+    SELECT o.id FROM outbox_events o
+    WHERE o.status = 'pending'
+      AND o.next_attempt_at <= CURRENT_TIMESTAMP
+      AND NOT EXISTS (
+        SELECT 1 FROM outbox_events earlier
+        WHERE earlier.user_id = o.user_id
+          AND earlier.status = 'pending'
+          AND earlier.id < o.id
+      )
+    ORDER BY o.id
+    LIMIT sqlc.arg('max_events')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox_events
+SET published_to = array_append(published_to, sqlc.arg('sink')::text)
+WHERE id = sqlc.arg('id')
+  AND NOT sqlc.arg('sink')::text = ANY(published_to);
+
+-- name: MarkOutboxEventDispatched :exec
+UPDATE outbox_events
+SET status = 'dispatched',
+    attempts = attempts + 1,
+    last_error = NULL,
+    dispatched_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox_events
+SET status = $2,
+    attempts = attempts + 1,
+    last_error = $3,
+    next_attempt_at = $4
+WHERE id = $1;
+
+-- name: Notify :exec
+SELECT pg_notify(sqlc.arg('channel')::text, sqlc.arg('payload')::text);
@@ -0,0 +1,61 @@
+-- name: CreateUserImport :one
+INSERT INTO user_imports (
+    format, on_duplicate, dry_run, column_map, actor, request_id, framework
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: CreateUserImportFile :exec
+INSERT INTO user_import_files (
+    import_id, data
+) VALUES (
+    $1, $2
+);
+
+-- name: GetUserImport :one
+SELECT * FROM user_imports
+WHERE id = $1 LIMIT 1;
+
+-- name: GetUserImportFile :one
+SELECT data FROM user_import_files
+WHERE import_id = $1 LIMIT 1;
+
+-- name: DeleteUserImportFile :exec
+DELETE FROM user_import_files
+WHERE import_id = $1;
+
+-- name: StartUserImport :one
+UPDATE user_imports
+SET status = 'running',
+    started_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: UpdateUserImportProgress :exec
+UPDATE user_imports
+SET total_rows = $2,
+    processed_rows = $3,
+    created_rows = $4,
+    updated_rows = $5,
+    skipped_rows = $6,
+    failed_rows = $7
+WHERE id = $1;
+
+-- name: FinishUserImport :exec
+UPDATE user_imports
+SET status = $2,
+    error = $3,
+    finished_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: CreateUserImportErrors :copyfrom
+INSERT INTO user_import_errors (
+    import_id, row_number, email, error
+) VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: ListUserImportErrors :many
+SELECT * FROM user_import_errors
+WHERE import_id = $1
+ORDER BY row_number;
@@ -1,11 +1,41 @@
 -- name: GetUser :one
 SELECT * FROM users
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetUserForUpdate :one
+SELECT * FROM users
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1
+FOR UPDATE;
+
+-- name: GetUserIncludingDeleted :one
+SELECT * FROM users
 WHERE id = $1 LIMIT 1;
 
+-- name: GetUsersForUpdateByIDs :many
+SELECT * FROM users
+WHERE id = ANY(sqlc.arg('ids')::int[]) AND deleted_at IS NULL
+ORDER BY id
+FOR UPDATE;
+
+-- name: GetActiveUsersByEmail :many
+SELECT * FROM users
+WHERE email = ANY(sqlc.arg('emails')::text[]) AND deleted_at IS NULL;
+
 -- name: GetUsers :many
 SELECT * FROM users
+WHERE deleted_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: GetUsersIncludingDeleted :many
+SELECT * FROM users
 ORDER BY created_at DESC;
 
+-- name: ListUsers :many
+SELECT * FROM users
+WHERE sqlc.arg('include_deleted')::boolean OR deleted_at IS NULL
+ORDER BY id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
 -- name: CreateUser :one
 INSERT INTO users (
     name, email, age
@@ -13,13 +43,74 @@ INSERT INTO users (
     $1, $2, $3
 ) RETURNING *;
 
+-- name: CreateUsers :copyfrom
+INSERT INTO users (
+    name, email, age
+) VALUES (
+    $1, $2, $3
+);
+
+-- name: CreateUserIfAbsent :batchone
+-- Returns no row when the email is already in use by an active user.
+INSERT INTO users (
+    name, email, age
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (email) WHERE deleted_at IS NULL DO NOTHING
+RETURNING *;
+
+-- name: UpsertUser :batchone
+-- Creates the user, or updates the name and age of the active user with the
+-- same email. Returns no row when that user is already up to date.
+INSERT INTO users (
+    name, email, age
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (email) WHERE deleted_at IS NULL DO UPDATE
+SET name = EXCLUDED.name,
+    age = EXCLUDED.age
+WHERE users.name <> EXCLUDED.name OR users.age <> EXCLUDED.age
+RETURNING *, (xmax = 0) AS inserted;
+
 -- name: UpdateUser :one
 UPDATE users
 SET name = $2,
     email = $3,
     age = $4
-WHERE id = $1 RETURNING *;
+WHERE id = $1 AND deleted_at IS NULL RETURNING *;
+
+-- name: UpdateUserIfEmailFree :batchone
+-- Returns no row when another active user already has the new email, which
+-- includes emails taken by earlier statements of the same batch.
+UPDATE users
+SET name = $2,
+    email = $3,
+    age = $4
+WHERE id = $1 AND deleted_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM users other
+    WHERE other.email = $3 AND other.id <> $1 AND other.deleted_at IS NULL
+  )
+RETURNING *;
+
+-- name: DeleteUser :one
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL RETURNING *;
+
+-- name: DeleteUsers :many
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = ANY(sqlc.arg('ids')::int[]) AND deleted_at IS NULL RETURNING *;
+
+-- name: RestoreUser :one
+UPDATE users
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL RETURNING *;
 
--- name: DeleteUser :exec
+-- name: PurgeDeletedUsers :many
 DELETE FROM users
-WHERE id = $1;
\ No newline at end of file
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+RETURNING *;
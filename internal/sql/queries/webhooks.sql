@@ -0,0 +1,107 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (
+    url, secret, events
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhooks :many
+SELECT * FROM webhooks
+ORDER BY id;
+
+-- name: UpdateWebhook :one
+-- Saving a webhook as active clears any failure streak that disabled it.
+UPDATE webhooks
+SET url = $2,
+    secret = $3,
+    events = $4,
+    active = $5,
+    failure_count = CASE WHEN $5 THEN 0 ELSE failure_count END,
+    disabled_at = CASE WHEN $5 THEN NULL ELSE disabled_at END
+WHERE id = $1 RETURNING *;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1;
+
+-- name: EnqueueWebhookDeliveries :exec
+INSERT INTO webhook_deliveries (
+    webhook_id, event_id, event_type, payload
+)
+SELECT id, sqlc.arg('event_id')::bigint, sqlc.arg('event_type')::text, sqlc.arg('payload')::jsonb
+FROM webhooks
+WHERE active
+  AND (cardinality(events) = 0 OR sqlc.arg('event_type')::text = ANY(events))
+ON CONFLICT (webhook_id, event_id) DO NOTHING;
+
+-- name: ClaimWebhookDeliveries :many
+-- Claims due deliveries of active webhooks, leasing them until lease_until:
+-- they are not claimed again before then unless the deliverer records them
+-- as failed.
+UPDATE webhook_deliveries d
+SET next_attempt_at = sqlc.arg('lease_until')
+FROM webhooks w
+WHERE w.id = d.webhook_id
+  AND d.id IN (
+    SELECT due.id FROM webhook_deliveries due
+    JOIN webhooks active ON active.id = due.webhook_id
+    WHERE due.status = 'pending'
+      AND due.next_attempt_at <= CURRENT_TIMESTAMP
+      AND active.active
+    ORDER BY due.id
+    LIMIT sqlc.arg('max_deliveries')
+    FOR UPDATE OF due SKIP LOCKED
+  )
+RETURNING d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.attempts, w.url, w.secret;
+
+-- name: CreateWebhookDeliveryAttempt :exec
+INSERT INTO webhook_delivery_attempts (
+    delivery_id, response_code, error, duration_ms
+) VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: UpdateWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = $2,
+    attempts = attempts + 1,
+    last_response_code = $3,
+    last_error = $4,
+    next_attempt_at = $5
+WHERE id = $1;
+
+-- name: ResetWebhookFailures :exec
+UPDATE webhooks
+SET failure_count = 0
+WHERE id = $1;
+
+-- name: RecordWebhookFailure :one
+-- Disables the webhook once it reaches max_failures consecutive failures.
+UPDATE webhooks
+SET failure_count = failure_count + 1,
+    active = active AND failure_count + 1 < sqlc.arg('max_failures')::int,
+    disabled_at = CASE
+        WHEN active AND failure_count + 1 >= sqlc.arg('max_failures')::int THEN CURRENT_TIMESTAMP
+        ELSE disabled_at
+    END
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = $1
+ORDER BY id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: RedeliverWebhookDelivery :one
+-- Queues the delivery again with a fresh set of attempts.
+UPDATE webhook_deliveries
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND webhook_id = $2
+RETURNING *;
@@ -0,0 +1,49 @@
+-- +goose Up
+CREATE TABLE user_imports (
+    id SERIAL PRIMARY KEY,
+    -- pending, running, succeeded or failed.
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    format VARCHAR(16) NOT NULL,
+    -- What to do with rows whose email is already in use: skip, fail or upsert.
+    on_duplicate VARCHAR(16) NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    -- User field to source column name, for sources with other headers.
+    column_map JSONB NOT NULL DEFAULT '{}',
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    created_rows INTEGER NOT NULL DEFAULT 0,
+    updated_rows INTEGER NOT NULL DEFAULT 0,
+    skipped_rows INTEGER NOT NULL DEFAULT 0,
+    failed_rows INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    actor TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    framework VARCHAR(16) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX user_imports_pending_idx ON user_imports (id) WHERE status = 'pending';
+
+-- Uploaded files are kept apart so reading an import's progress never loads
+-- them, and are removed once the import has been processed.
+CREATE TABLE user_import_files (
+    import_id INTEGER PRIMARY KEY REFERENCES user_imports (id) ON DELETE CASCADE,
+    data BYTEA NOT NULL
+);
+
+CREATE TABLE user_import_errors (
+    id BIGSERIAL PRIMARY KEY,
+    import_id INTEGER NOT NULL REFERENCES user_imports (id) ON DELETE CASCADE,
+    row_number INTEGER NOT NULL,
+    email TEXT NOT NULL,
+    error TEXT NOT NULL
+);
+
+CREATE INDEX user_import_errors_import_id_idx ON user_import_errors (import_id, row_number);
+
+-- +goose Down
+DROP TABLE user_import_errors;
+DROP TABLE user_import_files;
+DROP TABLE user_imports;
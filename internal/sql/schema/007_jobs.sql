@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE jobs (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    -- queued, running, succeeded, failed or cancelled.
+    status VARCHAR(16) NOT NULL DEFAULT 'queued',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    run_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Set on jobs that must only be enqueued once, such as each run of a
+    -- recurring schedule that every server sees come due.
+    unique_key TEXT UNIQUE,
+    last_error TEXT,
+    -- Refreshed while a worker is running the job; a stale heartbeat means
+    -- the worker died and the job can be claimed again.
+    heartbeat_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX jobs_queued_idx ON jobs (run_at, id) WHERE status = 'queued';
+CREATE INDEX jobs_running_idx ON jobs (heartbeat_at) WHERE status = 'running';
+CREATE INDEX jobs_status_idx ON jobs (status, id);
+
+-- Imports are queued as jobs now rather than claimed from their own table.
+DROP INDEX user_imports_pending_idx;
+
+-- +goose Down
+CREATE INDEX user_imports_pending_idx ON user_imports (id) WHERE status = 'pending';
+
+DROP TABLE jobs;
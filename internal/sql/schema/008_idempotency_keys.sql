@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE idempotency_keys (
+    key TEXT PRIMARY KEY,
+    -- Hash of the method, path, query, credentials and body of the first
+    -- request with the key, which a repeat must match.
+    fingerprint TEXT NOT NULL,
+    -- The response, stored once the first request has finished. A NULL
+    -- status means it is still in progress.
+    status INTEGER,
+    headers JSONB,
+    body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idempotency_keys_expires_idx ON idempotency_keys (expires_at);
+
+-- +goose Down
+DROP TABLE idempotency_keys;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- The sinks each event has been published to, so an event retried after some
+-- of its sinks failed is only published to the others.
+ALTER TABLE outbox_events ADD COLUMN published_to TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE outbox_events DROP COLUMN published_to;
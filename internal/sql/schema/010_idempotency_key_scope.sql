@@ -0,0 +1,21 @@
+-- +goose Up
+-- Keys are scoped to the client and route of the request that claimed them,
+-- so the same key sent by another client or to another route is a key of
+-- its own. Keys stored before are left in a scope of their own and expire.
+ALTER TABLE idempotency_keys ADD COLUMN scope TEXT NOT NULL DEFAULT '';
+-- A random token of each claim, so a request releases or completes the key
+-- only while its claim has not been taken over by a retry.
+ALTER TABLE idempotency_keys ADD COLUMN claim_token TEXT NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys ALTER COLUMN scope DROP DEFAULT;
+ALTER TABLE idempotency_keys ALTER COLUMN claim_token DROP DEFAULT;
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (scope, key);
+
+-- +goose Down
+DELETE FROM idempotency_keys a
+USING idempotency_keys b
+WHERE a.key = b.key AND a.created_at < b.created_at;
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (key);
+ALTER TABLE idempotency_keys DROP COLUMN claim_token;
+ALTER TABLE idempotency_keys DROP COLUMN scope;
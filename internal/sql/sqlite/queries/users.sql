@@ -0,0 +1,42 @@
+-- name: GetUser :one
+SELECT * FROM users
+WHERE id = ? AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetUserIncludingDeleted :one
+SELECT * FROM users
+WHERE id = ? LIMIT 1;
+
+-- name: ListUsers :many
+SELECT * FROM users
+WHERE CAST(sqlc.arg('include_deleted') AS BOOLEAN) OR deleted_at IS NULL
+ORDER BY id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CreateUser :one
+INSERT INTO users (
+    name, email, age
+) VALUES (
+    ?, ?, ?
+) RETURNING *;
+
+-- name: UpdateUser :one
+UPDATE users
+SET name = ?2,
+    email = ?3,
+    age = ?4
+WHERE id = ?1 AND deleted_at IS NULL RETURNING *;
+
+-- name: DeleteUser :one
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = ? AND deleted_at IS NULL RETURNING *;
+
+-- name: RestoreUser :one
+UPDATE users
+SET deleted_at = NULL
+WHERE id = ? AND deleted_at IS NOT NULL RETURNING *;
+
+-- name: PurgeDeletedUsers :many
+DELETE FROM users
+WHERE deleted_at IS NOT NULL AND deleted_at < ?
+RETURNING *;
@@ -0,0 +1,11 @@
+// Package sqlite holds the SQLite migrations and queries, which the sqlitedb
+// package is generated from.
+package sqlite
+
+import "embed"
+
+// Schema is the migrations, which set up an in-memory database when applied
+// in order.
+//
+//go:embed schema/*.sql
+var Schema embed.FS
@@ -0,0 +1,82 @@
+// Package sticky gives clients read-your-writes consistency while reads go
+// to replicas, which may lag behind the primary: after a client writes, its
+// reads go to the primary for a short window. The window is carried in a
+// cookie, or in a header for clients without cookies.
+package sticky
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+)
+
+const (
+	// Header answers a write with when the reads of the client may go to
+	// replicas again, in Unix milliseconds. A client without cookies sends
+	// it back with its reads.
+	Header     = "Read-Primary-Until"
+	cookieName = "read_primary_until"
+)
+
+// Sticky sends the statements of recent writers to the primary.
+type Sticky struct {
+	window  time.Duration
+	enabled bool
+}
+
+func New(cfg config.DatabaseConfig) *Sticky {
+	return &Sticky{window: cfg.StickyWindow, enabled: len(cfg.ReplicaURLs) > 0}
+}
+
+// Middleware runs writes, and the reads of a client within the window after
+// its last write, with every statement on the primary. Without replicas it
+// does nothing.
+func (s *Sticky) Middleware(next http.Handler) http.Handler {
+	if !s.enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if s.sticks(r, now) {
+				r = r.WithContext(database.UsePrimary(r.Context()))
+			}
+		default:
+			until := strconv.FormatInt(now.Add(s.window).UnixMilli(), 10)
+			w.Header().Set(Header, until)
+			http.SetCookie(w, &http.Cookie{
+				Name:     cookieName,
+				Value:    until,
+				Path:     "/",
+				MaxAge:   int((s.window + time.Second - 1) / time.Second),
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+			r = r.WithContext(database.UsePrimary(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sticks reports whether the client of r wrote within the window, by the
+// header or, failing that, the cookie. A time further off than the window
+// was not given out here, and is ignored.
+func (s *Sticky) sticks(r *http.Request, now time.Time) bool {
+	value := r.Header.Get(Header)
+	if value == "" {
+		if cookie, err := r.Cookie(cookieName); err == nil {
+			value = cookie.Value
+		}
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	until := time.UnixMilli(ms)
+	return until.After(now) && !until.After(now.Add(s.window))
+}
@@ -0,0 +1,34 @@
+package store_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/store"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/store/storetest"
+)
+
+// TestMySQL runs the suite on the database TEST_MYSQL_DSN names, which must
+// have the MySQL migrations applied. Its users are deleted.
+func TestMySQL(t *testing.T) {
+	runOn(t, "TEST_MYSQL_DSN")
+}
+
+// runOn runs the suite on the database the environment variable env names,
+// skipping the test when it is not set.
+func runOn(t *testing.T, env string) {
+	t.Helper()
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skipf("%s is not set", env)
+	}
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := store.Open(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		storetest.Clear(t, s)
+		return s
+	})
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresStore runs the queries the API servers run.
+type postgresStore struct {
+	pool *pgxpool.Pool
+	q    *database.Queries
+}
+
+func openPostgres(dsn string) (*postgresStore, error) {
+	pool, err := database.Connect(dsn, "the DSN")
+	if err != nil {
+		return nil, err
+	}
+	return &postgresStore{pool: pool, q: database.New(pool)}, nil
+}
+
+func (s *postgresStore) GetUser(ctx context.Context, id int32, includeDeleted bool) (database.User, error) {
+	if includeDeleted {
+		return postgresUser(s.q.GetUserIncludingDeleted(ctx, id))
+	}
+	return postgresUser(s.q.GetUser(ctx, id))
+}
+
+func (s *postgresStore) ListUsers(ctx context.Context, arg database.ListUsersParams) ([]database.User, error) {
+	return s.q.ListUsers(ctx, arg)
+}
+
+func (s *postgresStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	return postgresUser(s.q.CreateUser(ctx, arg))
+}
+
+func (s *postgresStore) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+	return postgresUser(s.q.UpdateUser(ctx, arg))
+}
+
+func (s *postgresStore) DeleteUser(ctx context.Context, id int32) (database.User, error) {
+	return postgresUser(s.q.DeleteUser(ctx, id))
+}
+
+func (s *postgresStore) RestoreUser(ctx context.Context, id int32) (database.User, error) {
+	return postgresUser(s.q.RestoreUser(ctx, id))
+}
+
+func (s *postgresStore) PurgeDeletedUsers(ctx context.Context, cutoff time.Time) ([]database.User, error) {
+	return s.q.PurgeDeletedUsers(ctx, pgtype.Timestamptz{Time: cutoff, Valid: true})
+}
+
+func (s *postgresStore) Close() error {
+	s.pool.Close()
+	return nil
+}
+
+// postgresUser turns the errors of a query returning a user into those of
+// Store.
+func postgresUser(user database.User, err error) (database.User, error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return database.User{}, ErrNotFound
+	case database.IsUniqueViolation(err):
+		return database.User{}, ErrEmailTaken
+	}
+	return user, err
+}
@@ -0,0 +1,9 @@
+package store_test
+
+import "testing"
+
+// TestPostgres runs the suite on the database TEST_DATABASE_URL names, which
+// must have the migrations applied. Its users are deleted.
+func TestPostgres(t *testing.T) {
+	runOn(t, "TEST_DATABASE_URL")
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database/sqlitedb"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/sql/sqlite"
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteStore runs the SQLite queries, on the pure Go driver.
+type sqliteStore struct {
+	db *sql.DB
+	q  *sqlitedb.Queries
+}
+
+func openSQLite(dsn string) (*sqliteStore, error) {
+	_, path, _ := strings.Cut(dsn, ":")
+	path = strings.TrimPrefix(path, "//")
+	if path == "" {
+		return nil, errors.New("invalid SQLite DSN: no file, or :memory:")
+	}
+	memory := strings.HasPrefix(path, ":memory:")
+
+	// Writers wait for each other rather than failing straight away, and
+	// times are written as UTC text like CURRENT_TIMESTAMP, so they compare
+	// in time order.
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open("sqlite", path+sep+"_pragma=busy_timeout(5000)&_time_format=sqlite")
+	if err != nil {
+		return nil, err
+	}
+
+	if memory {
+		// Each connection to :memory: is a database of its own, so there
+		// must only ever be the one.
+		db.SetMaxOpenConns(1)
+		if err := applySchema(db, sqlite.Schema); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &sqliteStore{db: db, q: sqlitedb.New(db)}, nil
+}
+
+// applySchema runs the goose Up part of each migration in schema, in order.
+func applySchema(db *sql.DB, schema fs.FS) error {
+	files, err := fs.Glob(schema, "schema/*.sql")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(schema, file)
+		if err != nil {
+			return err
+		}
+		_, up, _ := strings.Cut(string(data), "-- +goose Up")
+		up, _, _ = strings.Cut(up, "-- +goose Down")
+		if _, err := db.Exec(up); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+func (s *sqliteStore) GetUser(ctx context.Context, id int32, includeDeleted bool) (database.User, error) {
+	if includeDeleted {
+		return sqliteUser(s.q.GetUserIncludingDeleted(ctx, int64(id)))
+	}
+	return sqliteUser(s.q.GetUser(ctx, int64(id)))
+}
+
+func (s *sqliteStore) ListUsers(ctx context.Context, arg database.ListUsersParams) ([]database.User, error) {
+	users, err := s.q.ListUsers(ctx, sqlitedb.ListUsersParams{
+		IncludeDeleted: arg.IncludeDeleted,
+		Limit:          int64(arg.Limit),
+		Offset:         int64(arg.Offset),
+	})
+	return sqliteUsers(users), err
+}
+
+func (s *sqliteStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	return sqliteUser(s.q.CreateUser(ctx, sqlitedb.CreateUserParams{
+		Name:  arg.Name,
+		Email: arg.Email,
+		Age:   int64(arg.Age),
+	}))
+}
+
+func (s *sqliteStore) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+	return sqliteUser(s.q.UpdateUser(ctx, sqlitedb.UpdateUserParams{
+		ID:    int64(arg.ID),
+		Name:  arg.Name,
+		Email: arg.Email,
+		Age:   int64(arg.Age),
+	}))
+}
+
+func (s *sqliteStore) DeleteUser(ctx context.Context, id int32) (database.User, error) {
+	return sqliteUser(s.q.DeleteUser(ctx, int64(id)))
+}
+
+func (s *sqliteStore) RestoreUser(ctx context.Context, id int32) (database.User, error) {
+	return sqliteUser(s.q.RestoreUser(ctx, int64(id)))
+}
+
+func (s *sqliteStore) PurgeDeletedUsers(ctx context.Context, cutoff time.Time) ([]database.User, error) {
+	users, err := s.q.PurgeDeletedUsers(ctx, sql.NullTime{Time: cutoff.UTC(), Valid: true})
+	return sqliteUsers(users), err
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+func sqliteUser(user sqlitedb.User, err error) (database.User, error) {
+	var sqliteErr *sqlitedriver.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return database.User{}, ErrNotFound
+	case errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return database.User{}, ErrEmailTaken
+	case err != nil:
+		return database.User{}, err
+	}
+
+	return database.User{
+		ID:        int32(user.ID),
+		Name:      user.Name,
+		Email:     user.Email,
+		Age:       int32(user.Age),
+		CreatedAt: timestamptz(user.CreatedAt),
+		DeletedAt: timestamptz(user.DeletedAt),
+	}, nil
+}
+
+func sqliteUsers(users []sqlitedb.User) []database.User {
+	out := make([]database.User, len(users))
+	for i, user := range users {
+		out[i], _ = sqliteUser(user, nil)
+	}
+	return out
+}
+
+func timestamptz(t sql.NullTime) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t.Time, Valid: t.Valid}
+}
@@ -0,0 +1,19 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/store"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/store/storetest"
+)
+
+func TestSQLite(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := store.Open("sqlite::memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
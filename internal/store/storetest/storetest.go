@@ -0,0 +1,288 @@
+// Package storetest is the conformance suite of store.Store, which every
+// engine runs against a database of its own.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/store"
+)
+
+// Run runs the suite, calling open for an empty users table for each test.
+// open closes the store when the test ends.
+func Run(t *testing.T, open func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s store.Store)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"CreateTakenEmail", testCreateTakenEmail},
+		{"Missing", testMissing},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"Restore", testRestore},
+		{"List", testList},
+		{"Purge", testPurge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, open(t))
+		})
+	}
+}
+
+// Clear empties the users table of s through the Store itself, for engines
+// whose tests share a database.
+func Clear(t *testing.T, s store.Store) {
+	t.Helper()
+	ctx := context.Background()
+	for {
+		users, err := s.ListUsers(ctx, database.ListUsersParams{Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) == 0 {
+			break
+		}
+		for _, user := range users {
+			deleteUser(t, s, user.ID)
+		}
+	}
+	if _, err := s.PurgeDeletedUsers(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func create(t *testing.T, s store.Store, name, email string) database.User {
+	t.Helper()
+	user, err := s.CreateUser(context.Background(), database.CreateUserParams{Name: name, Email: email, Age: 30})
+	if err != nil {
+		t.Fatalf("CreateUser(%s) = %v", email, err)
+	}
+	return user
+}
+
+func deleteUser(t *testing.T, s store.Store, id int32) database.User {
+	t.Helper()
+	user, err := s.DeleteUser(context.Background(), id)
+	if err != nil {
+		t.Fatalf("DeleteUser(%d) = %v", id, err)
+	}
+	return user
+}
+
+func ids(users []database.User) []int32 {
+	ids := make([]int32, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+func testCreateAndGet(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Minute)
+	created, err := s.CreateUser(ctx, database.CreateUserParams{Name: "Ada", Email: "ada@example.com", Age: 36})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || created.Name != "Ada" || created.Email != "ada@example.com" || created.Age != 36 {
+		t.Fatalf("created %+v", created)
+	}
+	if created.DeletedAt.Valid {
+		t.Errorf("created user has DeletedAt %v", created.DeletedAt.Time)
+	}
+	// A time read back in the wrong zone would be hours out.
+	if !created.CreatedAt.Valid || created.CreatedAt.Time.Before(before) || created.CreatedAt.Time.After(time.Now().Add(time.Minute)) {
+		t.Errorf("CreatedAt = %v, want about %v", created.CreatedAt.Time, time.Now())
+	}
+
+	got, err := s.GetUser(ctx, created.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != created.ID || got.Email != created.Email || !got.CreatedAt.Time.Equal(created.CreatedAt.Time) {
+		t.Errorf("GetUser = %+v, want %+v", got, created)
+	}
+}
+
+func testCreateTakenEmail(t *testing.T, s store.Store) {
+	create(t, s, "Ada", "ada@example.com")
+	_, err := s.CreateUser(context.Background(), database.CreateUserParams{Name: "Other", Email: "ada@example.com", Age: 20})
+	if !errors.Is(err, store.ErrEmailTaken) {
+		t.Errorf("CreateUser with a taken email = %v, want ErrEmailTaken", err)
+	}
+}
+
+func testMissing(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	calls := map[string]func() error{
+		"GetUser": func() error {
+			_, err := s.GetUser(ctx, 404, false)
+			return err
+		},
+		"GetUser including deleted": func() error {
+			_, err := s.GetUser(ctx, 404, true)
+			return err
+		},
+		"UpdateUser": func() error {
+			_, err := s.UpdateUser(ctx, database.UpdateUserParams{ID: 404, Name: "x", Email: "x@example.com", Age: 1})
+			return err
+		},
+		"DeleteUser": func() error {
+			_, err := s.DeleteUser(ctx, 404)
+			return err
+		},
+		"RestoreUser": func() error {
+			_, err := s.RestoreUser(ctx, 404)
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s of a missing user = %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func testUpdate(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	ada := create(t, s, "Ada", "ada@example.com")
+	create(t, s, "Grace", "grace@example.com")
+
+	updated, err := s.UpdateUser(ctx, database.UpdateUserParams{ID: ada.ID, Name: "Ada L", Email: "lovelace@example.com", Age: 37})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ID != ada.ID || updated.Name != "Ada L" || updated.Email != "lovelace@example.com" || updated.Age != 37 {
+		t.Errorf("updated %+v", updated)
+	}
+
+	// MySQL counts a row set to what it was as unchanged; that is still a
+	// found user.
+	if _, err := s.UpdateUser(ctx, database.UpdateUserParams{ID: ada.ID, Name: "Ada L", Email: "lovelace@example.com", Age: 37}); err != nil {
+		t.Errorf("UpdateUser to the same values = %v", err)
+	}
+
+	_, err = s.UpdateUser(ctx, database.UpdateUserParams{ID: ada.ID, Name: "Ada", Email: "grace@example.com", Age: 37})
+	if !errors.Is(err, store.ErrEmailTaken) {
+		t.Errorf("UpdateUser to a taken email = %v, want ErrEmailTaken", err)
+	}
+
+	deleteUser(t, s, ada.ID)
+	_, err = s.UpdateUser(ctx, database.UpdateUserParams{ID: ada.ID, Name: "Ada", Email: "ada@example.com", Age: 37})
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("UpdateUser of a deleted user = %v, want ErrNotFound", err)
+	}
+}
+
+func testDelete(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	ada := create(t, s, "Ada", "ada@example.com")
+
+	deleted := deleteUser(t, s, ada.ID)
+	if deleted.ID != ada.ID || !deleted.DeletedAt.Valid {
+		t.Errorf("deleted %+v", deleted)
+	}
+	if _, err := s.GetUser(ctx, ada.ID, false); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetUser of a deleted user = %v, want ErrNotFound", err)
+	}
+	if got, err := s.GetUser(ctx, ada.ID, true); err != nil || !got.DeletedAt.Valid {
+		t.Errorf("GetUser including deleted = %+v, %v", got, err)
+	}
+	if _, err := s.DeleteUser(ctx, ada.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeleteUser twice = %v, want ErrNotFound", err)
+	}
+
+	// The email of a deleted user is free for a new one.
+	if again := create(t, s, "Ada", "ada@example.com"); again.ID == ada.ID {
+		t.Errorf("new user reused ID %d", ada.ID)
+	}
+}
+
+func testRestore(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	ada := create(t, s, "Ada", "ada@example.com")
+
+	if _, err := s.RestoreUser(ctx, ada.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("RestoreUser of an active user = %v, want ErrNotFound", err)
+	}
+
+	deleteUser(t, s, ada.ID)
+	restored, err := s.RestoreUser(ctx, ada.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ID != ada.ID || restored.DeletedAt.Valid {
+		t.Errorf("restored %+v", restored)
+	}
+
+	deleteUser(t, s, ada.ID)
+	create(t, s, "New Ada", "ada@example.com")
+	if _, err := s.RestoreUser(ctx, ada.ID); !errors.Is(err, store.ErrEmailTaken) {
+		t.Errorf("RestoreUser with the email taken = %v, want ErrEmailTaken", err)
+	}
+}
+
+func testList(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	var all []int32
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"} {
+		all = append(all, create(t, s, "User", email).ID)
+	}
+	deleteUser(t, s, all[1])
+
+	tests := []struct {
+		name string
+		arg  database.ListUsersParams
+		want []int32
+	}{
+		{"active", database.ListUsersParams{Limit: 10}, []int32{all[0], all[2], all[3]}},
+		{"with deleted", database.ListUsersParams{IncludeDeleted: true, Limit: 10}, all},
+		{"page", database.ListUsersParams{Limit: 2, Offset: 1}, []int32{all[2], all[3]}},
+		{"past the end", database.ListUsersParams{Limit: 2, Offset: 3}, []int32{}},
+	}
+	for _, tt := range tests {
+		users, err := s.ListUsers(ctx, tt.arg)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := ids(users); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: ListUsers = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func testPurge(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	ada := create(t, s, "Ada", "ada@example.com")
+	grace := create(t, s, "Grace", "grace@example.com")
+	deleteUser(t, s, ada.ID)
+
+	purged, err := s.PurgeDeletedUsers(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(purged) != 0 {
+		t.Errorf("purged %v before the users were deleted", ids(purged))
+	}
+
+	purged, err = s.PurgeDeletedUsers(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(purged); !slices.Equal(got, []int32{ada.ID}) {
+		t.Errorf("purged %v, want [%d]", got, ada.ID)
+	}
+	if _, err := s.GetUser(ctx, ada.ID, true); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetUser of a purged user = %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetUser(ctx, grace.ID, false); err != nil {
+		t.Errorf("GetUser of an active user after a purge = %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+// Package timeout gives every request a deadline, by its route, and answers
+// requests that run out of time with a problem details body.
+package timeout
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/metrics"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/models"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/route"
+)
+
+var timeouts = metrics.NewCounter("http_request_timeouts_total",
+	"Requests answered with 503 or 504 for running out of time.",
+	"server", "method", "route", "status")
+
+// Deadlines sets the deadlines of the requests to one server.
+type Deadlines struct {
+	cfg    config.TimeoutConfig
+	server string
+}
+
+func New(cfg config.TimeoutConfig, server string) *Deadlines {
+	return &Deadlines{cfg: cfg, server: server}
+}
+
+// Middleware runs each request with the timeout of its route, which must
+// have matched, as the deadline of its context. Database statements are
+// canceled when it passes, and the response the handler makes of that is
+// replaced with a 503, or a 504 if a statement timed out before the request
+// did. Once the response has started it cannot be replaced, and a streamed
+// response just ends.
+func (d *Deadlines) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pattern := route.Pattern(r)
+		timeout := d.cfg.For(r.Method, pattern)
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(database.TrackTimeouts(r.Context()), timeout)
+		defer cancel()
+		tw := &timeoutWriter{ResponseWriter: w, ctx: ctx, timeout: timeout, timedOut: func(status int) {
+			timeouts.Inc(d.server, r.Method, pattern, strconv.Itoa(status))
+		}}
+		next.ServeHTTP(tw, r.WithContext(ctx))
+
+		if !tw.wroteHeader && ctx.Err() != nil {
+			// The handler gave up without answering
+			tw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// timeoutWriter replaces an error response with a timeout problem if the
+// request, or a statement it ran, timed out.
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	timeout  time.Duration
+	timedOut func(status int)
+
+	wroteHeader bool
+	// replaced is set once the response has been replaced, after which the
+	// writes of the handler are dropped.
+	replaced bool
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	if tw.wroteHeader || status < http.StatusOK {
+		tw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	tw.wroteHeader = true
+
+	if status >= http.StatusBadRequest {
+		switch {
+		case errors.Is(tw.ctx.Err(), context.DeadlineExceeded):
+			tw.replace(http.StatusServiceUnavailable, "Request timed out",
+				"The request did not finish within "+tw.timeout.String()+".")
+			return
+		case database.TimedOut(tw.ctx):
+			tw.replace(http.StatusGatewayTimeout, "Database timed out",
+				"A database statement the request ran did not finish in time.")
+			return
+		}
+	}
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *timeoutWriter) replace(status int, title, detail string) {
+	tw.replaced = true
+	tw.timedOut(status)
+
+	h := tw.ResponseWriter.Header()
+	for _, name := range []string{"Content-Length", "Content-Encoding", "Content-Disposition", "ETag", "Last-Modified", "Location"} {
+		h.Del(name)
+	}
+	h.Set("Content-Type", "application/problem+json")
+	if status == http.StatusServiceUnavailable {
+		h.Set("Retry-After", "1")
+	}
+	tw.ResponseWriter.WriteHeader(status)
+	json.NewEncoder(tw.ResponseWriter).Encode(models.Problem{
+		Type:   "about:blank",
+		Title:  title,
+		Status: status,
+		Detail: detail,
+	})
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if tw.replaced {
+		return len(b), nil
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+func (tw *timeoutWriter) Flush() {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if !tw.replaced {
+		http.NewResponseController(tw.ResponseWriter).Flush()
+	}
+}
+
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(tw.ResponseWriter).Hijack()
+}
+
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
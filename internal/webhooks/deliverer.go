@@ -0,0 +1,211 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+
+	// Only the start of a failing receiver's response is kept for debugging.
+	maxErrorBody = 512
+
+	// leaseMargin is the time a lease allows for recording the outcome of a
+	// delivery once its endpoint has answered or timed out.
+	leaseMargin = 30 * time.Second
+)
+
+// Deliverer sends queued deliveries to webhook endpoints.
+type Deliverer struct {
+	cfg    *config.APIConfig
+	client *http.Client
+	db     *database.Queries
+	// withTx runs fn in a transaction, as cfg.WithTx does.
+	withTx func(ctx context.Context, fn func(*database.Queries) error) error
+}
+
+// NewDeliverer returns a Deliverer using client, or a client with the
+// configured timeout when client is nil.
+func NewDeliverer(cfg *config.APIConfig, client *http.Client) *Deliverer {
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Webhooks.Timeout}
+	}
+	return &Deliverer{cfg: cfg, client: client, db: cfg.DB, withTx: cfg.WithTx}
+}
+
+// Run delivers pending deliveries until ctx is done.
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Webhooks.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			claimed, err := d.DeliverBatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Error delivering webhooks: %v", err)
+				}
+				break
+			}
+			if claimed < d.cfg.Webhooks.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverBatch makes one attempt at a batch of due deliveries and returns
+// how many it claimed. The deliveries are leased rather than held locked,
+// and sent concurrently outside any transaction, so an endpoint that is slow
+// to answer holds no locks or connections, and a batch takes no longer than
+// its slowest delivery. A delivery whose deliverer stops before recording
+// its outcome is sent again once the lease runs out.
+func (d *Deliverer) DeliverBatch(ctx context.Context) (int, error) {
+	deliveries, err := d.db.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+		LeaseUntil:    pgtype.Timestamptz{Time: time.Now().Add(d.cfg.Webhooks.Timeout + leaseMargin), Valid: true},
+		MaxDeliveries: int32(d.cfg.Webhooks.BatchSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.attempt(ctx, delivery); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("delivery %d: %w", delivery.ID, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return len(deliveries), errors.Join(errs...)
+}
+
+// attempt sends a delivery, then records the attempt and its outcome in a
+// transaction of their own.
+func (d *Deliverer) attempt(ctx context.Context, delivery database.ClaimWebhookDeliveriesRow) error {
+	start := time.Now()
+	code, sendErr := d.send(ctx, delivery)
+	duration := time.Since(start)
+
+	return d.withTx(ctx, func(q *database.Queries) error {
+		return d.record(ctx, q, delivery, code, sendErr, duration)
+	})
+}
+
+func (d *Deliverer) record(ctx context.Context, q *database.Queries, delivery database.ClaimWebhookDeliveriesRow, code int, sendErr error, duration time.Duration) error {
+	responseCode := pgtype.Int4{Int32: int32(code), Valid: code != 0}
+	lastError := pgtype.Text{}
+	if sendErr != nil {
+		lastError = pgtype.Text{String: sendErr.Error(), Valid: true}
+	}
+
+	if err := q.CreateWebhookDeliveryAttempt(ctx, database.CreateWebhookDeliveryAttemptParams{
+		DeliveryID:   delivery.ID,
+		ResponseCode: responseCode,
+		Error:        lastError,
+		DurationMs:   int32(duration.Milliseconds()),
+	}); err != nil {
+		return err
+	}
+
+	status := StatusSucceeded
+	attempts := int(delivery.Attempts) + 1
+	nextAttempt := time.Now()
+
+	if sendErr != nil {
+		status = StatusPending
+		nextAttempt = nextAttempt.Add(outbox.Backoff(attempts))
+		if attempts >= d.cfg.Webhooks.MaxAttempts {
+			status = StatusFailed
+		}
+
+		webhook, err := q.RecordWebhookFailure(ctx, database.RecordWebhookFailureParams{
+			MaxFailures: int32(d.cfg.Webhooks.MaxFailures),
+			ID:          delivery.WebhookID,
+		})
+		if err != nil {
+			return err
+		}
+		if !webhook.Active && webhook.FailureCount == int32(d.cfg.Webhooks.MaxFailures) {
+			log.Printf("Disabled webhook %d after %d consecutive failures", webhook.ID, webhook.FailureCount)
+		}
+	} else if err := q.ResetWebhookFailures(ctx, delivery.WebhookID); err != nil {
+		return err
+	}
+
+	return q.UpdateWebhookDelivery(ctx, database.UpdateWebhookDeliveryParams{
+		ID:               delivery.ID,
+		Status:           status,
+		LastResponseCode: responseCode,
+		LastError:        lastError,
+		NextAttemptAt:    pgtype.Timestamptz{Time: nextAttempt, Valid: true},
+	})
+}
+
+// send POSTs the signed payload and returns the response status code, which
+// is zero when no response was received.
+func (d *Deliverer) send(ctx context.Context, delivery database.ClaimWebhookDeliveriesRow) (int, error) {
+	// Whatever the client, a delivery must finish within its lease
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Webhooks.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-frameworks-crud-webhooks")
+	req.Header.Set("X-Webhook-ID", strconv.Itoa(int(delivery.WebhookID)))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Event-ID", strconv.FormatInt(delivery.EventID, 10))
+	req.Header.Set("X-Event-Type", delivery.EventType)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,351 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/config"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database/dbtest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const secret = "whsec_test"
+
+// store keeps a webhook and its deliveries as the webhook queries would.
+type store struct {
+	mu         sync.Mutex
+	webhook    database.Webhook
+	deliveries map[int64]*database.WebhookDelivery
+	attempts   []database.CreateWebhookDeliveryAttemptParams
+	// inTx counts the transactions in progress.
+	inTx int
+}
+
+func newStore(url string) *store {
+	return &store{
+		webhook:    database.Webhook{ID: 1, Url: url, Secret: secret, Active: true},
+		deliveries: map[int64]*database.WebhookDelivery{},
+	}
+}
+
+func (s *store) enqueue(id int64, payload string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[id] = &database.WebhookDelivery{
+		ID:            id,
+		WebhookID:     s.webhook.ID,
+		EventID:       id * 10,
+		EventType:     "user.created",
+		Payload:       []byte(payload),
+		Status:        StatusPending,
+		NextAttemptAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+}
+
+func (s *store) delivery(id int64) database.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.deliveries[id]
+}
+
+// due makes a delivery due now, as if its backoff had passed.
+func (s *store) due(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[id].NextAttemptAt.Time = time.Now()
+}
+
+func (s *store) transactions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inTx
+}
+
+func (s *store) db() *dbtest.DB {
+	db := dbtest.New()
+	db.Handle("ClaimWebhookDeliveries", func(args ...any) ([][]any, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		lease, limit := args[0].(pgtype.Timestamptz), args[1].(int32)
+
+		var claimed []database.ClaimWebhookDeliveriesRow
+		for _, d := range s.deliveries {
+			if d.Status != StatusPending || d.NextAttemptAt.Time.After(time.Now()) || !s.webhook.Active || len(claimed) == int(limit) {
+				continue
+			}
+			d.NextAttemptAt = lease
+			claimed = append(claimed, database.ClaimWebhookDeliveriesRow{
+				ID:        d.ID,
+				WebhookID: d.WebhookID,
+				EventID:   d.EventID,
+				EventType: d.EventType,
+				Payload:   d.Payload,
+				Attempts:  d.Attempts,
+				Url:       s.webhook.Url,
+				Secret:    s.webhook.Secret,
+			})
+		}
+		return dbtest.Rows(claimed...), nil
+	})
+	db.Handle("CreateWebhookDeliveryAttempt", func(args ...any) ([][]any, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.attempts = append(s.attempts, database.CreateWebhookDeliveryAttemptParams{
+			DeliveryID:   args[0].(int64),
+			ResponseCode: args[1].(pgtype.Int4),
+			Error:        args[2].(pgtype.Text),
+			DurationMs:   args[3].(int32),
+		})
+		return nil, nil
+	})
+	db.Handle("RecordWebhookFailure", func(args ...any) ([][]any, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.webhook.FailureCount++
+		s.webhook.Active = s.webhook.Active && s.webhook.FailureCount < args[0].(int32)
+		return dbtest.Rows(s.webhook), nil
+	})
+	db.Handle("ResetWebhookFailures", func(args ...any) ([][]any, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.webhook.FailureCount = 0
+		return nil, nil
+	})
+	db.Handle("UpdateWebhookDelivery", func(args ...any) ([][]any, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		d := s.deliveries[args[0].(int64)]
+		d.Status = args[1].(string)
+		d.Attempts++
+		d.LastResponseCode = args[2].(pgtype.Int4)
+		d.LastError = args[3].(pgtype.Text)
+		d.NextAttemptAt = args[4].(pgtype.Timestamptz)
+		return nil, nil
+	})
+	db.Handle("RedeliverWebhookDelivery", func(args ...any) ([][]any, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		d, ok := s.deliveries[args[0].(int64)]
+		if !ok || d.WebhookID != args[1].(int32) {
+			return nil, pgx.ErrNoRows
+		}
+		d.Status = StatusPending
+		d.Attempts = 0
+		d.NextAttemptAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		return dbtest.Rows(*d), nil
+	})
+	return db
+}
+
+// receiver is a webhook endpoint that answers with the next of statuses, or
+// 200 once they run out, and keeps the requests it received.
+type receiver struct {
+	t        *testing.T
+	store    *store
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if n := rc.store.transactions(); n != 0 {
+		rc.t.Errorf("delivery sent inside %d transactions", n)
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) received() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func setup(t *testing.T, maxAttempts int, statuses ...int) (*Deliverer, *store, *receiver) {
+	t.Helper()
+	rc := &receiver{t: t, statuses: statuses}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	s := newStore(srv.URL)
+	rc.store = s
+	db := database.New(s.db())
+
+	cfg := &config.APIConfig{
+		DB: db,
+		Webhooks: config.WebhookConfig{
+			MaxAttempts:  maxAttempts,
+			MaxFailures:  100,
+			Timeout:      5 * time.Second,
+			PollInterval: time.Second,
+			BatchSize:    10,
+		},
+	}
+	d := NewDeliverer(cfg, srv.Client())
+	d.withTx = func(ctx context.Context, fn func(*database.Queries) error) error {
+		s.mu.Lock()
+		s.inTx++
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			s.inTx--
+			s.mu.Unlock()
+		}()
+		return fn(db)
+	}
+	return d, s, rc
+}
+
+func deliver(t *testing.T, d *Deliverer, want int) {
+	t.Helper()
+	claimed, err := d.DeliverBatch(context.Background())
+	if err != nil {
+		t.Fatalf("DeliverBatch: %v", err)
+	}
+	if claimed != want {
+		t.Fatalf("DeliverBatch claimed %d deliveries, want %d", claimed, want)
+	}
+}
+
+func TestDeliverBatchSignsDeliveries(t *testing.T) {
+	d, s, rc := setup(t, 3)
+	s.enqueue(1, `{"id":10,"type":"user.created"}`)
+
+	deliver(t, d, 1)
+
+	if rc.received() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", rc.received())
+	}
+	r, body := rc.requests[0], rc.bodies[0]
+	if string(body) != `{"id":10,"type":"user.created"}` {
+		t.Errorf("body = %s", body)
+	}
+	if err := Verify(secret, r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader), body, time.Minute); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if err := Verify("whsec_other", r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader), body, time.Minute); err == nil {
+		t.Error("signature verified with another secret")
+	}
+	for header, want := range map[string]string{
+		"X-Webhook-ID":       "1",
+		"X-Webhook-Delivery": "1",
+		"X-Event-ID":         "10",
+		"X-Event-Type":       "user.created",
+		"Content-Type":       "application/json",
+	} {
+		if got := r.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	delivery := s.delivery(1)
+	if delivery.Status != StatusSucceeded || delivery.Attempts != 1 || delivery.LastResponseCode.Int32 != http.StatusOK {
+		t.Errorf("delivery = %s after %d attempts with %d, want succeeded after 1 with 200", delivery.Status, delivery.Attempts, delivery.LastResponseCode.Int32)
+	}
+	if len(s.attempts) != 1 || s.attempts[0].ResponseCode.Int32 != http.StatusOK || s.attempts[0].Error.Valid {
+		t.Errorf("attempts = %+v, want one with 200 and no error", s.attempts)
+	}
+}
+
+func TestDeliverBatchRetriesWithBackoff(t *testing.T) {
+	d, s, rc := setup(t, 3, http.StatusInternalServerError)
+	s.enqueue(1, `{}`)
+
+	start := time.Now()
+	deliver(t, d, 1)
+
+	delivery := s.delivery(1)
+	if delivery.Status != StatusPending || delivery.Attempts != 1 || delivery.LastResponseCode.Int32 != http.StatusInternalServerError {
+		t.Fatalf("delivery = %s after %d attempts with %d, want pending after 1 with 500", delivery.Status, delivery.Attempts, delivery.LastResponseCode.Int32)
+	}
+	// The first retry backs off a second, with up to 20% jitter
+	if wait := delivery.NextAttemptAt.Time.Sub(start); wait < time.Second || wait > 1300*time.Millisecond {
+		t.Errorf("next attempt in %v, want 1s to 1.2s", wait)
+	}
+	if s.webhook.FailureCount != 1 {
+		t.Errorf("failure count = %d, want 1", s.webhook.FailureCount)
+	}
+
+	// Not due again until the backoff has passed
+	deliver(t, d, 0)
+
+	s.due(1)
+	deliver(t, d, 1)
+
+	delivery = s.delivery(1)
+	if delivery.Status != StatusSucceeded || delivery.Attempts != 2 {
+		t.Errorf("delivery = %s after %d attempts, want succeeded after 2", delivery.Status, delivery.Attempts)
+	}
+	if rc.received() != 2 || s.webhook.FailureCount != 0 {
+		t.Errorf("receiver got %d requests and failure count = %d, want 2 and 0", rc.received(), s.webhook.FailureCount)
+	}
+	// Each attempt is signed afresh
+	for i, r := range rc.requests {
+		if err := Verify(secret, r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader), rc.bodies[i], time.Minute); err != nil {
+			t.Errorf("attempt %d: Verify: %v", i+1, err)
+		}
+	}
+}
+
+func TestDeliverBatchRedeliversFailedDeliveries(t *testing.T) {
+	d, s, rc := setup(t, 2, http.StatusBadGateway, http.StatusBadGateway, http.StatusServiceUnavailable)
+	s.enqueue(1, `{}`)
+
+	deliver(t, d, 1)
+	s.due(1)
+	deliver(t, d, 1)
+
+	delivery := s.delivery(1)
+	if delivery.Status != StatusFailed || delivery.Attempts != 2 {
+		t.Fatalf("delivery = %s after %d attempts, want failed after 2", delivery.Status, delivery.Attempts)
+	}
+
+	// Given up on, it is not claimed however long it waits
+	s.due(1)
+	deliver(t, d, 0)
+
+	redelivered, err := d.db.RedeliverWebhookDelivery(context.Background(), database.RedeliverWebhookDeliveryParams{ID: 1, WebhookID: 1})
+	if err != nil {
+		t.Fatalf("RedeliverWebhookDelivery: %v", err)
+	}
+	if redelivered.Status != StatusPending || redelivered.Attempts != 0 {
+		t.Fatalf("redelivered = %s after %d attempts, want pending after 0", redelivered.Status, redelivered.Attempts)
+	}
+
+	// A redelivered delivery gets every attempt again, so one more failure
+	// does not give up on it
+	deliver(t, d, 1)
+	if delivery = s.delivery(1); delivery.Status != StatusPending || delivery.Attempts != 1 {
+		t.Fatalf("delivery = %s after %d attempts, want pending after 1", delivery.Status, delivery.Attempts)
+	}
+
+	s.due(1)
+	deliver(t, d, 1)
+	if delivery = s.delivery(1); delivery.Status != StatusSucceeded {
+		t.Errorf("delivery = %s, want succeeded", delivery.Status)
+	}
+	if rc.received() != 4 {
+		t.Errorf("receiver got %d requests, want 4", rc.received())
+	}
+	if got := rc.requests[3].Header.Get("X-Webhook-Delivery"); got != strconv.Itoa(1) {
+		t.Errorf("X-Webhook-Delivery = %q, want 1", got)
+	}
+}
@@ -0,0 +1,35 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/database"
+	"github.com/KennyMwendwaX/go-frameworks-crud/internal/outbox"
+)
+
+// FanoutSink is an outbox sink that queues a delivery of each event for every
+// active webhook subscribed to it. Queuing is idempotent per webhook and
+// event, so events the outbox publishes twice are only delivered once.
+type FanoutSink struct {
+	db *database.Queries
+}
+
+func NewFanoutSink(db *database.Queries) *FanoutSink {
+	return &FanoutSink{db: db}
+}
+
+func (s *FanoutSink) Name() string { return "webhooks" }
+
+func (s *FanoutSink) Publish(ctx context.Context, event outbox.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return s.db.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   payload,
+	})
+}
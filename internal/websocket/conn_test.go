@@ -0,0 +1,302 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// frame is a frame as it travels over the connection.
+type frame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+// encode returns f as a client sends it, masked unless unmasked is set.
+func (f frame) encode(unmasked bool) []byte {
+	head := f.opcode
+	if f.fin {
+		head |= 0x80
+	}
+	b := []byte{head}
+
+	maskBit := byte(0x80)
+	if unmasked {
+		maskBit = 0
+	}
+	switch length := len(f.payload); {
+	case length <= 125:
+		b = append(b, maskBit|byte(length))
+	case length <= 0xffff:
+		b = append(b, maskBit|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(length))
+	default:
+		b = append(b, maskBit|127)
+		b = binary.BigEndian.AppendUint64(b, uint64(length))
+	}
+	if unmasked {
+		return append(b, f.payload...)
+	}
+
+	mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+	b = append(b, mask[:]...)
+	for i, c := range f.payload {
+		b = append(b, c^mask[i%4])
+	}
+	return b
+}
+
+func closePayload(code int, text string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(code)), text...)
+}
+
+// pipe returns a server Conn and a client that writes the given raw bytes
+// to it, and receives the frames it sends, which must be unmasked.
+func pipe(t *testing.T) (*Conn, func(...[]byte), <-chan frame) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+
+	received := make(chan frame, 16)
+	go func() {
+		defer close(received)
+		r := bufio.NewReader(client)
+		for {
+			var head [2]byte
+			if _, err := io.ReadFull(r, head[:]); err != nil {
+				return
+			}
+			if head[1]&0x80 != 0 {
+				t.Error("server frame is masked")
+				return
+			}
+			length := uint64(head[1] & 0x7f)
+			switch length {
+			case 126:
+				var ext [2]byte
+				io.ReadFull(r, ext[:])
+				length = uint64(binary.BigEndian.Uint16(ext[:]))
+			case 127:
+				var ext [8]byte
+				io.ReadFull(r, ext[:])
+				length = binary.BigEndian.Uint64(ext[:])
+			}
+			payload := make([]byte, length)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			received <- frame{fin: head[0]&0x80 != 0, opcode: head[0] & 0x0f, payload: payload}
+		}
+	}()
+
+	send := func(raw ...[]byte) {
+		go client.Write(bytes.Join(raw, nil))
+	}
+	return &Conn{conn: server, br: bufio.NewReader(server)}, send, received
+}
+
+func next(t *testing.T, received <-chan frame) frame {
+	t.Helper()
+	select {
+	case f, ok := <-received:
+		if !ok {
+			t.Fatal("connection closed, want a frame")
+		}
+		return f
+	case <-time.After(5 * time.Second):
+		t.Fatal("no frame sent")
+	}
+	return frame{}
+}
+
+func TestReadMessageUnmasksEveryLength(t *testing.T) {
+	// Lengths in 7 bits, 16 bits, and 64 bits up to the limit
+	for _, n := range []int{0, 5, 125, 126, 300, 0xffff, maxMessageSize} {
+		conn, send, _ := pipe(t)
+		payload := bytes.Repeat([]byte("abcdefg"), n/7+1)[:n]
+		send(frame{fin: true, opcode: BinaryMessage, payload: payload}.encode(false))
+
+		messageType, got, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("%d bytes: %v", n, err)
+		}
+		if messageType != BinaryMessage || !bytes.Equal(got, payload) {
+			t.Fatalf("%d bytes: got type %d and %d bytes back", n, messageType, len(got))
+		}
+	}
+}
+
+func TestWriteMessageRoundTrips(t *testing.T) {
+	for _, n := range []int{5, 300, 70000} {
+		conn, _, received := pipe(t)
+		payload := bytes.Repeat([]byte("x"), n)
+		go conn.WriteMessage(TextMessage, payload)
+
+		f := next(t, received)
+		if !f.fin || f.opcode != TextMessage || !bytes.Equal(f.payload, payload) {
+			t.Fatalf("%d bytes: got fin %t, opcode %d and %d bytes", n, f.fin, f.opcode, len(f.payload))
+		}
+	}
+}
+
+func TestFragmentsAreJoinedAroundControlFrames(t *testing.T) {
+	conn, send, received := pipe(t)
+	pongs := 0
+	conn.SetPongHandler(func() { pongs++ })
+	send(
+		frame{opcode: TextMessage, payload: []byte("Hel")}.encode(false),
+		frame{fin: true, opcode: PingMessage, payload: []byte("ping")}.encode(false),
+		frame{opcode: 0, payload: []byte("lo, ")}.encode(false),
+		frame{fin: true, opcode: PongMessage}.encode(false),
+		frame{fin: true, opcode: 0, payload: []byte("world")}.encode(false),
+	)
+
+	messageType, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageType != TextMessage || string(message) != "Hello, world" {
+		t.Fatalf("got %d %q, want the text message of the fragments", messageType, message)
+	}
+	if pongs != 1 {
+		t.Errorf("pong handler called %d times, want 1", pongs)
+	}
+	// The ping is answered with a pong of the same payload
+	if f := next(t, received); f.opcode != PongMessage || string(f.payload) != "ping" {
+		t.Errorf("answered the ping with opcode %d %q, want a pong", f.opcode, f.payload)
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	conn, send, received := pipe(t)
+	send(frame{fin: true, opcode: CloseMessage, payload: closePayload(CloseGoingAway, "bye")}.encode(false))
+
+	_, _, err := conn.ReadMessage()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseGoingAway || closeErr.Text != "bye" {
+		t.Fatalf("got %v, want the close frame of the client", err)
+	}
+	f := next(t, received)
+	if f.opcode != CloseMessage || binary.BigEndian.Uint16(f.payload) != CloseNormal {
+		t.Fatalf("answered with opcode %d %v, want a normal close", f.opcode, f.payload)
+	}
+
+	// Nothing is sent after the close frame, and only one is ever sent
+	if err := conn.WriteMessage(TextMessage, []byte("late")); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("write after close: %v, want net.ErrClosed", err)
+	}
+	if err := conn.WriteClose(CloseNormal, ""); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+	conn.Close()
+	if f, ok := <-received; ok {
+		t.Errorf("sent opcode %d after the close frame", f.opcode)
+	}
+}
+
+func TestProtocolErrorsCloseTheConnection(t *testing.T) {
+	tooBig := maxMessageSize/2 + 1
+	tests := []struct {
+		name   string
+		frames [][]byte
+		code   int
+	}{
+		{"unmasked", [][]byte{frame{fin: true, opcode: TextMessage, payload: []byte("hi")}.encode(true)}, CloseProtocolError},
+		{"reserved bits", [][]byte{{0x80 | 0x40 | TextMessage, 0x80, 0, 0, 0, 0}}, CloseProtocolError},
+		{"unknown opcode", [][]byte{frame{fin: true, opcode: 3}.encode(false)}, CloseProtocolError},
+		{"fragmented control frame", [][]byte{frame{opcode: PingMessage}.encode(false)}, CloseProtocolError},
+		{"long control frame", [][]byte{frame{fin: true, opcode: PingMessage, payload: make([]byte, 126)}.encode(false)}, CloseProtocolError},
+		{"continuation first", [][]byte{frame{fin: true, opcode: 0, payload: []byte("x")}.encode(false)}, CloseProtocolError},
+		{"message inside a message", [][]byte{
+			frame{opcode: TextMessage, payload: []byte("a")}.encode(false),
+			frame{fin: true, opcode: TextMessage, payload: []byte("b")}.encode(false),
+		}, CloseProtocolError},
+		{"frame over the limit", [][]byte{frame{fin: true, opcode: BinaryMessage, payload: make([]byte, maxMessageSize+1)}.encode(false)}, CloseMessageTooBig},
+		{"fragments over the limit", [][]byte{
+			frame{opcode: BinaryMessage, payload: make([]byte, tooBig)}.encode(false),
+			frame{fin: true, opcode: 0, payload: make([]byte, tooBig)}.encode(false),
+		}, CloseMessageTooBig},
+	}
+	for _, tt := range tests {
+		conn, send, received := pipe(t)
+		send(tt.frames...)
+
+		_, _, err := conn.ReadMessage()
+		var closeErr *CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != tt.code {
+			t.Errorf("%s: got %v, want close %d", tt.name, err, tt.code)
+			continue
+		}
+		f := next(t, received)
+		if f.opcode != CloseMessage || int(binary.BigEndian.Uint16(f.payload)) != tt.code {
+			t.Errorf("%s: sent opcode %d %v, want close %d", tt.name, f.opcode, f.payload, tt.code)
+		}
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	handshake := func(modify func(r *http.Request)) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		r.Header.Set("Connection", "keep-alive, Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Sec-WebSocket-Version", "13")
+		// The key of the example in RFC 6455
+		r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if modify != nil {
+			modify(r)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *http.Request)
+		version string
+	}{
+		{"POST", func(r *http.Request) { r.Method = http.MethodPost }, ""},
+		{"no upgrade", func(r *http.Request) { r.Header.Del("Upgrade") }, ""},
+		{"old version", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Version", "8") }, "13"},
+		{"short key", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Key", "c2hvcnQ=") }, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		if _, err := CheckHandshake(w, handshake(tt.modify)); !errors.Is(err, ErrBadHandshake) {
+			t.Errorf("%s: got %v, want a bad handshake", tt.name, err)
+		}
+		if got := w.Header().Get("Sec-WebSocket-Version"); got != tt.version {
+			t.Errorf("%s: Sec-WebSocket-Version %q, want %q", tt.name, got, tt.version)
+		}
+	}
+
+	key, err := CheckHandshake(httptest.NewRecorder(), handshake(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, client := net.Pipe()
+	defer client.Close()
+	go Accept(server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), key)
+
+	res, err := http.ReadResponse(bufio.NewReader(client), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols || !strings.EqualFold(res.Header.Get("Upgrade"), "websocket") {
+		t.Fatalf("got %s, upgrade %q, want 101 to websocket", res.Status, res.Header.Get("Upgrade"))
+	}
+	if accept := res.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept %q, want the one of RFC 6455", accept)
+	}
+}